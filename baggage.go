@@ -0,0 +1,225 @@
+package trace
+
+import (
+	"encoding/json"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+// ErrBaggageItemNotFound is the error returned by the baggage accessors when
+// the baggage item is not set.
+var ErrBaggageItemNotFound = errors.New("baggage item not found")
+
+// Default baggage limits.
+const (
+	DefaultBaggageMaxItems = 64
+	DefaultBaggageMaxSize  = 8192
+)
+
+// BaggageLimits are the limits of the baggage items of a span enforced by
+// SetBaggage and the typed baggage setters.
+type BaggageLimits struct {
+	MaxItems int // The maximum number of baggage items, or zero for no maximum.
+	MaxSize  int // The maximum total size in bytes of the baggage item names and values, or zero for no maximum.
+}
+
+// baggageLimits are the package baggage limits.
+var baggageLimits atomic.Pointer[BaggageLimits]
+
+func init() {
+	baggageLimits.Store(&BaggageLimits{MaxItems: DefaultBaggageMaxItems, MaxSize: DefaultBaggageMaxSize})
+}
+
+// GetBaggageLimits returns the baggage limits enforced by this package.
+func GetBaggageLimits() BaggageLimits {
+	return *baggageLimits.Load()
+}
+
+// SetBaggageLimits sets the baggage limits enforced by this package. The
+// default limits are DefaultBaggageMaxItems and DefaultBaggageMaxSize.
+func SetBaggageLimits(l BaggageLimits) {
+	baggageLimits.Store(&l)
+}
+
+// SetBaggage sets a baggage item of an opentracing span, if it does not exceed
+// the baggage limits. If it does, the item is not set, a warning is logged
+// for the span, and false is returned.
+func SetBaggage(span opentracing.Span, key, value string) bool {
+	if span == nil {
+		return false
+	}
+	limits := GetBaggageLimits()
+	items, size := 0, 0
+	replaced := false
+	span.Context().ForeachBaggageItem(func(k, v string) bool {
+		if k == key {
+			replaced = true
+			return true
+		}
+		items++
+		size += len(k) + len(v)
+		return true
+	})
+	items++
+	size += len(key) + len(value)
+
+	var exceeded string
+	switch {
+	case limits.MaxItems > 0 && items > limits.MaxItems && !replaced:
+		exceeded = "baggage item limit exceeded"
+	case limits.MaxSize > 0 && size > limits.MaxSize:
+		exceeded = "baggage size limit exceeded"
+	}
+	if exceeded != "" {
+		LogWarning(span, exceeded, map[string]interface{}{LogFieldBaggageKey: key})
+		return false
+	}
+	span.SetBaggageItem(key, value)
+	return true
+}
+
+// baggageItem returns a baggage item of an opentracing span.
+func baggageItem(span opentracing.Span, key string) (string, error) {
+	if span == nil {
+		return "", ErrBaggageItemNotFound
+	}
+	v := span.BaggageItem(key)
+	if v == "" {
+		return "", ErrBaggageItemNotFound
+	}
+	return v, nil
+}
+
+// SetBaggageInt sets an integer baggage item of an opentracing span, see
+// SetBaggage.
+func SetBaggageInt(span opentracing.Span, key string, v int64) bool {
+	return SetBaggage(span, key, strconv.FormatInt(v, 10))
+}
+
+// BaggageInt returns an integer baggage item of an opentracing span.
+func BaggageInt(span opentracing.Span, key string) (int64, error) {
+	v, err := baggageItem(span, key)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.ParseInt(v, 10, 64)
+	return i, errors.Wrapf(err, "invalid baggage item %q", key)
+}
+
+// SetBaggageBool sets a boolean baggage item of an opentracing span, see
+// SetBaggage.
+func SetBaggageBool(span opentracing.Span, key string, v bool) bool {
+	return SetBaggage(span, key, strconv.FormatBool(v))
+}
+
+// BaggageBool returns a boolean baggage item of an opentracing span.
+func BaggageBool(span opentracing.Span, key string) (bool, error) {
+	v, err := baggageItem(span, key)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	return b, errors.Wrapf(err, "invalid baggage item %q", key)
+}
+
+// SetBaggageDuration sets a duration baggage item of an opentracing span, see
+// SetBaggage.
+func SetBaggageDuration(span opentracing.Span, key string, v time.Duration) bool {
+	return SetBaggage(span, key, v.String())
+}
+
+// BaggageDuration returns a duration baggage item of an opentracing span.
+func BaggageDuration(span opentracing.Span, key string) (time.Duration, error) {
+	v, err := baggageItem(span, key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	return d, errors.Wrapf(err, "invalid baggage item %q", key)
+}
+
+// SetBaggageJSON sets a baggage item of an opentracing span to the JSON
+// encoding of the specified value, which should be small, see SetBaggage.
+func SetBaggageJSON(span opentracing.Span, key string, v interface{}) (bool, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return false, errors.Wrapf(err, "invalid baggage item %q", key)
+	}
+	return SetBaggage(span, key, string(b)), nil
+}
+
+// BaggageJSON decodes a JSON baggage item of an opentracing span into the
+// value pointed to by v.
+func BaggageJSON(span opentracing.Span, key string, v interface{}) error {
+	s, err := baggageItem(span, key)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(json.Unmarshal([]byte(s), v), "invalid baggage item %q", key)
+}
+
+// BaggageTagPrefix is the prefix of the tag names of the baggage items set
+// as tags by a BaggageTagsTracer.
+const BaggageTagPrefix = "baggage."
+
+// Ensure BaggageTagsTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &BaggageTagsTracer{}
+
+// BaggageTagsTracer is an opentracing.Tracer that wraps another tracer,
+// setting allow-listed baggage items of the referenced span contexts as tags
+// of the spans it starts, named with the BaggageTagPrefix, e.g.
+// "baggage.user". Tags set by the span options take precedence.
+//
+// The spans are the spans of the wrapped tracer, so the spans started by
+// their Tracer do not have the baggage tags set.
+type BaggageTagsTracer struct {
+	tracer opentracing.Tracer
+	keys   map[string]bool
+}
+
+// NewBaggageTagsTracer returns a new tracer that wraps the specified tracer,
+// setting the baggage items with the specified names as tags.
+func NewBaggageTagsTracer(tracer opentracing.Tracer, keys ...string) *BaggageTagsTracer {
+	t := &BaggageTagsTracer{tracer: tracer, keys: make(map[string]bool, len(keys))}
+	for _, k := range keys {
+		t.keys[k] = true
+	}
+	return t
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *BaggageTagsTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	tags := make(opentracing.Tags)
+	for _, ref := range sso.References {
+		ref.ReferencedContext.ForeachBaggageItem(func(k, v string) bool {
+			if t.keys[k] {
+				if _, ok := sso.Tags[BaggageTagPrefix+k]; !ok {
+					tags[BaggageTagPrefix+k] = v
+				}
+			}
+			return true
+		})
+	}
+	if len(tags) > 0 {
+		opts = append([]opentracing.StartSpanOption{tags}, opts...)
+	}
+	return t.tracer.StartSpan(operationName, opts...)
+}
+
+// Inject implements the opentracing.Tracer interface.
+func (t *BaggageTagsTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	return t.tracer.Inject(sc, format, carrier)
+}
+
+// Extract implements the opentracing.Tracer interface.
+func (t *BaggageTagsTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	return t.tracer.Extract(format, carrier)
+}
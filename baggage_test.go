@@ -0,0 +1,134 @@
+package trace_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+type baggageUser struct {
+	ID   int    `json:"id"`
+	Role string `json:"role"`
+}
+
+func TestTypedBaggage(t *testing.T) {
+	tracer := mocktracer.New()
+	span := tracer.StartSpan("client")
+	otexts.SetBaggageInt(span, "attempt", 3)
+	otexts.SetBaggageBool(span, "debug", true)
+	otexts.SetBaggageDuration(span, "budget", 1500*time.Millisecond)
+	if _, err := otexts.SetBaggageJSON(span, "user", baggageUser{ID: 1, Role: "admin"}); err != nil {
+		t.Fatalf("set JSON baggage: %v", err)
+	}
+	span.SetBaggageItem("invalid", "x")
+
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	sc, err := tracer.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	child := tracer.StartSpan("server", opentracing.ChildOf(sc))
+
+	if got, err := otexts.BaggageInt(child, "attempt"); err != nil || got != 3 {
+		t.Errorf("int baggage: got %v, %v, want 3", got, err)
+	}
+	if got, err := otexts.BaggageBool(child, "debug"); err != nil || !got {
+		t.Errorf("bool baggage: got %v, %v, want true", got, err)
+	}
+	if got, err := otexts.BaggageDuration(child, "budget"); err != nil || got != 1500*time.Millisecond {
+		t.Errorf("duration baggage: got %v, %v, want 1.5s", got, err)
+	}
+	var user baggageUser
+	if err := otexts.BaggageJSON(child, "user", &user); err != nil || user != (baggageUser{ID: 1, Role: "admin"}) {
+		t.Errorf("JSON baggage: got %+v, %v", user, err)
+	}
+	if _, err := otexts.BaggageInt(child, "missing"); err != otexts.ErrBaggageItemNotFound {
+		t.Errorf("missing baggage: got error %v, want %v", err, otexts.ErrBaggageItemNotFound)
+	}
+	if _, err := otexts.BaggageInt(child, "invalid"); err == nil {
+		t.Errorf("invalid int baggage: got nil error")
+	}
+	if _, err := otexts.BaggageBool(nil, "debug"); err != otexts.ErrBaggageItemNotFound {
+		t.Errorf("nil span baggage: got error %v, want %v", err, otexts.ErrBaggageItemNotFound)
+	}
+}
+
+func TestSetBaggage_limits(t *testing.T) {
+	defer otexts.SetBaggageLimits(otexts.GetBaggageLimits())
+	otexts.SetBaggageLimits(otexts.BaggageLimits{MaxItems: 2, MaxSize: 15})
+
+	tt := []struct {
+		name  string
+		items [][2]string
+		ok    []bool
+	}{
+		{
+			name:  "within limits",
+			items: [][2]string{{"a", "1"}, {"b", "2"}, {"a", "3"}},
+			ok:    []bool{true, true, true},
+		},
+		{
+			name:  "item limit",
+			items: [][2]string{{"a", "1"}, {"b", "2"}, {"c", "3"}},
+			ok:    []bool{true, true, false},
+		},
+		{
+			name:  "size limit",
+			items: [][2]string{{"a", "1234567"}, {"b", "1234567"}, {"a", "12345"}},
+			ok:    []bool{true, false, true},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+			warnings := 0
+			for i, item := range tc.items {
+				if got, want := otexts.SetBaggage(span, item[0], item[1]), tc.ok[i]; got != want {
+					t.Errorf("set %q: got %v, want %v", item[0], got, want)
+				}
+				if !tc.ok[i] {
+					warnings++
+				}
+			}
+			if got, want := len(span.Logs()), warnings; got != want {
+				t.Errorf("warnings: got %d, want %d", got, want)
+			}
+			for _, l := range span.Logs() {
+				if got, want := l.Fields[0].ValueString, otexts.LogEventWarning; got != want {
+					t.Errorf("event: got %q, want %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBaggageTagsTracer(t *testing.T) {
+	mock := mocktracer.New()
+	tracer := otexts.NewBaggageTagsTracer(mock, "user", "tenant")
+
+	parent := tracer.StartSpan("parent")
+	parent.SetBaggageItem("user", "test")
+	parent.SetBaggageItem("tenant", "acme")
+	parent.SetBaggageItem("secret", "value")
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(parent.Context(), opentracing.TextMap, carrier); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	sc, err := tracer.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	tracer.StartSpan("child", opentracing.ChildOf(sc), opentracing.Tag{Key: "baggage.tenant", Value: "explicit"}).Finish()
+
+	ensureTagsSet(t, map[string]string{
+		"baggage.user":   "test",
+		"baggage.tenant": "explicit",
+	}, mock.FinishedSpans()[0].Tags())
+}
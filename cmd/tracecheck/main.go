@@ -0,0 +1,19 @@
+// Command tracecheck reports misuse of opentracing spans and of the
+// opentracing-exts utilities.
+//
+// Usage:
+//
+//	tracecheck [flags] packages...
+//
+// See the tracecheck package for the reported issues.
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/code-willing/opentracing-exts/tracecheck"
+)
+
+func main() {
+	singlechecker.Main(tracecheck.Analyzer)
+}
@@ -0,0 +1,103 @@
+// Command traceview renders the traces of span files, written by the
+// jsontrace tracer or as Zipkin v2 or Jaeger UI JSON, as indented trees with
+// durations, waterfall bars, errors, and the critical path.
+//
+// Usage:
+//
+//	traceview [flags] [files...]
+//
+// The files are read from the standard input if none is specified. The
+// flags are:
+//
+//	-format auto|jsonl|zipkin|jaeger
+//		The format of the files, detected from their content by default.
+//	-op substring
+//		Show only the spans whose operation name contains the substring.
+//	-tag key[=value]
+//		Show only the spans with the tag, and tag value. Can be repeated.
+//	-min-duration duration
+//		Show only the spans that last at least the duration, e.g. 10ms.
+//	-width n
+//		The width of the waterfall bars.
+//	-critical
+//		Mark the spans on the critical path with an asterisk.
+//	-color
+//		Highlight the critical path and the errors with ANSI colors.
+//
+// The ancestors of the shown spans are shown too.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+func main() {
+	var (
+		f filter
+		r renderer
+	)
+	format := flag.String("format", formatAuto, "the format of the files: auto, jsonl, zipkin, or jaeger")
+	flag.StringVar(&f.operation, "op", "", "show only the spans whose operation name contains the `substring`")
+	flag.Func("tag", "show only the spans with the tag `key[=value]` (can be repeated)", func(s string) error {
+		tag, err := parseTagFilter(s)
+		if err != nil {
+			return err
+		}
+		f.tags = append(f.tags, tag)
+		return nil
+	})
+	flag.DurationVar(&f.minDuration, "min-duration", 0, "show only the spans that last at least the `duration`")
+	flag.IntVar(&r.width, "width", 40, "the width of the waterfall bars")
+	flag.BoolVar(&r.critical, "critical", true, "mark the spans on the critical path")
+	flag.BoolVar(&r.color, "color", false, "highlight the critical path and the errors with ANSI colors")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: traceview [flags] [files...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if r.width < 1 {
+		fatal(fmt.Errorf("invalid width %d", r.width))
+	}
+
+	paths := flag.Args()
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	var spans []*jsontrace.Span
+	for _, path := range paths {
+		s, err := readFile(path, *format)
+		if err != nil {
+			fatal(err)
+		}
+		spans = append(spans, s...)
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	first := true
+	for _, t := range buildTraces(spans) {
+		if !f.apply(t) {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w)
+		}
+		first = false
+		if err := r.render(w, t); err != nil {
+			fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		fatal(err)
+	}
+}
+
+// fatal prints the specified error and exits.
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "traceview: %v\n", err)
+	os.Exit(1)
+}
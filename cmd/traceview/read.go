@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+// Trace file formats.
+const (
+	formatAuto   = "auto"
+	formatJSONL  = "jsonl"
+	formatZipkin = "zipkin"
+	formatJaeger = "jaeger"
+)
+
+// readFile reads the span records of the specified trace file, or of the
+// standard input if the path is "-".
+func readFile(path, format string) ([]*jsontrace.Span, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	spans, err := readSpans(b, format)
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	return spans, nil
+}
+
+// readSpans reads the span records of the specified trace file content in the
+// specified format, detecting it if it is formatAuto.
+func readSpans(b []byte, format string) ([]*jsontrace.Span, error) {
+	if format == formatAuto {
+		format = detectFormat(b)
+	}
+	r := bytes.NewReader(b)
+	switch format {
+	case formatJSONL:
+		return jsontrace.ReadSpans(r)
+	case formatZipkin:
+		return jsontrace.ReadZipkin(r)
+	case formatJaeger:
+		return jsontrace.ReadJaeger(r)
+	}
+	return nil, errors.Errorf("unknown format %q", format)
+}
+
+// detectFormat returns the format of the specified trace file content: a
+// Zipkin JSON list of spans, a Jaeger UI JSON object with a "data" list, or
+// else JSON lines.
+func detectFormat(b []byte) string {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && b[0] == '[' {
+		return formatZipkin
+	}
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(b, &file); err == nil && file["data"] != nil {
+		return formatJaeger
+	}
+	return formatJSONL
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ANSI escape codes of the colored output.
+const (
+	ansiBold  = "\x1b[1m"
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
+
+// renderer renders trace trees as indented text.
+type renderer struct {
+	width    int  // The width of the waterfall bars.
+	critical bool // Whether the critical path is highlighted.
+	color    bool // Whether ANSI colors are used.
+}
+
+// line is a rendered span line, before alignment.
+type line struct {
+	node  *node
+	label string
+}
+
+// render writes the visible spans of the specified trace.
+func (r *renderer) render(w io.Writer, t *trace) error {
+	duration := t.end.Sub(t.start)
+	if _, err := fmt.Fprintf(w, "trace %s (%d spans, %s)\n", t.id, t.spans, formatDuration(duration)); err != nil {
+		return err
+	}
+
+	var lines []line
+	for _, root := range t.roots {
+		lines = appendLines(lines, root, "", "")
+	}
+	labelWidth, durationWidth := 0, 0
+	for _, l := range lines {
+		labelWidth = max(labelWidth, utf8.RuneCountInString(l.label))
+		durationWidth = max(durationWidth, len(formatDuration(l.node.span.Duration)))
+	}
+
+	for _, l := range lines {
+		span := l.node.span
+		var b strings.Builder
+		if r.critical {
+			if l.node.critical {
+				b.WriteString("* ")
+			} else {
+				b.WriteString("  ")
+			}
+		}
+		label := l.label + strings.Repeat(" ", labelWidth-utf8.RuneCountInString(l.label))
+		if r.critical && l.node.critical && r.color {
+			label = ansiBold + label + ansiReset
+		}
+		b.WriteString(label)
+		fmt.Fprintf(&b, "  %*s  |%s|", durationWidth, formatDuration(span.Duration), r.bar(t, l.node))
+		if isError(span) {
+			marker := "! error"
+			if text := errorText(span); text != "" {
+				marker = "! " + text
+			}
+			if r.color {
+				marker = ansiRed + marker + ansiReset
+			}
+			b.WriteString("  " + marker)
+		}
+		b.WriteByte('\n')
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendLines appends the lines of the visible spans of the specified span
+// tree, drawing the tree with the specified prefixes.
+func appendLines(lines []line, n *node, prefix, childPrefix string) []line {
+	if !n.visible {
+		return lines
+	}
+	lines = append(lines, line{node: n, label: prefix + n.span.Operation})
+	var children []*node
+	for _, child := range n.children {
+		if child.visible {
+			children = append(children, child)
+		}
+	}
+	for i, child := range children {
+		if i == len(children)-1 {
+			lines = appendLines(lines, child, childPrefix+"└─ ", childPrefix+"   ")
+		} else {
+			lines = appendLines(lines, child, childPrefix+"├─ ", childPrefix+"│  ")
+		}
+	}
+	return lines
+}
+
+// bar returns the waterfall bar of the specified span of the trace: the span
+// is drawn at its offset in the trace, with at least one character.
+func (r *renderer) bar(t *trace, n *node) string {
+	duration := float64(t.end.Sub(t.start))
+	if duration <= 0 {
+		duration = 1
+	}
+	from := int(float64(n.span.Start.Sub(t.start)) / duration * float64(r.width))
+	to := int(math.Ceil(float64(n.end().Sub(t.start)) / duration * float64(r.width)))
+	from = min(max(from, 0), r.width-1)
+	to = min(max(to, from+1), r.width)
+	return strings.Repeat(" ", from) + strings.Repeat("█", to-from) + strings.Repeat(" ", r.width-to)
+}
+
+// formatDuration formats the specified duration rounded to the microsecond.
+func formatDuration(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+func TestRender(t *testing.T) {
+	tt := []struct {
+		name     string
+		renderer renderer
+		filter   filter
+		want     string
+	}{
+		{
+			name:     "all spans",
+			renderer: renderer{width: 10, critical: true},
+			want: `trace 4bf92f3577b34da6a3ce929d0e0e4736 (5 spans, 100ms)
+* handle      100ms  |██████████|
+  ├─ cache     20ms  | ██       |
+* └─ fetch     60ms  | ██████   |  ! *url.Error: connection refused
+*    └─ dial    5ms  | █        |
+* orphan       10ms  |        █ |
+`,
+		},
+		{
+			name:     "filtered without critical path",
+			renderer: renderer{width: 10},
+			filter:   filter{tags: []tagFilter{{key: "error"}}},
+			want: `trace 4bf92f3577b34da6a3ce929d0e0e4736 (5 spans, 100ms)
+handle    100ms  |██████████|
+└─ fetch   60ms  | ██████   |  ! *url.Error: connection refused
+`,
+		},
+		{
+			name:     "color",
+			renderer: renderer{width: 10, critical: true, color: true},
+			filter:   filter{minDuration: 50 * time.Millisecond},
+			want: "trace 4bf92f3577b34da6a3ce929d0e0e4736 (5 spans, 100ms)\n" +
+				"* " + ansiBold + "handle  " + ansiReset + "  100ms  |██████████|\n" +
+				"* " + ansiBold + "└─ fetch" + ansiReset + "   60ms  | ██████   |  " + ansiRed + "! *url.Error: connection refused" + ansiReset + "\n",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := buildTraces(testSpans())[0]
+			tc.filter.apply(tr)
+			var b strings.Builder
+			if err := tc.renderer.render(&b, tr); err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			if got, want := b.String(), tc.want; got != want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestRenderErrorWithoutLog(t *testing.T) {
+	span := testSpan("1", "", "handle", 0, 10)
+	span.Tags["error"] = true
+	tr := buildTraces([]*jsontrace.Span{span})[0]
+	(&filter{}).apply(tr)
+	var b strings.Builder
+	if err := (&renderer{width: 4}).render(&b, tr); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got, want := b.String(), "trace 4bf92f3577b34da6a3ce929d0e0e4736 (1 spans, 10ms)\nhandle  10ms  |████|  ! error\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tt := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "zipkin", input: "\n[{\"traceId\":\"1\"}]", want: formatZipkin},
+		{name: "jaeger", input: `{"data":[]}`, want: formatJaeger},
+		{name: "single line", input: `{"trace_id":"1"}`, want: formatJSONL},
+		{name: "lines", input: "{\"data\":1}\n{\"data\":2}\n", want: formatJSONL},
+		{name: "empty", input: "", want: formatJSONL},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := detectFormat([]byte(tc.input)); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadSpans(t *testing.T) {
+	for _, path := range []string{"trace.jsonl", "trace.zipkin.json", "trace.jaeger.json"} {
+		t.Run(path, func(t *testing.T) {
+			spans, err := readFile("../../jsontrace/testdata/"+path, formatAuto)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if got, want := len(spans), 5; got != want {
+				t.Errorf("got %d spans, want %d", got, want)
+			}
+		})
+	}
+	if _, err := readSpans([]byte("{}"), "xml"); err == nil {
+		t.Errorf("got no error for unknown format")
+	}
+}
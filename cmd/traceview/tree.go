@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go/ext"
+
+	otexts "github.com/code-willing/opentracing-exts"
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+// trace is a tree of the spans of a trace.
+type trace struct {
+	id    string
+	roots []*node
+	spans int
+	start time.Time
+	end   time.Time
+}
+
+// node is a span of a trace tree.
+type node struct {
+	span     *jsontrace.Span
+	children []*node
+	critical bool // Whether the span is on the critical path.
+	visible  bool // Whether the span, or one of its descendants, matches the filter.
+}
+
+// end returns the end time of the span.
+func (n *node) end() time.Time {
+	return n.span.Start.Add(n.span.Duration)
+}
+
+// buildTraces returns the trees of the traces of the specified spans, in order
+// of start time. The spans whose parent is missing are roots, and so are the
+// spans whose parent is their descendant, breaking the parent cycles of
+// malformed or merged files. The duplicates of a span, with the same trace
+// and span IDs, e.g. reported twice, are skipped.
+func buildTraces(spans []*jsontrace.Span) []*trace {
+	var traces []*trace
+	var unique []*jsontrace.Span
+	byID := make(map[string]*trace)
+	nodes := make(map[string]map[string]*node)
+	for _, span := range spans {
+		if _, ok := nodes[span.TraceID][span.SpanID]; ok {
+			continue
+		}
+		unique = append(unique, span)
+		t, ok := byID[span.TraceID]
+		if !ok {
+			t = &trace{id: span.TraceID, start: span.Start, end: span.Start.Add(span.Duration)}
+			byID[span.TraceID] = t
+			nodes[span.TraceID] = make(map[string]*node)
+			traces = append(traces, t)
+		}
+		n := &node{span: span}
+		nodes[span.TraceID][span.SpanID] = n
+		t.spans++
+		if span.Start.Before(t.start) {
+			t.start = span.Start
+		}
+		if end := n.end(); end.After(t.end) {
+			t.end = end
+		}
+	}
+	parents := make(map[*node]*node, len(unique))
+	for _, span := range unique {
+		t, n := byID[span.TraceID], nodes[span.TraceID][span.SpanID]
+		if parent, ok := nodes[span.TraceID][span.ParentID]; ok && !isAncestor(n, parent, parents) {
+			parent.children = append(parent.children, n)
+			parents[n] = parent
+		} else {
+			t.roots = append(t.roots, n)
+		}
+	}
+	for _, t := range traces {
+		sortNodes(t.roots)
+		for _, root := range t.roots {
+			markCriticalPath(root)
+		}
+	}
+	sort.SliceStable(traces, func(i, j int) bool {
+		return traces[i].start.Before(traces[j].start)
+	})
+	return traces
+}
+
+// isAncestor reports whether the specified ancestor is the specified node, or
+// one of its ancestors by the specified parents.
+func isAncestor(ancestor, n *node, parents map[*node]*node) bool {
+	for ; n != nil; n = parents[n] {
+		if n == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// sortNodes sorts the specified nodes, and their descendants, by start time.
+func sortNodes(nodes []*node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].span.Start.Before(nodes[j].span.Start)
+	})
+	for _, n := range nodes {
+		sortNodes(n.children)
+	}
+}
+
+// markCriticalPath marks the critical path of the specified span: the span,
+// and walking back from its end, the critical path of the child that ends
+// last, then of the child that ends last before that child started, etc.
+func markCriticalPath(n *node) {
+	n.critical = true
+	children := make([]*node, len(n.children))
+	copy(children, n.children)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].end().After(children[j].end())
+	})
+	cursor := n.end()
+	for _, child := range children {
+		if child.span.Start.Before(cursor) {
+			markCriticalPath(child)
+			cursor = child.span.Start
+		}
+	}
+}
+
+// filter selects the spans of a trace.
+type filter struct {
+	operation   string      // A substring of the operation name.
+	tags        []tagFilter // The tags.
+	minDuration time.Duration
+}
+
+// tagFilter selects the spans with a tag, and a tag value if not empty.
+type tagFilter struct {
+	key   string
+	value string
+}
+
+// parseTagFilter parses a tag filter of the form "key" or "key=value".
+func parseTagFilter(s string) (tagFilter, error) {
+	key, value, _ := strings.Cut(s, "=")
+	if key == "" {
+		return tagFilter{}, fmt.Errorf("invalid tag filter %q", s)
+	}
+	return tagFilter{key: key, value: value}, nil
+}
+
+// match reports whether the specified span matches the filter. The tag values
+// are compared formatted with fmt.Sprint.
+func (f *filter) match(span *jsontrace.Span) bool {
+	if f.operation != "" && !strings.Contains(span.Operation, f.operation) {
+		return false
+	}
+	if span.Duration < f.minDuration {
+		return false
+	}
+	for _, tag := range f.tags {
+		v, ok := span.Tags[tag.key]
+		if !ok || tag.value != "" && fmt.Sprint(v) != tag.value {
+			return false
+		}
+	}
+	return true
+}
+
+// apply marks the visible spans of the specified trace, the spans that match
+// the filter and their ancestors, and reports whether any span is visible.
+func (f *filter) apply(t *trace) bool {
+	visible := false
+	for _, root := range t.roots {
+		if f.applyNode(root) {
+			visible = true
+		}
+	}
+	return visible
+}
+
+// applyNode marks the visible spans of the specified span tree, and reports
+// whether the span is visible.
+func (f *filter) applyNode(n *node) bool {
+	n.visible = f.match(n.span)
+	for _, child := range n.children {
+		if f.applyNode(child) {
+			n.visible = true
+		}
+	}
+	return n.visible
+}
+
+// isError reports whether the "error" tag of the specified span is set.
+func isError(span *jsontrace.Span) bool {
+	return fmt.Sprint(span.Tags[string(ext.Error)]) == "true"
+}
+
+// errorText returns the error kind and message of the first error log of the
+// specified span, as logged by LogError, if any.
+func errorText(span *jsontrace.Span) string {
+	for _, l := range span.Logs {
+		if f, ok := l.Field(otexts.LogFieldEvent); !ok || f.Value != otexts.LogEventError {
+			continue
+		}
+		var msg string
+		for _, key := range []string{otexts.LogFieldMessage, otexts.LogFieldErrorObject, "error"} {
+			if f, ok := l.Field(key); ok {
+				msg = fmt.Sprint(f.Value)
+				break
+			}
+		}
+		kind, ok := l.Field(otexts.LogFieldErrorKind)
+		switch {
+		case ok && msg != "":
+			return fmt.Sprintf("%v: %s", kind.Value, msg)
+		case ok:
+			return fmt.Sprint(kind.Value)
+		}
+		return msg
+	}
+	return ""
+}
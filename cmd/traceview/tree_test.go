@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+var testStart = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// testSpan returns a span record of the test trace, starting and lasting the
+// specified number of milliseconds.
+func testSpan(id, parentID, operation string, start, duration int) *jsontrace.Span {
+	return &jsontrace.Span{
+		TraceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:    id,
+		ParentID:  parentID,
+		Operation: operation,
+		Start:     testStart.Add(time.Duration(start) * time.Millisecond),
+		Duration:  time.Duration(duration) * time.Millisecond,
+		Tags:      map[string]interface{}{},
+	}
+}
+
+// testSpans returns the spans of the test trace, in finish order: a root
+// span with two parallel children, a child of the longest child, and an
+// orphan span whose parent is missing.
+func testSpans() []*jsontrace.Span {
+	fetch := testSpan("3", "1", "fetch", 10, 60)
+	fetch.Tags["error"] = true
+	fetch.Tags["http.status_code"] = json.Number("503")
+	fetch.Logs = []jsontrace.Log{{Fields: []jsontrace.Field{
+		{Key: "event", Type: jsontrace.FieldString, Value: "error"},
+		{Key: "error.kind", Type: jsontrace.FieldString, Value: "*url.Error"},
+		{Key: "message", Type: jsontrace.FieldString, Value: "connection refused"},
+	}}}
+	return []*jsontrace.Span{
+		testSpan("2", "1", "cache", 10, 20),
+		testSpan("4", "3", "dial", 15, 5),
+		fetch,
+		testSpan("1", "", "handle", 0, 100),
+		testSpan("5", "9", "orphan", 80, 10),
+	}
+}
+
+// operations returns the operation names of the specified nodes.
+func operations(nodes []*node) []string {
+	var names []string
+	for _, n := range nodes {
+		names = append(names, n.span.Operation)
+	}
+	return names
+}
+
+func TestBuildTraces(t *testing.T) {
+	traces := buildTraces(testSpans())
+	if got, want := len(traces), 1; got != want {
+		t.Fatalf("got %d traces, want %d", got, want)
+	}
+	tr := traces[0]
+	if got, want := tr.spans, 5; got != want {
+		t.Errorf("got %d spans, want %d", got, want)
+	}
+	if got, want := tr.end.Sub(tr.start), 100*time.Millisecond; got != want {
+		t.Errorf("got duration %v, want %v", got, want)
+	}
+	if got, want := operations(tr.roots), []string{"handle", "orphan"}; !equalStrings(got, want) {
+		t.Errorf("got roots %v, want %v", got, want)
+	}
+	if got, want := operations(tr.roots[0].children), []string{"cache", "fetch"}; !equalStrings(got, want) {
+		t.Errorf("got children %v, want %v", got, want)
+	}
+
+	critical := make(map[string]bool)
+	var walk func(n *node)
+	walk = func(n *node) {
+		critical[n.span.Operation] = n.critical
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(tr.roots[0])
+	want := map[string]bool{"handle": true, "fetch": true, "dial": true, "cache": false}
+	for op, w := range want {
+		if got := critical[op]; got != w {
+			t.Errorf("%s: got critical %v, want %v", op, got, w)
+		}
+	}
+}
+
+func TestBuildTraces_duplicates(t *testing.T) {
+	other := testSpan("1", "", "other", 200, 10)
+	other.TraceID = "0af7651916cd43dd8448eb211c80319c"
+	traces := buildTraces(append(append(testSpans(), testSpans()...), other))
+	if got, want := len(traces), 2; got != want {
+		t.Fatalf("got %d traces, want %d", got, want)
+	}
+	tr := traces[0]
+	if got, want := tr.spans, 5; got != want {
+		t.Errorf("got %d spans, want %d", got, want)
+	}
+	if got, want := operations(tr.roots), []string{"handle", "orphan"}; !equalStrings(got, want) {
+		t.Errorf("got roots %v, want %v", got, want)
+	}
+	if got, want := operations(tr.roots[0].children), []string{"cache", "fetch"}; !equalStrings(got, want) {
+		t.Errorf("got children %v, want %v", got, want)
+	}
+	if got, want := operations(traces[1].roots), []string{"other"}; !equalStrings(got, want) {
+		t.Errorf("got other roots %v, want %v", got, want)
+	}
+}
+
+func TestBuildTraces_cycles(t *testing.T) {
+	spans := []*jsontrace.Span{
+		testSpan("1", "2", "a", 0, 10),
+		testSpan("2", "1", "b", 10, 10),
+		testSpan("3", "1", "c", 20, 10),
+		testSpan("4", "4", "self", 30, 10),
+	}
+	traces := buildTraces(spans)
+	if got, want := len(traces), 1; got != want {
+		t.Fatalf("got %d traces, want %d", got, want)
+	}
+	tr := traces[0]
+	if got, want := tr.spans, 4; got != want {
+		t.Errorf("got %d spans, want %d", got, want)
+	}
+	var rendered []string
+	var walk func(n *node)
+	walk = func(n *node) {
+		rendered = append(rendered, n.span.Operation)
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	for _, root := range tr.roots {
+		walk(root)
+	}
+	if got, want := rendered, []string{"b", "a", "c", "self"}; !equalStrings(got, want) {
+		t.Errorf("got spans %v, want %v", got, want)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	tt := []struct {
+		name    string
+		filter  filter
+		visible []string
+	}{
+		{name: "none", filter: filter{}, visible: []string{"handle", "cache", "fetch", "dial", "orphan"}},
+		{name: "operation", filter: filter{operation: "ca"}, visible: []string{"handle", "cache"}},
+		{name: "tag", filter: filter{tags: []tagFilter{{key: "error"}}}, visible: []string{"handle", "fetch"}},
+		{name: "tag value", filter: filter{tags: []tagFilter{{key: "http.status_code", value: "503"}}}, visible: []string{"handle", "fetch"}},
+		{name: "tag value mismatch", filter: filter{tags: []tagFilter{{key: "http.status_code", value: "500"}}}},
+		{name: "min duration", filter: filter{minDuration: 10 * time.Millisecond}, visible: []string{"handle", "cache", "fetch", "orphan"}},
+		{name: "combined", filter: filter{operation: "d", minDuration: 10 * time.Millisecond}, visible: []string{"handle"}},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := buildTraces(testSpans())[0]
+			if got, want := tc.filter.apply(tr), len(tc.visible) > 0; got != want {
+				t.Errorf("got visible trace %v, want %v", got, want)
+			}
+			var visible []string
+			var walk func(n *node)
+			walk = func(n *node) {
+				if n.visible {
+					visible = append(visible, n.span.Operation)
+				}
+				for _, child := range n.children {
+					walk(child)
+				}
+			}
+			for _, root := range tr.roots {
+				walk(root)
+			}
+			if got, want := visible, tc.visible; !equalStrings(got, want) {
+				t.Errorf("got visible spans %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseTagFilter(t *testing.T) {
+	tt := []struct {
+		input   string
+		want    tagFilter
+		wantErr bool
+	}{
+		{input: "error", want: tagFilter{key: "error"}},
+		{input: "http.status_code=500", want: tagFilter{key: "http.status_code", value: "500"}},
+		{input: "db.statement=a=b", want: tagFilter{key: "db.statement", value: "a=b"}},
+		{input: "=500", wantErr: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseTagFilter(tc.input)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
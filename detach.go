@@ -0,0 +1,42 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// DetachOptions are the options of Detach.
+type DetachOptions struct {
+	// Baggage are the names of the baggage items copied from the span in the
+	// context to the new span. Tracers may propagate the baggage items to the
+	// spans that follow from a span, this copies them regardless.
+	Baggage []string
+
+	// SpanOptions are the options of the new span.
+	SpanOptions []opentracing.StartSpanOption
+}
+
+// Detach starts a span that follows from the span in the specified context,
+// if any, and returns it with a new context carrying the span and the values
+// of the specified context, but not its deadline or cancellation. It is used
+// for asynchronous work that is linked to a request's trace, but outlives the
+// request. The options may be nil.
+//
+// The returned span must be finished by the caller. The context helpers of
+// this package, e.g. LogErrorCtx, use the returned span.
+func Detach(ctx context.Context, operationName string, opts *DetachOptions) (opentracing.Span, context.Context) {
+	if opts == nil {
+		opts = &DetachOptions{}
+	}
+	parent := opentracing.SpanFromContext(ctx)
+	span, _ := startSpanFromContext(ctx, opentracing.FollowsFromRef, operationName, opts.SpanOptions...)
+	if parent != nil {
+		for _, k := range opts.Baggage {
+			if v := parent.BaggageItem(k); v != "" {
+				span.SetBaggageItem(k, v)
+			}
+		}
+	}
+	return span, opentracing.ContextWithSpan(context.WithoutCancel(ctx), span)
+}
@@ -0,0 +1,76 @@
+package trace_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+type detachTestKey struct{}
+
+func TestDetach(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("request")
+	parent.SetBaggageItem("user", "test")
+	parent.SetBaggageItem("session", "secret")
+
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+	ctx = context.WithValue(ctx, detachTestKey{}, "value")
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	span, detached := otexts.Detach(ctx, "async", &otexts.DetachOptions{
+		Baggage:     []string{"user", "missing"},
+		SpanOptions: []opentracing.StartSpanOption{opentracing.Tag{Key: "async", Value: true}},
+	})
+	cancel()
+	parent.Finish()
+
+	if err := detached.Err(); err != nil {
+		t.Errorf("detached context error: got %v, want nil", err)
+	}
+	if _, ok := detached.Deadline(); ok {
+		t.Errorf("detached context has a deadline")
+	}
+	if got, want := detached.Value(detachTestKey{}), "value"; got != want {
+		t.Errorf("detached context value: got %v, want %v", got, want)
+	}
+	if got, want := opentracing.SpanFromContext(detached), span; got != want {
+		t.Errorf("detached context span: got %v, want %v", got, want)
+	}
+	otexts.LogErrorCtx(detached, errors.New("error"))
+	span.Finish()
+
+	spans := tracer.FinishedSpans()
+	asyncSpan := spans[1]
+	if got, want := asyncSpan.ParentID, spans[0].SpanContext.SpanID; got != want {
+		t.Errorf("parent ID: got %d, want %d", got, want)
+	}
+	if got, want := asyncSpan.BaggageItem("user"), "test"; got != want {
+		t.Errorf("user baggage item: got %q, want %q", got, want)
+	}
+	if got, want := asyncSpan.Tag("async"), true; got != want {
+		t.Errorf("async tag: got %v, want %v", got, want)
+	}
+	if got, want := len(asyncSpan.Logs()), 1; got != want {
+		t.Errorf("logs: got %d, want %d", got, want)
+	}
+}
+
+func TestDetach_noSpan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	span, detached := otexts.Detach(ctx, "async", nil)
+	defer span.Finish()
+
+	if err := detached.Err(); err != nil {
+		t.Errorf("detached context error: got %v, want nil", err)
+	}
+	if got, want := opentracing.SpanFromContext(detached), span; got != want {
+		t.Errorf("detached context span: got %v, want %v", got, want)
+	}
+}
@@ -2,3 +2,5 @@
 //
 // See https://github.com/opentracing/specification/blob/master/semantic_conventions.md.
 package trace
+
+//go:generate go run ./internal/cmd/tagsgen -spec tags.json -out tags_gen.go -test tags_gen_test.go
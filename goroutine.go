@@ -0,0 +1,164 @@
+package trace
+
+import (
+	"context"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// ErrPoolClosed is the error returned by Pool.Submit when the pool is closed.
+var ErrPoolClosed = errors.New("pool closed")
+
+// startSpanFromContext starts a span with the specified reference to the span
+// in the specified context, if any, using the tracer of that span, or the
+// global tracer otherwise. It returns the span and a copy of the context with
+// the span.
+func startSpanFromContext(ctx context.Context, refType opentracing.SpanReferenceType, operationName string, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	tracer := opentracing.GlobalTracer()
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		tracer = parent.Tracer()
+		opts = append([]opentracing.StartSpanOption{
+			opentracing.SpanReference{Type: refType, ReferencedContext: parent.Context()},
+		}, opts...)
+	}
+	span := tracer.StartSpan(operationName, opts...)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
+
+// runSpan runs the specified function with a context with the specified span,
+// logging its error for the span and finishing the span when it returns.
+func runSpan(ctx context.Context, span opentracing.Span, fn func(ctx context.Context) error) error {
+	defer span.Finish()
+	err := fn(ctx)
+	LogError(span, err)
+	return err
+}
+
+// Go calls the specified function in a new goroutine with a context with a
+// child span of the span in the specified context. The error returned by the
+// function is logged for the span with LogError, and the span is finished
+// when the function returns.
+func Go(ctx context.Context, operationName string, fn func(ctx context.Context) error, opts ...opentracing.StartSpanOption) {
+	span, ctx := startSpanFromContext(ctx, opentracing.ChildOfRef, operationName, opts...)
+	go runSpan(ctx, span, fn)
+}
+
+// GoFollowsFrom is like Go, but the span follows from the span in the
+// specified context rather than being its child, for work whose result the
+// caller does not wait for.
+func GoFollowsFrom(ctx context.Context, operationName string, fn func(ctx context.Context) error, opts ...opentracing.StartSpanOption) {
+	span, ctx := startSpanFromContext(ctx, opentracing.FollowsFromRef, operationName, opts...)
+	go runSpan(ctx, span, fn)
+}
+
+// Group is a golang.org/x/sync/errgroup.Group that starts a child span of the
+// span in its context for each task, logging the error of each task for its
+// span with LogError.
+type Group struct {
+	group *errgroup.Group
+	ctx   context.Context
+}
+
+// WithGroup returns a new group and a derived context, like
+// errgroup.WithContext. The derived context is canceled the first time a task
+// returns an error or Wait returns, whichever occurs first.
+func WithGroup(ctx context.Context) (*Group, context.Context) {
+	g, ctx := errgroup.WithContext(ctx)
+	return &Group{group: g, ctx: ctx}, ctx
+}
+
+// Go calls the specified function in a new goroutine with a context with a
+// child span of the span in the group's context. The first task to return an
+// error cancels the group's context, and its error is returned by Wait.
+func (g *Group) Go(operationName string, fn func(ctx context.Context) error, opts ...opentracing.StartSpanOption) {
+	g.group.Go(func() error {
+		span, ctx := startSpanFromContext(g.ctx, opentracing.ChildOfRef, operationName, opts...)
+		return runSpan(ctx, span, fn)
+	})
+}
+
+// SetLimit limits the number of active tasks in the group to at most n, see
+// errgroup.Group.SetLimit.
+func (g *Group) SetLimit(n int) {
+	g.group.SetLimit(n)
+}
+
+// Wait blocks until all the tasks have returned, then returns the first
+// error, if any.
+func (g *Group) Wait() error {
+	return g.group.Wait()
+}
+
+// poolTask is a task submitted to a Pool.
+type poolTask struct {
+	ctx  context.Context
+	span opentracing.Span
+	fn   func(ctx context.Context) error
+}
+
+// Pool is a bounded pool of workers that run each task with a child span of
+// the span in the context it was submitted with.
+type Pool struct {
+	tasks  chan poolTask
+	wg     sync.WaitGroup
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool returns a new pool with the specified number of workers, at least
+// one.
+func NewPool(workers int) *Pool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &Pool{tasks: make(chan poolTask)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// work runs tasks until the pool is closed.
+func (p *Pool) work() {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		runSpan(task.ctx, task.span, task.fn)
+	}
+}
+
+// Submit submits the specified function to be run by a worker with a context
+// with a child span of the span in the specified context, blocking until a
+// worker is available. The error returned by the function is logged for the
+// span with LogError. It returns the error of the specified context if it is
+// done before a worker is available, or ErrPoolClosed if the pool is closed.
+func (p *Pool) Submit(ctx context.Context, operationName string, fn func(ctx context.Context) error, opts ...opentracing.StartSpanOption) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	span, spanCtx := startSpanFromContext(ctx, opentracing.ChildOfRef, operationName, opts...)
+	select {
+	case p.tasks <- poolTask{ctx: spanCtx, span: span, fn: fn}:
+		return nil
+	case <-ctx.Done():
+		LogError(span, ctx.Err())
+		span.Finish()
+		return ctx.Err()
+	}
+}
+
+// Close closes the pool and waits for the submitted tasks to return.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.tasks)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
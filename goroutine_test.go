@@ -0,0 +1,190 @@
+package trace_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// finishedChildren returns the operation names of the finished children of the
+// specified span.
+func finishedChildren(tracer *mocktracer.MockTracer, parent opentracing.Span) map[string]bool {
+	children := make(map[string]bool)
+	parentID := parent.Context().(mocktracer.MockSpanContext).SpanID
+	for _, span := range tracer.FinishedSpans() {
+		if span.ParentID == parentID {
+			children[span.OperationName] = true
+		}
+	}
+	return children
+}
+
+func TestGo(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	ctx := opentracing.ContextWithSpan(context.Background(), parent)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	otexts.Go(ctx, "child", func(ctx context.Context) error {
+		defer wg.Done()
+		otexts.LogInfoCtx(ctx, "running", nil)
+		return errors.New("error")
+	}, opentracing.Tag{Key: "task", Value: 1})
+	otexts.GoFollowsFrom(ctx, "follower", func(ctx context.Context) error {
+		defer wg.Done()
+		return nil
+	})
+	wg.Wait()
+	parent.Finish()
+
+	spans := tracer.FinishedSpans()
+	if got, want := len(spans), 3; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	parentID := parent.Context().(mocktracer.MockSpanContext).SpanID
+	for _, span := range spans[:2] {
+		if got, want := span.ParentID, parentID; got != want {
+			t.Errorf("%s parent ID: got %d, want %d", span.OperationName, got, want)
+		}
+		switch span.OperationName {
+		case "child":
+			if got, want := span.Tag(string(ext.Error)), true; got != want {
+				t.Errorf("child error tag: got %v, want %v", got, want)
+			}
+			if got, want := span.Tag("task"), 1; got != want {
+				t.Errorf("child task tag: got %v, want %v", got, want)
+			}
+			if got, want := len(span.Logs()), 2; got != want {
+				t.Errorf("child logs: got %d, want %d", got, want)
+			}
+		case "follower":
+			if got := span.Tag(string(ext.Error)); got != nil {
+				t.Errorf("follower error tag: got %v, want nil", got)
+			}
+		}
+	}
+}
+
+func TestGroup(t *testing.T) {
+	tracer := mocktracer.New()
+	parent := tracer.StartSpan("parent")
+	g, ctx := otexts.WithGroup(opentracing.ContextWithSpan(context.Background(), parent))
+	g.SetLimit(2)
+
+	errFailed := errors.New("failed")
+	for _, op := range []string{"a", "b", "c"} {
+		op := op
+		g.Go(op, func(ctx context.Context) error {
+			if op == "b" {
+				return errFailed
+			}
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}
+	if err := g.Wait(); err != errFailed {
+		t.Errorf("wait: got error %v, want %v", err, errFailed)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("group context not canceled")
+	}
+	parent.Finish()
+
+	children := finishedChildren(tracer, parent)
+	if got, want := len(children), 3; got != want {
+		t.Errorf("children: got %d %v, want %d", got, children, want)
+	}
+	for _, span := range tracer.FinishedSpans() {
+		if span.OperationName == "parent" {
+			continue
+		}
+		if got, want := span.Tag(string(ext.Error)), true; got != want {
+			t.Errorf("%s error tag: got %v, want %v", span.OperationName, got, want)
+		}
+	}
+}
+
+func TestPool(t *testing.T) {
+	tracer := mocktracer.New()
+	pool := otexts.NewPool(2)
+
+	var wg sync.WaitGroup
+	parents := make([]opentracing.Span, 10)
+	for i := range parents {
+		parents[i] = tracer.StartSpan("parent")
+		ctx := opentracing.ContextWithSpan(context.Background(), parents[i])
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := pool.Submit(ctx, "task", func(ctx context.Context) error {
+				if opentracing.SpanFromContext(ctx) == nil {
+					return errors.New("missing span")
+				}
+				return nil
+			})
+			if err != nil {
+				t.Errorf("submit: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	pool.Close()
+
+	for _, parent := range parents {
+		parent.Finish()
+		children := finishedChildren(tracer, parent)
+		if !children["task"] {
+			t.Errorf("parent %v: missing task span", parent.Context())
+		}
+	}
+	for _, span := range tracer.FinishedSpans() {
+		if got := span.Tag(string(ext.Error)); got != nil {
+			t.Errorf("%s error tag: got %v, want nil", span.OperationName, got)
+		}
+	}
+
+	if err := pool.Submit(context.Background(), "task", func(ctx context.Context) error { return nil }); err != otexts.ErrPoolClosed {
+		t.Errorf("submit after close: got error %v, want %v", err, otexts.ErrPoolClosed)
+	}
+}
+
+func TestPool_canceled(t *testing.T) {
+	tracer := mocktracer.New()
+	pool := otexts.NewPool(1)
+	defer pool.Close()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	if err := pool.Submit(context.Background(), "blocking", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+	<-started
+
+	parent := tracer.StartSpan("parent")
+	ctx, cancel := context.WithCancel(opentracing.ContextWithSpan(context.Background(), parent))
+	cancel()
+	if err := pool.Submit(ctx, "canceled", func(ctx context.Context) error { return nil }); err != context.Canceled {
+		t.Errorf("submit: got error %v, want %v", err, context.Canceled)
+	}
+	close(release)
+
+	spans := tracer.FinishedSpans()
+	if got, want := len(spans), 1; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	if got, want := spans[0].Tag(string(ext.Error)), true; got != want {
+		t.Errorf("error tag: got %v, want %v", got, want)
+	}
+}
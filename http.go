@@ -0,0 +1,164 @@
+package trace
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
+)
+
+// httpOperationName returns the operation name of the spans of the HTTP
+// requests with the specified method.
+func httpOperationName(method string) string {
+	return "HTTP " + method
+}
+
+// HTTPHandler returns an http.Handler that starts a server span with the
+// specified tracer for each request, named "HTTP " followed by the request
+// method, a child of the span context extracted from the request headers, if
+// any. The span is set in the request context, and has the HTTPTags of the
+// request and response set. A response status code of 500 or more logs an
+// error. Use a PropagatorTracer to extract the W3C Trace Context or B3
+// headers.
+func HTTPHandler(tracer opentracing.Tracer, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, _ := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(r.Header))
+		span := tracer.StartSpan(httpOperationName(r.Method), ext.RPCServerOption(sc),
+			HTTPTags{Method: r.Method, URL: r.URL.String()})
+		defer span.Finish()
+
+		rw := &statusResponseWriter{ResponseWriter: w}
+		h.ServeHTTP(rw, r.WithContext(opentracing.ContextWithSpan(r.Context(), span)))
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		SetHTTPTags(span, HTTPTags{StatusCode: rw.status})
+		if rw.status >= http.StatusInternalServerError {
+			LogError(span, errors.Errorf("HTTP status %d %s", rw.status, http.StatusText(rw.status)))
+		}
+	})
+}
+
+// Ensure statusResponseWriter implements the http.Flusher, http.Hijacker,
+// and io.ReaderFrom interfaces.
+var (
+	_ http.Flusher  = &statusResponseWriter{}
+	_ http.Hijacker = &statusResponseWriter{}
+	_ io.ReaderFrom = &statusResponseWriter{}
+)
+
+// statusResponseWriter is an http.ResponseWriter that records the response
+// status code. It implements the optional http.Flusher, http.Hijacker, and
+// io.ReaderFrom interfaces of the wrapped http.ResponseWriter, e.g. for
+// streaming responses and websocket upgrades.
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader implements the http.ResponseWriter interface.
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements the http.ResponseWriter interface.
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush implements the http.Flusher interface. It does nothing if the wrapped
+// http.ResponseWriter does not support flushing.
+func (w *statusResponseWriter) Flush() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Hijack implements the http.Hijacker interface. It returns an error
+// matching http.ErrNotSupported if the wrapped http.ResponseWriter does not
+// support hijacking, e.g. for HTTP/2 requests.
+func (w *statusResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// ReadFrom implements the io.ReaderFrom interface, with the io.ReaderFrom of
+// the wrapped http.ResponseWriter if any, e.g. to send files efficiently.
+func (w *statusResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		return rf.ReadFrom(r)
+	}
+	return io.Copy(w.ResponseWriter, r)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter, for http.ResponseController.
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Ensure httpTransport implements the http.RoundTripper interface.
+var _ http.RoundTripper = httpTransport{}
+
+// httpTransport is an http.RoundTripper that starts a client span for each
+// request.
+type httpTransport struct {
+	tracer opentracing.Tracer
+	rt     http.RoundTripper
+}
+
+// HTTPTransport returns an http.RoundTripper that starts a client span with
+// the specified tracer for each request, named "HTTP " followed by the
+// request method, a child of the span in the request context, if any. The
+// span context is injected in the request headers, and the span has the
+// HTTPTags of the request and response set. The span finishes when the
+// response headers are received, a request error is logged. If the specified
+// round tripper is nil, http.DefaultTransport is used. Use a PropagatorTracer
+// to inject the W3C Trace Context or B3 headers.
+func HTTPTransport(tracer opentracing.Tracer, rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return httpTransport{tracer: tracer, rt: rt}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t httpTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	opts := []opentracing.StartSpanOption{ext.SpanKindRPCClient, HTTPTags{Method: req.Method, URL: req.URL.String()}}
+	if parent := opentracing.SpanFromContext(req.Context()); parent != nil {
+		opts = append(opts, opentracing.ChildOf(parent.Context()))
+	}
+	span := t.tracer.StartSpan(httpOperationName(req.Method), opts...)
+	defer span.Finish()
+
+	// A RoundTripper must not modify the request.
+	req = req.Clone(req.Context())
+	if err := t.tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header)); err != nil {
+		LogWarning(span, "inject span context in request headers", map[string]interface{}{
+			LogFieldErrorObject: err,
+		})
+	}
+	resp, err := t.rt.RoundTrip(req)
+	if err != nil {
+		LogError(span, err)
+		return nil, err
+	}
+	SetHTTPTags(span, HTTPTags{StatusCode: resp.StatusCode})
+	return resp, nil
+}
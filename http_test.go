@@ -0,0 +1,200 @@
+package trace_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestHTTPHandlerAndTransport(t *testing.T) {
+	tt := []struct {
+		name   string
+		status int
+		errors bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "not found", status: http.StatusNotFound},
+		{name: "server error", status: http.StatusServiceUnavailable, errors: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, mock := newTestPropagatorTracer()
+			server := httptest.NewServer(otexts.HTTPHandler(tracer, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if opentracing.SpanFromContext(r.Context()) == nil {
+					t.Errorf("no span in the request context")
+				}
+				if r.Header.Get("Traceparent") == "" {
+					t.Errorf("no traceparent header")
+				}
+				w.WriteHeader(tc.status)
+			})))
+			defer server.Close()
+
+			parent := tracer.StartSpan("parent")
+			req, err := http.NewRequestWithContext(opentracing.ContextWithSpan(context.Background(), parent), http.MethodGet, server.URL+"/path", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: otexts.HTTPTransport(tracer, nil)}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if req.Header.Get("Traceparent") != "" {
+				t.Errorf("transport modified the request headers")
+			}
+			parent.Finish()
+
+			spans := mock.FinishedSpans()
+			if got, want := len(spans), 3; got != want {
+				t.Fatalf("finished spans: got %d, want %d", got, want)
+			}
+			serverSpan, clientSpan := spans[0], spans[1]
+			for _, span := range []*mocktracer.MockSpan{serverSpan, clientSpan} {
+				if got, want := span.OperationName, "HTTP GET"; got != want {
+					t.Errorf("operation name: got %q, want %q", got, want)
+				}
+				if got, want := span.Tag(string(ext.HTTPStatusCode)), uint16(tc.status); got != want {
+					t.Errorf("status code tag: got %v, want %v", got, want)
+				}
+			}
+			if got, want := serverSpan.ParentID, clientSpan.SpanContext.SpanID; got != want {
+				t.Errorf("server parent ID: got %d, want %d", got, want)
+			}
+			if got, want := clientSpan.ParentID, spans[2].SpanContext.SpanID; got != want {
+				t.Errorf("client parent ID: got %d, want %d", got, want)
+			}
+			if got, want := serverSpan.Tag(string(ext.SpanKind)), ext.SpanKindRPCServerEnum; got != want {
+				t.Errorf("server span kind: got %v, want %v", got, want)
+			}
+			if got, want := clientSpan.Tag(string(ext.SpanKind)), ext.SpanKindRPCClientEnum; got != want {
+				t.Errorf("client span kind: got %v, want %v", got, want)
+			}
+			if got, want := serverSpan.Tag(string(ext.Error)) == true, tc.errors; got != want {
+				t.Errorf("server error tag: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// errorTransport is an http.RoundTripper that always fails.
+type errorTransport struct{}
+
+func (errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestHTTPTransportError(t *testing.T) {
+	mock := mocktracer.New()
+	client := &http.Client{Transport: otexts.HTTPTransport(mock, errorTransport{})}
+	if _, err := client.Get("http://example.com"); err == nil {
+		t.Fatalf("request: got no error")
+	}
+	spans := mock.FinishedSpans()
+	if got, want := len(spans), 1; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	if got, want := spans[0].Tag(string(ext.Error)), true; got != want {
+		t.Errorf("error tag: got %v, want %v", got, want)
+	}
+}
+
+func TestHTTPHandlerFlushHijack(t *testing.T) {
+	mock := mocktracer.New()
+	handler := otexts.HTTPHandler(mock, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/flush" {
+			w.Write([]byte("flushed"))
+			w.(http.Flusher).Flush()
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("hijack: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 8\r\nConnection: close\r\n\r\nhijacked")
+		rw.Flush()
+	}))
+	// The response may be read before the handler finishes the span.
+	done := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.ServeHTTP(w, r)
+		done <- struct{}{}
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/flush", "/hijack"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("request %s: %v", path, err)
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		if got, want := string(b), path[1:]+"ed"; got != want {
+			t.Errorf("%s body: got %q, want %q", path, got, want)
+		}
+		<-done
+	}
+	spans := mock.FinishedSpans()
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	for i, want := range []uint16{http.StatusOK, http.StatusSwitchingProtocols} {
+		if got := spans[i].Tag(string(ext.HTTPStatusCode)); got != want {
+			t.Errorf("span %d status code tag: got %v, want %v", i, got, want)
+		}
+	}
+}
+
+// failingInjector is a mocktracer injector that always fails.
+type failingInjector struct{}
+
+func (failingInjector) Inject(mocktracer.MockSpanContext, interface{}) error {
+	return opentracing.ErrInvalidCarrier
+}
+
+func TestHTTPTransportInjectError(t *testing.T) {
+	mock := mocktracer.New()
+	mock.RegisterInjector(opentracing.HTTPHeaders, failingInjector{})
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: otexts.HTTPTransport(mock, nil)}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	resp.Body.Close()
+
+	span := mock.FinishedSpans()[0]
+	if got, want := len(span.Logs()), 1; got != want {
+		t.Fatalf("logs: got %d, want %d", got, want)
+	}
+	var found bool
+	for _, f := range span.Logs()[0].Fields {
+		if f.Key == otexts.LogFieldErrorObject && f.ValueString == opentracing.ErrInvalidCarrier.Error() {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("inject error not logged: %v", span.Logs()[0].Fields)
+	}
+	if got := span.Tag(string(ext.Error)); got != nil {
+		t.Errorf("error tag: got %v, want not set", got)
+	}
+}
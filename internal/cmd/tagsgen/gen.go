@@ -0,0 +1,502 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Generator generates the tag option types and tests of a spec.
+type Generator struct {
+	Package    string // The generated code package name.
+	ImportPath string // The generated code package import path.
+	SpecPath   string // The spec file path, used in the generated file header.
+}
+
+// Code returns the generated tag option types of the specified spec.
+func (g Generator) Code(spec *Spec) ([]byte, error) {
+	imports := map[string]bool{
+		"context":                                   true,
+		"github.com/opentracing/opentracing-go":     true,
+		"github.com/opentracing/opentracing-go/ext": true,
+	}
+	var body bytes.Buffer
+	for _, grp := range spec.Groups {
+		for _, f := range grp.Fields {
+			for _, imp := range fieldTypes[f.Type].imports {
+				imports[imp] = true
+			}
+			if f.Transform == "lower" {
+				imports["strings"] = true
+			}
+		}
+		if grp.Internal {
+			writeInternalGroup(&body, grp)
+		} else {
+			writeGroup(&body, grp)
+		}
+	}
+	writeOTelKeys(&body, spec)
+
+	var b bytes.Buffer
+	g.writeHeader(&b, g.Package, imports)
+	b.Write(body.Bytes())
+	return formatSource(&b)
+}
+
+// Tests returns the generated tests of the tag option types of the specified
+// spec.
+func (g Generator) Tests(spec *Spec) ([]byte, error) {
+	imports := map[string]bool{
+		"fmt":     true,
+		"testing": true,
+		"github.com/opentracing/opentracing-go/mocktracer": true,
+		"otexts " + strconv.Quote(g.ImportPath):            true,
+	}
+	var body bytes.Buffer
+	for _, grp := range spec.Groups {
+		if grp.Internal {
+			continue
+		}
+		for _, f := range grp.allFields() {
+			for _, imp := range fieldTypes[f.Type].imports {
+				imports[imp] = true
+			}
+		}
+		writeGroupTest(&body, grp)
+	}
+	body.WriteString(`
+func checkSpecTags(t *testing.T, want map[string]string, tags map[string]interface{}) {
+	t.Helper()
+	if got, want := len(tags), len(want); got != want {
+		t.Errorf("tags: got %d, want %d: %v", got, want, tags)
+	}
+	for k, v := range want {
+		if got := fmt.Sprint(tags[k]); got != v {
+			t.Errorf("tag %q: got %q, want %q", k, got, v)
+		}
+	}
+}
+`)
+
+	var b bytes.Buffer
+	g.writeHeader(&b, g.Package+"_test", imports)
+	b.Write(body.Bytes())
+	return formatSource(&b)
+}
+
+// writeHeader writes the generated file header, package clause, and imports.
+func (g Generator) writeHeader(b *bytes.Buffer, pkg string, imports map[string]bool) {
+	fmt.Fprintf(b, "// Code generated by tagsgen from %s. DO NOT EDIT.\n\n", filepath.ToSlash(g.SpecPath))
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+	// Group the standard library, third party, and this module's imports.
+	groups := make([][]string, 3)
+	for imp := range imports {
+		spec := imp
+		if !strings.Contains(imp, `"`) {
+			spec = strconv.Quote(imp)
+		}
+		switch path := importPath(spec); {
+		case path == strconv.Quote(g.ImportPath):
+			groups[2] = append(groups[2], spec)
+		case strings.Contains(path, "."):
+			groups[1] = append(groups[1], spec)
+		default:
+			groups[0] = append(groups[0], spec)
+		}
+	}
+	b.WriteString("import (")
+	for _, group := range groups {
+		if len(group) == 0 {
+			continue
+		}
+		sort.Slice(group, func(i, j int) bool {
+			return importPath(group[i]) < importPath(group[j])
+		})
+		b.WriteString("\n")
+		for _, imp := range group {
+			fmt.Fprintf(b, "\t%s\n", imp)
+		}
+	}
+	b.WriteString(")\n")
+}
+
+// importPath returns the path of an import spec.
+func importPath(spec string) string {
+	return spec[strings.Index(spec, `"`):]
+}
+
+func writeGroup(b *bytes.Buffer, g *Group) {
+	typeName := g.Name + "Tags"
+	fmt.Fprintf(b, "\n// Ensure %s implements the opentracing.StartSpanOption interface.\n", typeName)
+	fmt.Fprintf(b, "var _ opentracing.StartSpanOption = (*%s)(nil)\n\n", typeName)
+
+	doc := fmt.Sprintf("%s is an opentracing.StartSpanOption that sets the standard %s tags", typeName, g.Title)
+	if g.Context != "" {
+		doc += " " + g.Context
+	}
+	writeDoc(b, "", doc+".")
+	if g.See != "" {
+		b.WriteString("//\n")
+		fmt.Fprintf(b, "// See %s.\n", g.See)
+	}
+	writeStruct(b, typeName, g)
+
+	b.WriteString("\n// Apply implements the opentracing.StartSpanOption interface.\n")
+	fmt.Fprintf(b, "func (t %s) Apply(opts *opentracing.StartSpanOptions) {\n", typeName)
+	writeApplyBody(b, g, "t."+modeField)
+	for _, inc := range g.Include {
+		fmt.Fprintf(b, "\t%s.apply(opts, t.%s)\n", includeLiteral(inc), modeField)
+	}
+	b.WriteString("}\n\n")
+
+	writeDoc(b, "", fmt.Sprintf("Set%s sets the standard %s tags on the specified span.", typeName, g.Title))
+	fmt.Fprintf(b, "func Set%s(span opentracing.Span, t %s) {\n", typeName, typeName)
+	writeSetBody(b, g, "t."+modeField)
+	for _, inc := range g.Include {
+		fmt.Fprintf(b, "\t%s.set(span, t.%s)\n", includeLiteral(inc), modeField)
+	}
+	b.WriteString("}\n\n")
+
+	writeDoc(b, "", fmt.Sprintf("Set%sCtx calls Set%s with the span from the specified context, if any.", typeName, typeName))
+	fmt.Fprintf(b, "func Set%sCtx(ctx context.Context, t %s) {\n", typeName, typeName)
+	fmt.Fprintf(b, "\tSet%s(opentracing.SpanFromContext(ctx), t)\n", typeName)
+	b.WriteString("}\n")
+}
+
+func writeInternalGroup(b *bytes.Buffer, g *Group) {
+	typeName := g.Name + "Tags"
+	b.WriteString("\n")
+	writeDoc(b, "", fmt.Sprintf("%s are the standard %s tags.", typeName, g.Title))
+	writeStruct(b, typeName, g)
+
+	b.WriteString("\n")
+	writeDoc(b, "", fmt.Sprintf("apply sets the standard %s tags in the specified span options, with the specified tag mode.", g.Title))
+	fmt.Fprintf(b, "func (t %s) apply(opts *opentracing.StartSpanOptions, mode TagMode) {\n", typeName)
+	writeApplyBody(b, g, "mode")
+	b.WriteString("}\n\n")
+
+	writeDoc(b, "", fmt.Sprintf("set sets the standard %s tags on the specified span, with the specified tag mode.", g.Title))
+	fmt.Fprintf(b, "func (t %s) set(span opentracing.Span, mode TagMode) {\n", typeName)
+	writeSetBody(b, g, "mode")
+	b.WriteString("}\n")
+}
+
+func writeStruct(b *bytes.Buffer, typeName string, g *Group) {
+	fmt.Fprintf(b, "type %s struct {\n", typeName)
+	for _, f := range g.Fields {
+		fmt.Fprintf(b, "\t%s %s // %s\n", f.Name, fieldTypes[f.Type].goType, f.Doc)
+	}
+	for _, inc := range g.Include {
+		if len(g.Fields) > 0 {
+			b.WriteString("\n")
+		}
+		if inc.Doc != "" {
+			writeDoc(b, "\t", inc.Doc)
+		}
+		for _, f := range inc.group.Fields {
+			fmt.Fprintf(b, "\t%s%s %s // %s\n", inc.Prefix, f.Name, fieldTypes[f.Type].goType, f.Doc)
+		}
+	}
+	if !g.Internal {
+		b.WriteString("\n")
+		writeDoc(b, "\t", "Mode selects the opentracing tag names, the OpenTelemetry attribute names, or both. The zero value uses the global tag mode, see SetTagMode.")
+		fmt.Fprintf(b, "\t%s TagMode\n", modeField)
+	}
+	b.WriteString("}\n")
+}
+
+func writeApplyBody(b *bytes.Buffer, g *Group, mode string) {
+	b.WriteString("\tif opts == nil {\n\t\treturn\n\t}\n")
+	b.WriteString("\tif opts.Tags == nil {\n\t\topts.Tags = make(map[string]interface{})\n\t}\n")
+	for _, f := range g.Fixed {
+		fmt.Fprintf(b, "\topts.Tags[%s] = %s\n", keyExpr(f.Key, f.Ext), fieldTypes[f.Type].literalExpr(f.Value))
+	}
+	prior := g.otelPrior()
+	for _, f := range g.Fields {
+		writeCond(b, f, prior[f], mode, "opts.Tags[%s] = "+f.applyExpr())
+	}
+}
+
+func writeSetBody(b *bytes.Buffer, g *Group, mode string) {
+	b.WriteString("\tif span == nil {\n\t\treturn\n\t}\n")
+	for _, f := range g.Fixed {
+		fmt.Fprintf(b, "\tspan.SetTag(%s, %s)\n", keyExpr(f.Key, f.Ext), fieldTypes[f.Type].literalExpr(f.Value))
+	}
+	prior := g.otelPrior()
+	for _, f := range g.Fields {
+		writeCond(b, f, prior[f], mode, "span.SetTag(%s, "+f.setExpr()+")")
+	}
+}
+
+// otelPrior returns the fields of the group whose OpenTelemetry attribute
+// name is shared with a prior field, mapped to the first such field. The
+// attribute is set by the first field of the group whose tag is set.
+func (g *Group) otelPrior() map[*Field][]*Field {
+	prior := make(map[*Field][]*Field)
+	first := make(map[string][]*Field)
+	for _, f := range g.Fields {
+		if f.OTel == "" {
+			continue
+		}
+		prior[f] = first[f.OTel]
+		first[f.OTel] = append(first[f.OTel], f)
+	}
+	return prior
+}
+
+// writeCond writes the specified statement format, formatted with the tag
+// name, guarded by the condition that the field tag is set, if any. If the
+// field has an OpenTelemetry attribute name, the statement is repeated for
+// each name selected by the specified tag mode expression, without the
+// OpenTelemetry attribute name if the tag of a prior field sharing it is set.
+func writeCond(b *bytes.Buffer, f *Field, prior []*Field, mode, stmtFormat string) {
+	stmt := fmt.Sprintf(stmtFormat, keyExpr(f.Key, f.Ext))
+	switch {
+	case len(prior) > 0:
+		conds := make([]string, len(prior))
+		for i, p := range prior {
+			conds[i] = p.condExpr()
+			if conds[i] == "" {
+				conds[i] = "true"
+			}
+		}
+		stmt = fmt.Sprintf("for _, k := range %s.keysWithoutOTel(%s, %s) {\n%s\n}", mode, keyExpr(f.Key, f.Ext), strings.Join(conds, " || "), fmt.Sprintf(stmtFormat, "k"))
+	case f.OTel != "":
+		stmt = fmt.Sprintf("for _, k := range %s.keys(%s) {\n%s\n}", mode, keyExpr(f.Key, f.Ext), fmt.Sprintf(stmtFormat, "k"))
+	}
+	cond := f.condExpr()
+	if cond == "" {
+		fmt.Fprintf(b, "\t%s\n", stmt)
+		return
+	}
+	fmt.Fprintf(b, "\tif %s {\n\t\t%s\n\t}\n", cond, stmt)
+}
+
+// writeOTelKeys writes the map of the OpenTelemetry attribute names of the
+// tags, by tag name.
+func writeOTelKeys(b *bytes.Buffer, spec *Spec) {
+	b.WriteString("\n// otelTagKeys are the OpenTelemetry attribute names of the standard tags, by\n// tag name.\n")
+	b.WriteString("var otelTagKeys = map[string]string{\n")
+	written := make(map[string]bool)
+	for _, grp := range spec.Groups {
+		for _, f := range grp.Fields {
+			if f.OTel == "" || written[f.Key] {
+				continue
+			}
+			written[f.Key] = true
+			fmt.Fprintf(b, "\t%s: %q,\n", keyExpr(f.Key, f.Ext), f.OTel)
+		}
+	}
+	for _, t := range spec.Tags {
+		fmt.Fprintf(b, "\t%s: %q,\n", keyExpr(t.Key, t.Ext), t.OTel)
+	}
+	b.WriteString("}\n")
+}
+
+// includeLiteral returns the composite literal of an included group's struct
+// from the including group's struct fields.
+func includeLiteral(inc *Include) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sTags{\n", inc.group.Name)
+	for _, f := range inc.group.Fields {
+		fmt.Fprintf(&b, "\t\t%s: t.%s%s,\n", f.Name, inc.Prefix, f.Name)
+	}
+	b.WriteString("\t}")
+	return b.String()
+}
+
+// condExpr returns the condition that the field tag is set, or empty if the
+// tag is always set.
+func (f *Field) condExpr() string {
+	v := "t." + f.Name
+	if len(f.Enum) > 0 {
+		conds := make([]string, len(f.Enum))
+		for i, e := range f.Enum {
+			conds[i] = fmt.Sprintf("%s == %q", v, e)
+		}
+		return strings.Join(conds, " || ")
+	}
+	if f.Required {
+		return ""
+	}
+	return fmt.Sprintf(fieldTypes[f.Type].present, v)
+}
+
+// applyExpr returns the tag value set in the span options.
+func (f *Field) applyExpr() string {
+	return f.transform(fmt.Sprintf(fieldTypes[f.Type].apply, "t."+f.Name))
+}
+
+// setExpr returns the tag value set on the span.
+func (f *Field) setExpr() string {
+	v := f.transform(fmt.Sprintf(fieldTypes[f.Type].set, "t."+f.Name))
+	if f.SetType != "" {
+		v = f.SetType + "(" + v + ")"
+	}
+	return v
+}
+
+func (f *Field) transform(v string) string {
+	if f.Transform == "lower" {
+		return "strings.ToLower(" + v + ")"
+	}
+	return v
+}
+
+// example returns the Go expression of the sample field value and the
+// expected tag value.
+func (f *Field) example() (string, string) {
+	typ := fieldTypes[f.Type]
+	switch {
+	case len(f.Enum) > 0:
+		return strconv.Quote(f.Enum[0]), f.Enum[0]
+	case f.Transform == "lower":
+		return strconv.Quote(strings.ToUpper(typ.sample)), strings.ToLower(typ.sample)
+	}
+	return typ.example, typ.sample
+}
+
+// prefixedField is a struct field of a group, including the fields of the
+// included groups.
+type prefixedField struct {
+	*Field
+	Name string
+}
+
+// allFields returns the struct fields of the group, including the fields of
+// the included groups.
+func (g *Group) allFields() []prefixedField {
+	var fields []prefixedField
+	for _, f := range g.Fields {
+		fields = append(fields, prefixedField{Field: f, Name: f.Name})
+	}
+	for _, inc := range g.Include {
+		for _, f := range inc.group.Fields {
+			fields = append(fields, prefixedField{Field: f, Name: inc.Prefix + f.Name})
+		}
+	}
+	return fields
+}
+
+func writeGroupTest(b *bytes.Buffer, g *Group) {
+	typeName := g.Name + "Tags"
+	fixed := make(map[string]string)
+	for _, f := range g.Fixed {
+		fixed[f.Key] = f.Value
+	}
+	empty := copyTags(fixed)
+	all := copyTags(fixed)
+	allOTel := copyTags(fixed)
+	allBoth := copyTags(fixed)
+	var allFields []string
+	for _, f := range g.allFields() {
+		if f.Required {
+			empty[f.Key] = fieldTypes[f.Type].zero
+		}
+		expr, want := f.example()
+		allFields = append(allFields, fmt.Sprintf("%s: %s", f.Name, expr))
+		all[f.Key] = want
+		allBoth[f.Key] = want
+		if f.OTel != "" {
+			// The first field sharing an OpenTelemetry attribute name sets it.
+			if _, ok := allOTel[f.OTel]; !ok {
+				allOTel[f.OTel] = want
+				allBoth[f.OTel] = want
+			}
+		} else {
+			allOTel[f.Key] = want
+		}
+	}
+	otelFields := append(allFields[:len(allFields):len(allFields)], modeField+": otexts.TagModeOTel")
+	bothFields := append(allFields[:len(allFields):len(allFields)], modeField+": otexts.TagModeBoth")
+
+	fmt.Fprintf(b, "\nfunc Test%s_spec(t *testing.T) {\n", typeName)
+	b.WriteString("\ttt := []struct {\n\t\tname string\n")
+	fmt.Fprintf(b, "\t\ttags otexts.%s\n", typeName)
+	b.WriteString("\t\twant map[string]string\n\t}{\n")
+	writeTestCase(b, "no tags", typeName, nil, empty)
+	writeTestCase(b, "all tags", typeName, allFields, all)
+	writeTestCase(b, "all tags OpenTelemetry", typeName, otelFields, allOTel)
+	writeTestCase(b, "all tags both", typeName, bothFields, allBoth)
+	for _, f := range g.allFields() {
+		if len(f.Enum) == 0 {
+			continue
+		}
+		writeTestCase(b, "invalid "+f.Name, typeName, []string{f.Name + `: "invalid"`}, empty)
+	}
+	b.WriteString("\t}\n")
+	fmt.Fprintf(b, `	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("test", tc.tags).(*mocktracer.MockSpan)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+
+			span = mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+			otexts.Set%s(span, tc.tags)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+		})
+	}
+}
+`, typeName)
+}
+
+func writeTestCase(b *bytes.Buffer, name, typeName string, fields []string, want map[string]string) {
+	b.WriteString("\t\t{\n")
+	fmt.Fprintf(b, "\t\t\tname: %q,\n", name)
+	if len(fields) > 0 {
+		fmt.Fprintf(b, "\t\t\ttags: otexts.%s{\n", typeName)
+		for _, f := range fields {
+			fmt.Fprintf(b, "\t\t\t\t%s,\n", f)
+		}
+		b.WriteString("\t\t\t},\n")
+	}
+	b.WriteString("\t\t\twant: map[string]string{\n")
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(b, "\t\t\t\t%q: %q,\n", k, want[k])
+	}
+	b.WriteString("\t\t\t},\n\t\t},\n")
+}
+
+func copyTags(tags map[string]string) map[string]string {
+	c := make(map[string]string, len(tags))
+	for k, v := range tags {
+		c[k] = v
+	}
+	return c
+}
+
+// writeDoc writes a doc comment with the specified indent, wrapping the text
+// at 80 columns.
+func writeDoc(b *bytes.Buffer, indent, text string) {
+	const width = 80
+	line := indent + "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > width && len(line) > len(indent)+2 {
+			b.WriteString(line + "\n")
+			line = indent + "//"
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\n")
+}
+
+// formatSource returns the gofmt formatted source, or an error with the
+// unformatted source if it is invalid.
+func formatSource(b *bytes.Buffer) ([]byte, error) {
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("invalid generated code: %v\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGenerated ensures the generated files in the repository root are up to
+// date with the spec.
+func TestGenerated(t *testing.T) {
+	root := filepath.Join("..", "..", "..")
+	f, err := os.Open(filepath.Join(root, "tags.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	spec, err := ParseSpec(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := Generator{
+		Package:    "trace",
+		ImportPath: "github.com/code-willing/opentracing-exts",
+		SpecPath:   "tags.json",
+	}
+	code, err := g.Code(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests, err := g.Tests(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string][]byte{"tags_gen.go": code, "tags_gen_test.go": tests} {
+		got, err := os.ReadFile(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is out of date, run go generate", name)
+		}
+	}
+}
+
+func TestParseSpec(t *testing.T) {
+	tt := []struct {
+		name string
+		spec string
+		err  string
+	}{
+		{
+			name: "valid",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "type": "string", "doc": "Foo."}]}]}`,
+		},
+		{
+			name: "unknown field",
+			spec: `{"groups": [{"name": "Test", "title": "test", "bar": true}]}`,
+			err:  "unknown field",
+		},
+		{
+			name: "unexported group",
+			spec: `{"groups": [{"name": "test", "title": "test"}]}`,
+			err:  "must be exported",
+		},
+		{
+			name: "duplicate key",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "type": "string", "doc": "Foo."}, {"name": "Bar", "key": "foo", "type": "string", "doc": "Bar."}]}]}`,
+			err:  "duplicate key",
+		},
+		{
+			name: "invalid type",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "type": "float", "doc": "Foo."}]}]}`,
+			err:  "invalid type",
+		},
+		{
+			name: "invalid transform",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "type": "int", "transform": "lower", "doc": "Foo."}]}]}`,
+			err:  "requires a string type",
+		},
+		{
+			name: "reserved field name",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Mode", "key": "mode", "type": "string", "doc": "Mode."}]}]}`,
+			err:  "reserved name",
+		},
+		{
+			name: "OpenTelemetry name is the tag name",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "otel": "foo", "type": "string", "doc": "Foo."}]}]}`,
+			err:  "is the tag name",
+		},
+		{
+			name: "conflicting OpenTelemetry names",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "otel": "bar", "type": "string", "doc": "Foo."}]}, {"name": "Other", "title": "other", "fields": [{"name": "Foo", "key": "foo", "otel": "baz", "type": "string", "doc": "Foo."}]}]}`,
+			err:  "conflicting OpenTelemetry attribute names",
+		},
+		{
+			name: "tag",
+			spec: `{"groups": [], "tags": [{"key": "foo", "otel": "bar"}]}`,
+		},
+		{
+			name: "tag without OpenTelemetry name",
+			spec: `{"groups": [], "tags": [{"key": "foo"}]}`,
+			err:  "invalid OpenTelemetry attribute name",
+		},
+		{
+			name: "tag set by a group",
+			spec: `{"groups": [{"name": "Test", "title": "test", "fields": [{"name": "Foo", "key": "foo", "otel": "bar", "type": "string", "doc": "Foo."}]}], "tags": [{"key": "foo", "otel": "baz"}]}`,
+			err:  "set by a group field",
+		},
+		{
+			name: "unknown include",
+			spec: `{"groups": [{"name": "Test", "title": "test", "include": [{"group": "peer"}]}]}`,
+			err:  "unknown internal group",
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseSpec(strings.NewReader(tc.spec))
+			switch {
+			case tc.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tc.err != "" && err == nil:
+				t.Fatalf("expected error %q", tc.err)
+			case tc.err != "" && !strings.Contains(err.Error(), tc.err):
+				t.Fatalf("error: got %q, want %q", err, tc.err)
+			}
+		})
+	}
+}
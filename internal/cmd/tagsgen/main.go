@@ -0,0 +1,64 @@
+// Command tagsgen generates opentracing span tag option types from a JSON
+// description of tag groups.
+//
+// For each tag group, tagsgen generates a struct with a field per tag, an
+// Apply method implementing the opentracing.StartSpanOption interface, a
+// Set<Name>Tags function, a Set<Name>TagsCtx function, and table-driven tests.
+// Internal tag groups generate an unexported struct with apply and set
+// methods, for groups of tags that are included in other groups.
+//
+// Usage:
+//
+//	tagsgen -spec tags.json -out tags_gen.go -test tags_gen_test.go
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("tagsgen: ")
+
+	specPath := flag.String("spec", "tags.json", "The tag groups spec file.")
+	outPath := flag.String("out", "tags_gen.go", "The generated code file.")
+	testPath := flag.String("test", "tags_gen_test.go", "The generated test file, if set.")
+	pkg := flag.String("pkg", "trace", "The generated code package name.")
+	importPath := flag.String("import", "github.com/code-willing/opentracing-exts", "The generated code package import path, used by the generated tests.")
+	flag.Parse()
+
+	f, err := os.Open(*specPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	spec, err := ParseSpec(f)
+	f.Close()
+	if err != nil {
+		log.Fatalf("%s: %v", *specPath, err)
+	}
+
+	g := Generator{
+		Package:    *pkg,
+		ImportPath: *importPath,
+		SpecPath:   *specPath,
+	}
+	code, err := g.Code(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*outPath, code, 0644); err != nil {
+		log.Fatal(err)
+	}
+	if *testPath == "" {
+		return
+	}
+	tests, err := g.Tests(spec)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*testPath, tests, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"strconv"
+)
+
+// Spec is a description of tag groups.
+type Spec struct {
+	Groups []*Group `json:"groups"`
+	Tags   []*Tag   `json:"tags"` // The standard tags not set by the groups with an OpenTelemetry attribute name.
+}
+
+// Tag is a standard tag not set by the tag groups, with an OpenTelemetry
+// attribute name.
+type Tag struct {
+	Key  string `json:"key"`  // The tag name.
+	Ext  string `json:"ext"`  // The optional opentracing ext tag name constant.
+	OTel string `json:"otel"` // The OpenTelemetry attribute name.
+}
+
+// Group is a group of tags set by a generated tag option type.
+type Group struct {
+	Name     string     `json:"name"`     // The type name, without the "Tags" suffix.
+	Title    string     `json:"title"`    // The tag group title used in doc comments, e.g. "database".
+	Context  string     `json:"context"`  // The optional context used in the type doc comment.
+	See      string     `json:"see"`      // The optional specification URL.
+	Internal bool       `json:"internal"` // Whether the group is only included in other groups.
+	Fixed    []*Fixed   `json:"fixed"`    // The tags always set to a fixed value.
+	Fields   []*Field   `json:"fields"`   // The tags set from the struct fields.
+	Include  []*Include `json:"include"`  // The included internal groups.
+}
+
+// modeField is the name of the tag mode struct field of the generated types.
+const modeField = "Mode"
+
+// Field is a tag set from a struct field.
+type Field struct {
+	Name      string   `json:"name"`      // The struct field name.
+	Key       string   `json:"key"`       // The tag name.
+	Ext       string   `json:"ext"`       // The optional opentracing ext tag name constant.
+	OTel      string   `json:"otel"`      // The optional OpenTelemetry attribute name, set by the first field of a group sharing it whose tag is set.
+	Type      string   `json:"type"`      // The field type, see fieldTypes.
+	SetType   string   `json:"setType"`   // The optional tag value type when set on a span.
+	Transform string   `json:"transform"` // The optional value transform, "lower".
+	Required  bool     `json:"required"`  // Whether the tag is set even if empty.
+	Enum      []string `json:"enum"`      // The optional allowed values.
+	Doc       string   `json:"doc"`       // The struct field doc comment.
+}
+
+// Fixed is a tag always set to a fixed value.
+type Fixed struct {
+	Key   string `json:"key"`   // The tag name.
+	Ext   string `json:"ext"`   // The optional opentracing ext tag name constant.
+	Type  string `json:"type"`  // The value type, see fieldTypes.
+	Value string `json:"value"` // The tag value.
+}
+
+// Include is an internal group included in a group.
+type Include struct {
+	Group  string `json:"group"`  // The included group name.
+	Prefix string `json:"prefix"` // The prefix of the included struct field names.
+	Doc    string `json:"doc"`    // The doc comment of the included struct fields.
+
+	group *Group
+}
+
+// fieldType describes how the tags of a field type are generated.
+type fieldType struct {
+	goType  string   // The Go type of the struct field.
+	present string   // The format of the condition that the tag is set, or empty.
+	apply   string   // The format of the tag value set in the span options.
+	set     string   // The format of the tag value set on the span.
+	literal string   // The format of a literal value.
+	zero    string   // The tag value of a required field's zero value.
+	sample  string   // The sample tag value used in the generated tests.
+	example string   // The Go expression of the sample field value.
+	imports []string // The imports required by the Go type.
+}
+
+// fieldTypes are the supported field types.
+var fieldTypes = map[string]fieldType{
+	"string": {
+		zero:    "",
+		goType:  "string",
+		present: `%s != ""`,
+		apply:   "%s",
+		set:     "%s",
+		literal: "%q",
+		sample:  "test",
+		example: `"test"`,
+	},
+	"int": {
+		zero:    "0",
+		goType:  "int",
+		present: "%s > 0",
+		apply:   "%s",
+		set:     "%s",
+		literal: "%s",
+		sample:  "200",
+		example: "200",
+	},
+	"uint16": {
+		zero:    "0",
+		goType:  "uint16",
+		present: "%s > 0",
+		apply:   "%s",
+		set:     "%s",
+		literal: "%s",
+		sample:  "8080",
+		example: "8080",
+	},
+	"ipv4": {
+		zero:    "<nil>",
+		goType:  "net.IP",
+		present: "%s != nil",
+		apply:   "%s.String()",
+		set:     "%s.String()",
+		sample:  "127.0.0.1",
+		example: "net.IPv4(127, 0, 0, 1)",
+		imports: []string{"net"},
+	},
+	"ipv6": {
+		zero:    "<nil>",
+		goType:  "net.IP",
+		present: "%s != nil",
+		apply:   "%s.String()",
+		set:     "%s.String()",
+		sample:  "::1",
+		example: "net.IPv6loopback",
+		imports: []string{"net"},
+	},
+	"spankind": {
+		zero:    "",
+		goType:  "ext.SpanKindEnum",
+		present: `%s != ""`,
+		apply:   "string(%s)",
+		set:     "%s",
+		literal: "ext.SpanKindEnum(%q)",
+		sample:  "client",
+		example: `"client"`,
+	},
+}
+
+// ParseSpec parses and validates a JSON spec.
+func ParseSpec(r io.Reader) (*Spec, error) {
+	var spec Spec
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&spec); err != nil {
+		return nil, err
+	}
+	if err := spec.validate(); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+// validate validates the spec and resolves the included groups.
+func (s *Spec) validate() error {
+	groups := make(map[string]*Group)
+	for _, g := range s.Groups {
+		if !token.IsIdentifier(g.Name) {
+			return fmt.Errorf("group %q: invalid name", g.Name)
+		}
+		if _, ok := groups[g.Name]; ok {
+			return fmt.Errorf("group %q: duplicate name", g.Name)
+		}
+		if g.Internal && token.IsExported(g.Name) {
+			return fmt.Errorf("group %q: internal group name must be unexported", g.Name)
+		}
+		if !g.Internal && !token.IsExported(g.Name) {
+			return fmt.Errorf("group %q: group name must be exported", g.Name)
+		}
+		if g.Title == "" {
+			return fmt.Errorf("group %q: missing title", g.Name)
+		}
+		groups[g.Name] = g
+	}
+	otel := make(map[string]string)
+	for _, g := range s.Groups {
+		for _, f := range g.Fields {
+			if f.OTel == "" {
+				continue
+			}
+			if o, ok := otel[f.Key]; ok && o != f.OTel {
+				return fmt.Errorf("group %q: field %q: conflicting OpenTelemetry attribute names %q and %q", g.Name, f.Name, o, f.OTel)
+			}
+			otel[f.Key] = f.OTel
+		}
+	}
+	keys := make(map[string]bool)
+	for _, t := range s.Tags {
+		if err := validateKey(t.Key, t.Ext, keys); err != nil {
+			return fmt.Errorf("tag: %v", err)
+		}
+		if t.OTel == "" || t.OTel == t.Key {
+			return fmt.Errorf("tag %q: invalid OpenTelemetry attribute name %q", t.Key, t.OTel)
+		}
+		if _, ok := otel[t.Key]; ok {
+			return fmt.Errorf("tag %q: set by a group field", t.Key)
+		}
+	}
+	for _, g := range s.Groups {
+		names := make(map[string]bool)
+		keys := make(map[string]bool)
+		for _, f := range g.Fixed {
+			if err := validateKey(f.Key, f.Ext, keys); err != nil {
+				return fmt.Errorf("group %q: fixed tag: %v", g.Name, err)
+			}
+			typ, ok := fieldTypes[f.Type]
+			if !ok || typ.literal == "" {
+				return fmt.Errorf("group %q: fixed tag %q: invalid type %q", g.Name, f.Key, f.Type)
+			}
+		}
+		for _, f := range g.Fields {
+			if err := f.validate(names, keys); err != nil {
+				return fmt.Errorf("group %q: %v", g.Name, err)
+			}
+		}
+		for _, inc := range g.Include {
+			inc.group = groups[inc.Group]
+			if inc.group == nil || !inc.group.Internal {
+				return fmt.Errorf("group %q: include %q: unknown internal group", g.Name, inc.Group)
+			}
+			if len(inc.group.Include) > 0 {
+				return fmt.Errorf("group %q: include %q: nested includes are not supported", g.Name, inc.Group)
+			}
+			for _, f := range inc.group.Fields {
+				if names[inc.Prefix+f.Name] {
+					return fmt.Errorf("group %q: include %q: duplicate field %q", g.Name, inc.Group, inc.Prefix+f.Name)
+				}
+				names[inc.Prefix+f.Name] = true
+				if keys[f.Key] {
+					return fmt.Errorf("group %q: include %q: duplicate tag %q", g.Name, inc.Group, f.Key)
+				}
+				keys[f.Key] = true
+			}
+		}
+	}
+	return nil
+}
+
+// validate validates the field, adding its name and tag name to the specified
+// sets.
+func (f *Field) validate(names, keys map[string]bool) error {
+	if !token.IsIdentifier(f.Name) || !token.IsExported(f.Name) {
+		return fmt.Errorf("field %q: invalid name", f.Name)
+	}
+	if f.Name == modeField {
+		return fmt.Errorf("field %q: reserved name", f.Name)
+	}
+	if names[f.Name] {
+		return fmt.Errorf("field %q: duplicate name", f.Name)
+	}
+	names[f.Name] = true
+	if err := validateKey(f.Key, f.Ext, keys); err != nil {
+		return fmt.Errorf("field %q: %v", f.Name, err)
+	}
+	if f.OTel == f.Key {
+		return fmt.Errorf("field %q: OpenTelemetry attribute name %q is the tag name", f.Name, f.OTel)
+	}
+	typ, ok := fieldTypes[f.Type]
+	if !ok {
+		return fmt.Errorf("field %q: invalid type %q", f.Name, f.Type)
+	}
+	switch f.SetType {
+	case "", "int", "uint16", "string":
+	default:
+		return fmt.Errorf("field %q: invalid set type %q", f.Name, f.SetType)
+	}
+	switch f.Transform {
+	case "":
+	case "lower":
+		if typ.goType != "string" {
+			return fmt.Errorf("field %q: transform %q requires a string type", f.Name, f.Transform)
+		}
+	default:
+		return fmt.Errorf("field %q: invalid transform %q", f.Name, f.Transform)
+	}
+	if len(f.Enum) > 0 && typ.literal == "" {
+		return fmt.Errorf("field %q: enum values are not supported for type %q", f.Name, f.Type)
+	}
+	if len(f.Enum) > 0 && f.Required {
+		return fmt.Errorf("field %q: enum fields cannot be required", f.Name)
+	}
+	if f.Doc == "" {
+		return fmt.Errorf("field %q: missing doc", f.Name)
+	}
+	return nil
+}
+
+// validateKey validates a tag name, adding it to the specified set.
+func validateKey(key, ext string, keys map[string]bool) error {
+	if key == "" {
+		return fmt.Errorf("missing key")
+	}
+	if keys[key] {
+		return fmt.Errorf("duplicate key %q", key)
+	}
+	keys[key] = true
+	if ext != "" && !token.IsIdentifier(ext) {
+		return fmt.Errorf("key %q: invalid ext constant %q", key, ext)
+	}
+	return nil
+}
+
+// keyExpr returns the Go expression of a tag name.
+func keyExpr(key, ext string) string {
+	if ext != "" {
+		return "string(ext." + ext + ")"
+	}
+	return strconv.Quote(key)
+}
+
+// literalExpr returns the Go expression of a literal value of the field type.
+func (t fieldType) literalExpr(v string) string {
+	return fmt.Sprintf(t.literal, v)
+}
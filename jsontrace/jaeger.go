@@ -0,0 +1,211 @@
+package jsontrace
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// jaegerProcessID is the ID of the single process of a Jaeger trace.
+const jaegerProcessID = "p1"
+
+// jaegerFile is a Jaeger UI JSON file, as downloaded from the Jaeger UI.
+type jaegerFile struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+// jaegerTrace is a Jaeger UI trace.
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+// jaegerSpan is a Jaeger UI span.
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	StartTime     int64             `json:"startTime"`
+	Duration      int64             `json:"duration"`
+	Tags          []jaegerKeyValue  `json:"tags"`
+	Logs          []jaegerLog       `json:"logs"`
+	ProcessID     string            `json:"processID"`
+}
+
+// jaegerReference is a Jaeger UI span reference.
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+// jaegerKeyValue is a Jaeger UI typed tag or log field.
+type jaegerKeyValue struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+// jaegerLog is a Jaeger UI span log.
+type jaegerLog struct {
+	Timestamp int64            `json:"timestamp"`
+	Fields    []jaegerKeyValue `json:"fields"`
+}
+
+// jaegerProcess is a Jaeger UI process.
+type jaegerProcess struct {
+	ServiceName string           `json:"serviceName"`
+	Tags        []jaegerKeyValue `json:"tags"`
+}
+
+// jaegerRefTypes are the Jaeger reference types of the reference types.
+var jaegerRefTypes = map[string]string{
+	ReferenceChildOf:     "CHILD_OF",
+	ReferenceFollowsFrom: "FOLLOWS_FROM",
+}
+
+// WriteJaeger writes the specified span records as Jaeger UI JSON, which can
+// be loaded in the Jaeger UI, grouping the spans by trace in order of first
+// appearance, with the specified service name.
+//
+// The tags and log fields keep their opentracing names, e.g. "span.kind" and
+// "error", and are typed as string, bool, int64, or float64: the other values
+// are written as JSON strings. The baggage is not written.
+func WriteJaeger(w io.Writer, spans []*Span, serviceName string) error {
+	var file jaegerFile
+	traces := make(map[string]int)
+	for _, span := range spans {
+		i, ok := traces[span.TraceID]
+		if !ok {
+			i = len(file.Data)
+			traces[span.TraceID] = i
+			file.Data = append(file.Data, jaegerTrace{
+				TraceID: span.TraceID,
+				Processes: map[string]jaegerProcess{
+					jaegerProcessID: {ServiceName: serviceName, Tags: []jaegerKeyValue{}},
+				},
+			})
+		}
+		file.Data[i].Spans = append(file.Data[i].Spans, toJaeger(span))
+	}
+	if file.Data == nil {
+		file.Data = []jaegerTrace{}
+	}
+	b, err := json.MarshalIndent(file, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "write jaeger spans")
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// toJaeger returns the Jaeger span of the specified span record.
+func toJaeger(span *Span) jaegerSpan {
+	j := jaegerSpan{
+		TraceID:       span.TraceID,
+		SpanID:        span.SpanID,
+		OperationName: span.Operation,
+		References:    make([]jaegerReference, 0, len(span.References)),
+		StartTime:     span.Start.UnixMicro(),
+		Duration:      span.Duration.Microseconds(),
+		Tags:          make([]jaegerKeyValue, 0, len(span.Tags)),
+		Logs:          make([]jaegerLog, 0, len(span.Logs)),
+		ProcessID:     jaegerProcessID,
+	}
+	for _, ref := range span.References {
+		j.References = append(j.References, jaegerReference{
+			RefType: jaegerRefTypes[ref.Type],
+			TraceID: ref.TraceID,
+			SpanID:  ref.SpanID,
+		})
+	}
+	if len(span.References) == 0 && span.ParentID != "" {
+		j.References = append(j.References, jaegerReference{RefType: "CHILD_OF", TraceID: span.TraceID, SpanID: span.ParentID})
+	}
+	for k, v := range span.Tags {
+		j.Tags = append(j.Tags, jaegerValue(k, v))
+	}
+	sort.Slice(j.Tags, func(a, b int) bool {
+		return j.Tags[a].Key < j.Tags[b].Key
+	})
+	for _, l := range span.Logs {
+		jl := jaegerLog{Timestamp: l.Timestamp.UnixMicro(), Fields: make([]jaegerKeyValue, 0, len(l.Fields))}
+		for _, f := range l.Fields {
+			jl.Fields = append(jl.Fields, jaegerValue(f.Key, f.Value))
+		}
+		j.Logs = append(j.Logs, jl)
+	}
+	return j
+}
+
+// jaegerValue returns the Jaeger typed key value of the specified value.
+func jaegerValue(key string, v interface{}) jaegerKeyValue {
+	switch typ, v := typedValue(v); typ {
+	case FieldBool, FieldString, FieldInt64, FieldFloat64:
+		return jaegerKeyValue{Key: key, Type: typ, Value: v}
+	}
+	return jaegerKeyValue{Key: key, Type: FieldString, Value: stringValue(v)}
+}
+
+// ReadJaeger reads the span records of the specified Jaeger UI JSON, in trace
+// order. The numbers of the tag and log field values are decoded as
+// json.Number, and the parent of a span is its first CHILD_OF reference, or
+// else its first reference.
+func ReadJaeger(r io.Reader) ([]*Span, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var file jaegerFile
+	if err := dec.Decode(&file); err != nil {
+		return nil, errors.Wrap(err, "read jaeger spans")
+	}
+	var spans []*Span
+	for _, trace := range file.Data {
+		for _, j := range trace.Spans {
+			spans = append(spans, fromJaeger(j))
+		}
+	}
+	return spans, nil
+}
+
+// fromJaeger returns the span record of the specified Jaeger span.
+func fromJaeger(j jaegerSpan) *Span {
+	span := &Span{
+		TraceID:   j.TraceID,
+		SpanID:    j.SpanID,
+		Operation: j.OperationName,
+		Start:     time.UnixMicro(j.StartTime).UTC(),
+		Duration:  time.Duration(j.Duration) * time.Microsecond,
+	}
+	childOf := false
+	for _, ref := range j.References {
+		typ := ReferenceChildOf
+		if ref.RefType == jaegerRefTypes[ReferenceFollowsFrom] {
+			typ = ReferenceFollowsFrom
+		}
+		span.References = append(span.References, Reference{Type: typ, TraceID: ref.TraceID, SpanID: ref.SpanID})
+		if span.ParentID == "" || typ == ReferenceChildOf && !childOf {
+			span.ParentID = ref.SpanID
+			childOf = typ == ReferenceChildOf
+		}
+	}
+	if len(j.Tags) > 0 {
+		span.Tags = make(map[string]interface{}, len(j.Tags))
+		for _, kv := range j.Tags {
+			_, span.Tags[kv.Key] = typedValue(kv.Value)
+		}
+	}
+	for _, jl := range j.Logs {
+		l := Log{Timestamp: time.UnixMicro(jl.Timestamp).UTC(), Fields: make([]Field, 0, len(jl.Fields))}
+		for _, kv := range jl.Fields {
+			typ, v := typedValue(kv.Value)
+			l.Fields = append(l.Fields, Field{Key: kv.Key, Type: typ, Value: v})
+		}
+		span.Logs = append(span.Logs, l)
+	}
+	return span
+}
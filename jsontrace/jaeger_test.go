@@ -0,0 +1,117 @@
+package jsontrace_test
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+func TestWriteJaeger(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jsontrace.WriteJaeger(&buf, readFixture(t), "shop"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ensureFile(t, "testdata/trace.jaeger.json", buf.Bytes())
+}
+
+func TestJaegerRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/trace.jaeger.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	spans, err := jsontrace.ReadJaeger(f)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jsontrace.WriteJaeger(&buf, spans, "shop"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ensureFile(t, "testdata/trace.jaeger.json", buf.Bytes())
+
+	// The span records are preserved, except the baggage.
+	fixture := readFixture(t)
+	for _, span := range fixture {
+		span.Baggage = nil
+	}
+	if got, want := marshalSpans(t, spans), marshalSpans(t, fixture); got != want {
+		t.Errorf("got spans:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteJaegerEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jsontrace.WriteJaeger(&buf, nil, "shop"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got, want := buf.String(), "{\n\t\"data\": []\n}\n"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestFromMockSpans(t *testing.T) {
+	tracer := mocktracer.New()
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	parent := tracer.StartSpan("parent", opentracing.StartTime(start))
+	parent.SetBaggageItem("user", "alice")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()), opentracing.StartTime(start), ext.SpanKindRPCClient)
+	ext.PeerPort.Set(child, 8080)
+	child.LogFields(log.String("event", "retry"), log.Int("attempt", 2), log.Bool("ok", true), log.Float64("delay", 0.5), log.Float64("ratio", math.NaN()))
+	child.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(time.Second)})
+	parent.FinishWithOptions(opentracing.FinishOptions{FinishTime: start.Add(2 * time.Second)})
+
+	spans := jsontrace.FromMockSpans(tracer.FinishedSpans())
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("got %d spans, want %d", got, want)
+	}
+	c, p := spans[0], spans[1]
+	if got, want := c.ParentID, p.SpanID; got != want {
+		t.Errorf("got parent ID %q, want %q", got, want)
+	}
+	if got, want := len(c.TraceID), 32; got != want {
+		t.Errorf("got trace ID length %d, want %d", got, want)
+	}
+	if got, want := c.Duration, time.Second; got != want {
+		t.Errorf("got duration %v, want %v", got, want)
+	}
+	if got, want := c.Tags["span.kind"], "client"; got != want {
+		t.Errorf("got span.kind tag %#v, want %#v", got, want)
+	}
+	if got, want := p.Baggage["user"], "alice"; got != want {
+		t.Errorf("got baggage item %q, want %q", got, want)
+	}
+	wantTypes := []string{jsontrace.FieldString, jsontrace.FieldInt64, jsontrace.FieldBool, jsontrace.FieldFloat64, jsontrace.FieldFloat64}
+	for i, f := range c.Logs[0].Fields {
+		if got, want := f.Type, wantTypes[i]; got != want {
+			t.Errorf("field %q: got type %q, want %q", f.Key, got, want)
+		}
+	}
+
+	if got, want := c.Logs[0].Fields[4].Value, "NaN"; got != want {
+		t.Errorf("field %q: got %#v, want %#v", "ratio", got, want)
+	}
+
+	// The converted spans can be written as Zipkin JSON.
+	var buf bytes.Buffer
+	if err := jsontrace.WriteZipkin(&buf, spans, "test"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	zspans, err := jsontrace.ReadZipkin(&buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got, want := zspans[0].Tags["peer.port"], c.Tags["peer.port"]; got != want {
+		t.Errorf("got peer.port tag %#v, want %#v", got, want)
+	}
+}
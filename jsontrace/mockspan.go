@@ -0,0 +1,74 @@
+package jsontrace
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// FromMockSpans returns the span records of the specified mocktracer spans,
+// e.g. the spans of a tracetest.Recorder, so that they can be written as JSON
+// lines, Zipkin, or Jaeger JSON. The mocktracer span IDs are formatted as hex,
+// and the values are converted like the values read by ReadSpans.
+func FromMockSpans(spans []*mocktracer.MockSpan) []*Span {
+	records := make([]*Span, 0, len(spans))
+	for _, span := range spans {
+		record := &Span{
+			TraceID:   fmt.Sprintf("%032x", span.SpanContext.TraceID),
+			SpanID:    fmt.Sprintf("%016x", span.SpanContext.SpanID),
+			Operation: span.OperationName,
+			Start:     span.StartTime,
+			Duration:  span.FinishTime.Sub(span.StartTime),
+		}
+		if span.ParentID != 0 {
+			record.ParentID = fmt.Sprintf("%016x", span.ParentID)
+			record.References = []Reference{{Type: ReferenceChildOf, TraceID: record.TraceID, SpanID: record.ParentID}}
+		}
+		if tags := span.Tags(); len(tags) > 0 {
+			record.Tags = make(map[string]interface{}, len(tags))
+			for k, v := range tags {
+				record.Tags[k] = normalizeValue(v)
+			}
+		}
+		for _, l := range span.Logs() {
+			fields := make([]Field, len(l.Fields))
+			for i, f := range l.Fields {
+				fields[i] = mockField(f)
+			}
+			record.Logs = append(record.Logs, Log{Timestamp: l.Timestamp, Fields: fields})
+		}
+		if len(span.SpanContext.Baggage) > 0 {
+			record.Baggage = make(map[string]string, len(span.SpanContext.Baggage))
+			for k, v := range span.SpanContext.Baggage {
+				record.Baggage[k] = v
+			}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// mockField returns the field record of the specified mocktracer log field.
+// The mocktracer only retains the kind and the formatted value of the fields,
+// so the values of the other kinds are recorded as strings.
+func mockField(f mocktracer.MockKeyValue) Field {
+	switch f.ValueKind {
+	case reflect.Bool:
+		if b, err := strconv.ParseBool(f.ValueString); err == nil {
+			return Field{Key: f.Key, Type: FieldBool, Value: b}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if _, err := strconv.ParseFloat(f.ValueString, 64); err == nil {
+			return Field{Key: f.Key, Type: FieldInt64, Value: json.Number(f.ValueString)}
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, err := strconv.ParseFloat(f.ValueString, 64); err == nil {
+			return Field{Key: f.Key, Type: FieldFloat64, Value: floatValue(v, json.Number(f.ValueString))}
+		}
+	}
+	return Field{Key: f.Key, Type: FieldString, Value: f.ValueString}
+}
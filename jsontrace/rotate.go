@@ -0,0 +1,135 @@
+package jsontrace
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Rotating file defaults.
+const (
+	DefaultMaxSize    = 10 * 1024 * 1024
+	DefaultMaxBackups = 3
+)
+
+// RotatingFile is an io.WriteCloser that appends to a file, rotating it when
+// a write would exceed its maximum size: the file is renamed with the ".1"
+// suffix, the previous backups are shifted, e.g. from ".1" to ".2", and the
+// oldest backup is removed. Each write is kept in a single file, so that the
+// JSON lines of a Tracer are never split.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu     sync.Mutex
+	f      *os.File // The open file, or nil if it could not be reopened.
+	size   int64
+	closed bool
+}
+
+// OpenRotatingFile opens the specified file for appending, creating it if
+// needed. A maximum size or number of backups less than one is replaced by
+// its default.
+func OpenRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	if maxSize < 1 {
+		maxSize = DefaultMaxSize
+	}
+	if maxBackups < 1 {
+		maxBackups = DefaultMaxBackups
+	}
+	rf := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+// open opens the file for appending.
+func (rf *RotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return errors.Wrap(err, "open rotating file")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "open rotating file")
+	}
+	rf.f, rf.size = f, info.Size()
+	return nil
+}
+
+// backup returns the path of the specified backup.
+func (rf *RotatingFile) backup(n int) string {
+	return fmt.Sprintf("%s.%d", rf.path, n)
+}
+
+// rotate closes and renames the file and its backups, and opens a new file.
+// If the file cannot be renamed, it is reopened, so that writes continue to
+// the file until the next rotation succeeds.
+func (rf *RotatingFile) rotate() error {
+	err := rf.f.Close()
+	rf.f = nil
+	if err == nil {
+		err = rf.rename()
+	}
+	if openErr := rf.open(); err == nil {
+		err = openErr
+	}
+	return errors.Wrap(err, "rotate file")
+}
+
+// rename renames the file and its backups, removing the oldest backup.
+func (rf *RotatingFile) rename() error {
+	if err := os.Remove(rf.backup(rf.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for n := rf.maxBackups - 1; n > 0; n-- {
+		if err := os.Rename(rf.backup(n), rf.backup(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(rf.path, rf.backup(1))
+}
+
+// Write implements the io.Writer interface. If the file could not be
+// reopened by a failed rotation, it is opened again.
+func (rf *RotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return 0, os.ErrClosed
+	}
+	if rf.f == nil {
+		if err := rf.open(); err != nil {
+			return 0, err
+		}
+	}
+	if rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		if err := rf.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// Close implements the io.Closer interface.
+func (rf *RotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.closed {
+		return os.ErrClosed
+	}
+	rf.closed = true
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.f.Close()
+	rf.f = nil
+	return err
+}
@@ -0,0 +1,108 @@
+package jsontrace_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	f, err := jsontrace.OpenRotatingFile(path, 10, 2)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	for _, s := range []string{"aaaaaa\n", "bbb\n", "cccccc\n", "dddddddddddd\n", "e\n"} {
+		if _, err := f.Write([]byte(s)); err != nil {
+			t.Fatalf("write %q: %v", s, err)
+		}
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	want := map[string]string{
+		path:        "e\n",
+		path + ".1": "dddddddddddd\n",
+		path + ".2": "cccccc\n",
+	}
+	for p, w := range want {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("read %s: %v", p, err)
+		}
+		if got := string(b); got != w {
+			t.Errorf("%s: got %q, want %q", filepath.Base(p), got, w)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("got backup .3, want at most 2 backups")
+	}
+	if _, err := f.Write([]byte("f\n")); err != os.ErrClosed {
+		t.Errorf("write after close: got %v, want %v", err, os.ErrClosed)
+	}
+}
+
+func TestRotatingFileAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	if err := os.WriteFile(path, []byte("existing\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := jsontrace.OpenRotatingFile(path, 12, 1)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("next\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	b, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("read backup: %v", err)
+	}
+	if got, want := string(b), "existing\n"; got != want {
+		t.Errorf("got backup %q, want %q", got, want)
+	}
+}
+
+func TestRotatingFileRotateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+	f, err := jsontrace.OpenRotatingFile(path, 4, 1)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.Write([]byte("aaa\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// A non-empty directory in place of the backup cannot be removed.
+	if err := os.MkdirAll(filepath.Join(path+".1", "dir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("bbb\n")); err == nil {
+		t.Fatalf("write: got no error, want rotate error")
+	}
+	if err := os.RemoveAll(path + ".1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("ccc\n")); err != nil {
+		t.Fatalf("write after rotate error: %v", err)
+	}
+
+	want := map[string]string{
+		path:        "ccc\n",
+		path + ".1": "aaa\n",
+	}
+	for p, w := range want {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			t.Fatalf("read %s: %v", p, err)
+		}
+		if got := string(b); got != w {
+			t.Errorf("%s: got %q, want %q", filepath.Base(p), got, w)
+		}
+	}
+}
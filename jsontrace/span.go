@@ -0,0 +1,108 @@
+// Package jsontrace provides an opentracing.Tracer that writes the finished
+// spans as JSON lines, for local debugging without a collector, reads them
+// back, and converts them to and from Zipkin v2 and Jaeger UI JSON.
+package jsontrace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Reference types of a span record reference.
+const (
+	ReferenceChildOf     = "child_of"
+	ReferenceFollowsFrom = "follows_from"
+)
+
+// Field types of a log field record.
+const (
+	FieldString  = "string"
+	FieldBool    = "bool"
+	FieldInt     = "int"
+	FieldInt32   = "int32"
+	FieldInt64   = "int64"
+	FieldUint32  = "uint32"
+	FieldUint64  = "uint64"
+	FieldFloat32 = "float32"
+	FieldFloat64 = "float64"
+	FieldError   = "error"
+	FieldObject  = "object"
+)
+
+// Span is the JSON line record of a finished span.
+type Span struct {
+	TraceID    string                 `json:"trace_id"`            // The 128-bit trace ID, as 32 hex characters.
+	SpanID     string                 `json:"span_id"`             // The 64-bit span ID, as 16 hex characters.
+	ParentID   string                 `json:"parent_id,omitempty"` // The span ID of the parent span, if any.
+	References []Reference            `json:"references,omitempty"`
+	Operation  string                 `json:"operation"`
+	Start      time.Time              `json:"start"`
+	Duration   time.Duration          `json:"duration"` // The duration in nanoseconds.
+	Tags       map[string]interface{} `json:"tags,omitempty"`
+	Logs       []Log                  `json:"logs,omitempty"`
+	Baggage    map[string]string      `json:"baggage,omitempty"`
+}
+
+// Reference is a reference of a span record to another span.
+type Reference struct {
+	Type    string `json:"type"` // ReferenceChildOf or ReferenceFollowsFrom.
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+// Log is a log record of a span record.
+type Log struct {
+	Timestamp time.Time `json:"timestamp"`
+	Fields    []Field   `json:"fields"`
+}
+
+// Field is a typed log field of a log record. The values of object fields
+// are embedded as JSON, like the values of LogFields.Encode, or formatted
+// with fmt.Sprint if they cannot be marshaled. The values of float fields
+// that are not finite are strings, e.g. "NaN" or "+Inf".
+type Field struct {
+	Key   string      `json:"key"`
+	Type  string      `json:"type"` // One of the Field* types.
+	Value interface{} `json:"value"`
+}
+
+// Field returns the log field with the specified key, if any.
+func (l Log) Field(key string) (Field, bool) {
+	for _, f := range l.Fields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// ReadSpans reads the span records of the specified JSON lines, skipping the
+// empty lines. The numbers of the tag and log field values are decoded as
+// json.Number.
+func ReadSpans(r io.Reader) ([]*Span, error) {
+	var spans []*Span
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		b := bytes.TrimSpace(scanner.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.UseNumber()
+		var span Span
+		if err := dec.Decode(&span); err != nil {
+			return nil, errors.Wrapf(err, "line %d", line)
+		}
+		spans = append(spans, &span)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
@@ -0,0 +1,461 @@
+package jsontrace
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// BaggageHeaderPrefix is the prefix of the baggage item headers injected by
+// a Tracer.
+const BaggageHeaderPrefix = "ot-baggage-"
+
+// Options are the options of a Tracer.
+type Options struct {
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+}
+
+// Ensure Tracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &Tracer{}
+
+// Tracer is an opentracing.Tracer that writes each finished span as a JSON
+// line Span record to an io.Writer, e.g. a RotatingFile.
+//
+// The span contexts are injected and extracted for the TextMap and
+// HTTPHeaders formats as W3C Trace Context headers, with a header per
+// baggage item, so that the tracer can be used end to end between local
+// services.
+type Tracer struct {
+	now func() time.Time
+
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// NewTracer returns a new tracer that writes the finished spans to the
+// specified writer. The options may be nil.
+func NewTracer(w io.Writer, opts *Options) *Tracer {
+	if opts == nil {
+		opts = &Options{}
+	}
+	t := &Tracer{w: w, now: opts.Now}
+	if t.now == nil {
+		t.now = time.Now
+	}
+	return t
+}
+
+// Err returns the first error writing a span, if any.
+func (t *Tracer) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+// write writes the specified span record as a JSON line.
+func (t *Tracer) write(record *Span) {
+	b, err := json.Marshal(record)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err == nil {
+		_, err = t.w.Write(append(b, '\n'))
+	}
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	s := &span{
+		tracer:    t,
+		operation: operationName,
+		start:     sso.StartTime,
+		tags:      make(map[string]interface{}, len(sso.Tags)),
+		baggage:   make(map[string]string),
+	}
+	if s.start.IsZero() {
+		s.start = t.now()
+	}
+	for k, v := range sso.Tags {
+		s.tags[k] = v
+	}
+
+	// The parent is the first ChildOf reference, or the first reference if
+	// there is none.
+	var parent *spanContext
+	childOf := false
+	for _, ref := range sso.References {
+		sc, ok := ref.ReferencedContext.(spanContext)
+		if !ok {
+			continue
+		}
+		typ := ReferenceChildOf
+		if ref.Type == opentracing.FollowsFromRef {
+			typ = ReferenceFollowsFrom
+		}
+		s.refs = append(s.refs, Reference{Type: typ, TraceID: sc.traceID.String(), SpanID: sc.spanID.String()})
+		for k, v := range sc.baggage {
+			s.baggage[k] = v
+		}
+		if parent == nil || ref.Type == opentracing.ChildOfRef && !childOf {
+			parent = &sc
+			childOf = ref.Type == opentracing.ChildOfRef
+		}
+	}
+	if parent != nil {
+		s.traceID, s.parentID = parent.traceID, parent.spanID
+	} else {
+		binary.BigEndian.PutUint64(s.traceID[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(s.traceID[8:], nonZeroUint64())
+	}
+	binary.BigEndian.PutUint64(s.spanID[:], nonZeroUint64())
+	return s
+}
+
+// nonZeroUint64 returns a random non-zero uint64.
+func nonZeroUint64() uint64 {
+	for {
+		if n := rand.Uint64(); n != 0 {
+			return n
+		}
+	}
+}
+
+// Inject implements the opentracing.Tracer interface. The TextMap and
+// HTTPHeaders formats are supported.
+func (t *Tracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	c, ok := sc.(spanContext)
+	if !ok {
+		return opentracing.ErrInvalidSpanContext
+	}
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return opentracing.ErrUnsupportedFormat
+	}
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	tc := otexts.TraceContext{TraceID: c.traceID, SpanID: c.spanID, Sampling: otexts.SamplingAccept}
+	if err := (otexts.W3CPropagator{}).Inject(tc, w); err != nil {
+		return err
+	}
+	for k, v := range c.baggage {
+		if format == opentracing.HTTPHeaders {
+			v = url.QueryEscape(v)
+		}
+		w.Set(BaggageHeaderPrefix+k, v)
+	}
+	return nil
+}
+
+// Extract implements the opentracing.Tracer interface. The TextMap and
+// HTTPHeaders formats are supported. The baggage keys of the HTTPHeaders
+// format are lowercased, the baggage keys of the TextMap format are kept.
+func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+	tc, err := (otexts.W3CPropagator{}).Extract(r)
+	if err != nil {
+		return nil, err
+	}
+	c := spanContext{traceID: tc.TraceID, spanID: tc.SpanID, baggage: make(map[string]string)}
+	err = r.ForeachKey(func(k, v string) error {
+		if format == opentracing.HTTPHeaders {
+			// The HTTP header names are case-insensitive, and canonicalized
+			// by net/http.
+			k = strings.ToLower(k)
+		}
+		if !strings.HasPrefix(k, BaggageHeaderPrefix) {
+			return nil
+		}
+		if format == opentracing.HTTPHeaders {
+			if unescaped, err := url.QueryUnescape(v); err == nil {
+				v = unescaped
+			}
+		}
+		c.baggage[strings.TrimPrefix(k, BaggageHeaderPrefix)] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// spanContext is the span context of a Tracer span.
+type spanContext struct {
+	traceID otexts.TraceID
+	spanID  otexts.SpanID
+	baggage map[string]string
+}
+
+// ForeachBaggageItem implements the opentracing.SpanContext interface.
+func (c spanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+// span is a span of a Tracer.
+type span struct {
+	tracer   *Tracer
+	traceID  otexts.TraceID
+	spanID   otexts.SpanID
+	parentID otexts.SpanID
+	refs     []Reference
+	start    time.Time
+
+	mu        sync.Mutex
+	operation string
+	tags      map[string]interface{}
+	logs      []Log
+	baggage   map[string]string
+	finished  bool
+}
+
+// Finish implements the opentracing.Span interface.
+func (s *span) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions implements the opentracing.Span interface. Only the first
+// call writes the span.
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	s.mu.Lock()
+	finished := s.finished
+	s.finished = true
+	s.mu.Unlock()
+	if finished {
+		return
+	}
+
+	finishTime := opts.FinishTime
+	if finishTime.IsZero() {
+		finishTime = s.tracer.now()
+	}
+	for _, r := range opts.LogRecords {
+		s.log(r.Timestamp, r.Fields)
+	}
+	for _, data := range opts.BulkLogData {
+		r := data.ToLogRecord()
+		s.log(r.Timestamp, r.Fields)
+	}
+
+	s.mu.Lock()
+	record := &Span{
+		TraceID:    s.traceID.String(),
+		SpanID:     s.spanID.String(),
+		References: s.refs,
+		Operation:  s.operation,
+		Start:      s.start,
+		Duration:   finishTime.Sub(s.start),
+		Logs:       s.logs,
+	}
+	if s.parentID.IsValid() {
+		record.ParentID = s.parentID.String()
+	}
+	if len(s.tags) > 0 {
+		record.Tags = make(map[string]interface{}, len(s.tags))
+		for k, v := range s.tags {
+			record.Tags[k] = jsonValue(v)
+		}
+	}
+	if len(s.baggage) > 0 {
+		record.Baggage = make(map[string]string, len(s.baggage))
+		for k, v := range s.baggage {
+			record.Baggage[k] = v
+		}
+	}
+	s.mu.Unlock()
+
+	s.tracer.write(record)
+}
+
+// jsonValue returns the specified value if it can be marshaled as JSON, its
+// error message if it is an error, or the value formatted with fmt.Sprint.
+func jsonValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return json.RawMessage(b)
+}
+
+// Context implements the opentracing.Span interface.
+func (s *span) Context() opentracing.SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	baggage := make(map[string]string, len(s.baggage))
+	for k, v := range s.baggage {
+		baggage[k] = v
+	}
+	return spanContext{traceID: s.traceID, spanID: s.spanID, baggage: baggage}
+}
+
+// SetOperationName implements the opentracing.Span interface.
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.operation = operationName
+	return s
+}
+
+// SetTag implements the opentracing.Span interface.
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tags[key] = value
+	return s
+}
+
+// LogFields implements the opentracing.Span interface.
+func (s *span) LogFields(fields ...log.Field) {
+	s.log(time.Time{}, fields)
+}
+
+// LogKV implements the opentracing.Span interface.
+func (s *span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+// log records the specified log fields at the specified time, or now if
+// zero.
+func (s *span) log(timestamp time.Time, fields []log.Field) {
+	if timestamp.IsZero() {
+		timestamp = s.tracer.now()
+	}
+	enc := &fieldEncoder{}
+	for _, f := range fields {
+		if err, ok := f.Value().(error); ok {
+			enc.emit(f.Key(), FieldError, err.Error())
+			continue
+		}
+		f.Marshal(enc)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs = append(s.logs, Log{Timestamp: timestamp, Fields: enc.fields})
+}
+
+// SetBaggageItem implements the opentracing.Span interface.
+func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baggage[restrictedKey] = value
+	return s
+}
+
+// BaggageItem implements the opentracing.Span interface.
+func (s *span) BaggageItem(restrictedKey string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.baggage[restrictedKey]
+}
+
+// Tracer implements the opentracing.Span interface.
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent implements the deprecated opentracing.Span method.
+func (s *span) LogEvent(event string) {
+	s.LogFields(log.String(otexts.LogFieldEvent, event))
+}
+
+// LogEventWithPayload implements the deprecated opentracing.Span method.
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String(otexts.LogFieldEvent, event), log.Object("payload", payload))
+}
+
+// Log implements the deprecated opentracing.Span method.
+func (s *span) Log(data opentracing.LogData) {
+	r := data.ToLogRecord()
+	s.log(r.Timestamp, r.Fields)
+}
+
+// Ensure fieldEncoder implements the log.Encoder interface.
+var _ log.Encoder = &fieldEncoder{}
+
+// fieldEncoder is a log.Encoder that encodes log fields as typed field
+// records.
+type fieldEncoder struct {
+	fields []Field
+}
+
+func (e *fieldEncoder) emit(key, typ string, value interface{}) {
+	e.fields = append(e.fields, Field{Key: key, Type: typ, Value: value})
+}
+
+// EmitString implements the log.Encoder interface.
+func (e *fieldEncoder) EmitString(key, value string) { e.emit(key, FieldString, value) }
+
+// EmitBool implements the log.Encoder interface.
+func (e *fieldEncoder) EmitBool(key string, value bool) { e.emit(key, FieldBool, value) }
+
+// EmitInt implements the log.Encoder interface.
+func (e *fieldEncoder) EmitInt(key string, value int) { e.emit(key, FieldInt, value) }
+
+// EmitInt32 implements the log.Encoder interface.
+func (e *fieldEncoder) EmitInt32(key string, value int32) { e.emit(key, FieldInt32, value) }
+
+// EmitInt64 implements the log.Encoder interface.
+func (e *fieldEncoder) EmitInt64(key string, value int64) { e.emit(key, FieldInt64, value) }
+
+// EmitUint32 implements the log.Encoder interface.
+func (e *fieldEncoder) EmitUint32(key string, value uint32) { e.emit(key, FieldUint32, value) }
+
+// EmitUint64 implements the log.Encoder interface.
+func (e *fieldEncoder) EmitUint64(key string, value uint64) { e.emit(key, FieldUint64, value) }
+
+// EmitFloat32 implements the log.Encoder interface.
+func (e *fieldEncoder) EmitFloat32(key string, value float32) {
+	e.emit(key, FieldFloat32, floatValue(float64(value), value))
+}
+
+// EmitFloat64 implements the log.Encoder interface.
+func (e *fieldEncoder) EmitFloat64(key string, value float64) {
+	e.emit(key, FieldFloat64, floatValue(value, value))
+}
+
+// EmitObject implements the log.Encoder interface.
+func (e *fieldEncoder) EmitObject(key string, value interface{}) {
+	e.emit(key, FieldObject, jsonValue(value))
+}
+
+// EmitLazyLogger implements the log.Encoder interface.
+func (e *fieldEncoder) EmitLazyLogger(value log.LazyLogger) {
+	value(e)
+}
@@ -0,0 +1,302 @@
+package jsontrace_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+// newTestTracer returns a new tracer writing to a buffer, whose clock
+// advances by a second on each call.
+func newTestTracer() (*jsontrace.Tracer, *bytes.Buffer) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tracer := jsontrace.NewTracer(&buf, &jsontrace.Options{
+		Now: func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		},
+	})
+	return tracer, &buf
+}
+
+func readSpans(t *testing.T, buf *bytes.Buffer) []*jsontrace.Span {
+	t.Helper()
+	spans, err := jsontrace.ReadSpans(buf)
+	if err != nil {
+		t.Fatalf("read spans: %v", err)
+	}
+	return spans
+}
+
+func TestTracerSpan(t *testing.T) {
+	tracer, buf := newTestTracer()
+
+	parent := tracer.StartSpan("parent")
+	parent.SetBaggageItem("user", "alice")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()), ext.SpanKindRPCClient)
+	child.SetTag("http.status_code", 200)
+	child.SetOperationName("GET /users")
+	otexts.LogError(child, errors.New("timeout"))
+	child.LogFields(
+		log.Int64("retry.attempt", 2),
+		log.Bool("cached", false),
+		log.Error(errors.New("unavailable")),
+		log.Object("request", map[string]string{"id": "42"}),
+	)
+	child.Finish()
+	parent.Finish()
+
+	if err := tracer.Err(); err != nil {
+		t.Fatalf("tracer error: %v", err)
+	}
+	spans := readSpans(t, buf)
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("got %d spans, want %d", got, want)
+	}
+	c, p := spans[0], spans[1]
+
+	if got, want := c.Operation, "GET /users"; got != want {
+		t.Errorf("got operation %q, want %q", got, want)
+	}
+	if got, want := c.TraceID, p.TraceID; got != want {
+		t.Errorf("got trace ID %q, want %q", got, want)
+	}
+	if got, want := c.ParentID, p.SpanID; got != want {
+		t.Errorf("got parent ID %q, want %q", got, want)
+	}
+	if p.ParentID != "" || len(p.References) != 0 {
+		t.Errorf("got parent %q and references %v for root span", p.ParentID, p.References)
+	}
+	wantRef := jsontrace.Reference{Type: jsontrace.ReferenceChildOf, TraceID: p.TraceID, SpanID: p.SpanID}
+	if len(c.References) != 1 || c.References[0] != wantRef {
+		t.Errorf("got references %v, want [%v]", c.References, wantRef)
+	}
+	if got, want := c.Duration, 3*time.Second; got != want {
+		t.Errorf("got duration %v, want %v", got, want)
+	}
+	if got, want := c.Tags["span.kind"], "client"; got != want {
+		t.Errorf("got span.kind tag %v, want %v", got, want)
+	}
+	if got, want := c.Tags["http.status_code"], json.Number("200"); got != want {
+		t.Errorf("got http.status_code tag %v, want %v", got, want)
+	}
+	if got, want := c.Tags["error"], true; got != want {
+		t.Errorf("got error tag %v, want %v", got, want)
+	}
+	if got, want := c.Baggage["user"], "alice"; got != want {
+		t.Errorf("got baggage item %q, want %q", got, want)
+	}
+
+	if got, want := len(c.Logs), 2; got != want {
+		t.Fatalf("got %d logs, want %d", got, want)
+	}
+	if f, _ := c.Logs[0].Field(otexts.LogFieldMessage); f.Value != "timeout" {
+		t.Errorf("got message field %v, want timeout", f.Value)
+	}
+	want := map[string]jsontrace.Field{
+		"retry.attempt": {Key: "retry.attempt", Type: jsontrace.FieldInt64, Value: json.Number("2")},
+		"cached":        {Key: "cached", Type: jsontrace.FieldBool, Value: false},
+		"error":         {Key: "error", Type: jsontrace.FieldError, Value: "unavailable"},
+	}
+	for k, v := range want {
+		got, ok := c.Logs[1].Field(k)
+		if !ok || got != v {
+			t.Errorf("got field %v, want %v", got, v)
+		}
+	}
+	request, _ := c.Logs[1].Field("request")
+	if got, want := request.Type, jsontrace.FieldObject; got != want {
+		t.Errorf("got object field type %q, want %q", got, want)
+	}
+	if got, ok := request.Value.(map[string]interface{}); !ok || got["id"] != "42" {
+		t.Errorf("got object field value %#v, want embedded JSON object", request.Value)
+	}
+}
+
+func TestTracerFollowsFrom(t *testing.T) {
+	tracer, buf := newTestTracer()
+
+	producer := tracer.StartSpan("producer")
+	other := tracer.StartSpan("other")
+	consumer := tracer.StartSpan("consumer",
+		opentracing.FollowsFrom(producer.Context()),
+		opentracing.ChildOf(other.Context()),
+	)
+	consumer.Finish()
+	consumer.Finish()
+
+	spans := readSpans(t, buf)
+	if got, want := len(spans), 1; got != want {
+		t.Fatalf("got %d spans, want %d", got, want)
+	}
+	s := spans[0]
+	if got, want := len(s.References), 2; got != want {
+		t.Fatalf("got %d references, want %d", got, want)
+	}
+	if got, want := s.References[0].Type, jsontrace.ReferenceFollowsFrom; got != want {
+		t.Errorf("got reference type %q, want %q", got, want)
+	}
+	if got, want := s.ParentID, s.References[1].SpanID; got != want {
+		t.Errorf("got parent ID %q, want the ChildOf reference %q", got, want)
+	}
+}
+
+func TestTracerInjectExtract(t *testing.T) {
+	tt := []struct {
+		name    string
+		format  interface{}
+		carrier interface {
+			opentracing.TextMapWriter
+			opentracing.TextMapReader
+		}
+		userKey string // The extracted key of the "userID" baggage item.
+	}{
+		{name: "TextMap", format: opentracing.TextMap, carrier: opentracing.TextMapCarrier{}, userKey: "userID"},
+		{name: "HTTPHeaders", format: opentracing.HTTPHeaders, carrier: opentracing.HTTPHeadersCarrier(http.Header{}), userKey: "userid"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, buf := newTestTracer()
+
+			client := tracer.StartSpan("client")
+			client.SetBaggageItem("query", "a=b c")
+			client.SetBaggageItem("userID", "42")
+			if err := tracer.Inject(client.Context(), tc.format, tc.carrier); err != nil {
+				t.Fatalf("inject: %v", err)
+			}
+			sc, err := tracer.Extract(tc.format, tc.carrier)
+			if err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			server := tracer.StartSpan("server", ext.RPCServerOption(sc))
+			if got, want := server.BaggageItem("query"), "a=b c"; got != want {
+				t.Errorf("got baggage item %q, want %q", got, want)
+			}
+			if got, want := server.BaggageItem(tc.userKey), "42"; got != want {
+				t.Errorf("got baggage item %q: %q, want %q", tc.userKey, got, want)
+			}
+			server.Finish()
+			client.Finish()
+
+			spans := readSpans(t, buf)
+			if got, want := spans[0].TraceID, spans[1].TraceID; got != want {
+				t.Errorf("got trace ID %q, want %q", got, want)
+			}
+			if got, want := spans[0].ParentID, spans[1].SpanID; got != want {
+				t.Errorf("got parent ID %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestTracerInjectExtractErrors(t *testing.T) {
+	tracer, _ := newTestTracer()
+	span := tracer.StartSpan("span")
+
+	if got, want := tracer.Inject(span.Context(), opentracing.Binary, &bytes.Buffer{}), opentracing.ErrUnsupportedFormat; got != want {
+		t.Errorf("inject binary: got %v, want %v", got, want)
+	}
+	if got, want := tracer.Inject(span.Context(), opentracing.TextMap, "carrier"), opentracing.ErrInvalidCarrier; got != want {
+		t.Errorf("inject invalid carrier: got %v, want %v", got, want)
+	}
+	if _, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{}); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("extract empty carrier: got %v, want %v", err, opentracing.ErrSpanContextNotFound)
+	}
+	carrier := opentracing.TextMapCarrier{otexts.W3CTraceParentHeader: "00-invalid"}
+	if _, err := tracer.Extract(opentracing.TextMap, carrier); err != opentracing.ErrSpanContextCorrupted {
+		t.Errorf("extract corrupted carrier: got %v, want %v", err, opentracing.ErrSpanContextCorrupted)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestTracerErr(t *testing.T) {
+	tracer := jsontrace.NewTracer(errWriter{}, nil)
+	tracer.StartSpan("span").Finish()
+	if err := tracer.Err(); err == nil || err.Error() != "disk full" {
+		t.Errorf("got error %v, want disk full", err)
+	}
+}
+
+func TestTracerNonFiniteFloats(t *testing.T) {
+	tracer, buf := newTestTracer()
+	span := tracer.StartSpan("span")
+	span.LogFields(
+		log.Float64("ratio", math.NaN()),
+		log.Float32("max", float32(math.Inf(1))),
+		log.Float64("min", math.Inf(-1)),
+		log.Float64("valid", 0.5),
+	)
+	span.Finish()
+	if err := tracer.Err(); err != nil {
+		t.Fatalf("write span: %v", err)
+	}
+
+	spans := readSpans(t, buf)
+	if got, want := len(spans), 1; got != want {
+		t.Fatalf("got %d spans, want %d", got, want)
+	}
+	want := map[string]interface{}{
+		"ratio": "NaN",
+		"max":   "+Inf",
+		"min":   "-Inf",
+		"valid": json.Number("0.5"),
+	}
+	for k, w := range want {
+		f, ok := spans[0].Logs[0].Field(k)
+		if !ok {
+			t.Errorf("field %q: not found", k)
+			continue
+		}
+		if f.Value != w {
+			t.Errorf("field %q: got %#v, want %#v", k, f.Value, w)
+		}
+	}
+}
+
+func TestReadSpans(t *testing.T) {
+	tt := []struct {
+		name    string
+		input   string
+		want    int
+		wantErr string
+	}{
+		{name: "empty", input: "", want: 0},
+		{name: "empty lines", input: "\n{\"operation\":\"a\"}\n\n{\"operation\":\"b\"}\n", want: 2},
+		{name: "invalid line", input: "{\"operation\":\"a\"}\n{", wantErr: "line 2"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			spans, err := jsontrace.ReadSpans(strings.NewReader(tc.input))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got, want := len(spans), tc.want; got != want {
+				t.Errorf("got %d spans, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package jsontrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// normalizeValue returns the specified value as decoded from its JSON
+// encoding, with the numbers as json.Number, its error message if it is an
+// error, or the value formatted with fmt.Sprint if it cannot be marshaled.
+func normalizeValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var decoded interface{}
+	if err := dec.Decode(&decoded); err != nil {
+		return fmt.Sprint(v)
+	}
+	return decoded
+}
+
+// floatValue returns the specified float value, or its formatted string, e.g.
+// "NaN" or "+Inf", if it is not finite, since JSON cannot encode it.
+func floatValue(f float64, v interface{}) interface{} {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return fmt.Sprint(f)
+	}
+	return v
+}
+
+// typedValue returns the field type and the normalized value of the
+// specified value.
+func typedValue(v interface{}) (string, interface{}) {
+	switch v := normalizeValue(v).(type) {
+	case nil:
+		return FieldString, ""
+	case bool:
+		return FieldBool, v
+	case string:
+		return FieldString, v
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			return FieldInt64, v
+		}
+		return FieldFloat64, v
+	default:
+		return FieldObject, v
+	}
+}
+
+// stringValue returns the specified value if it is a string, or else its
+// JSON encoding.
+func stringValue(v interface{}) string {
+	switch v := normalizeValue(v).(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
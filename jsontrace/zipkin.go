@@ -0,0 +1,274 @@
+package jsontrace
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// zipkinSpan is a Zipkin v2 span.
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ID             string             `json:"id"`
+	ParentID       string             `json:"parentId,omitempty"`
+	Name           string             `json:"name"`
+	Kind           string             `json:"kind,omitempty"`
+	Timestamp      int64              `json:"timestamp"`
+	Duration       int64              `json:"duration"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint,omitempty"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint,omitempty"`
+	Annotations    []zipkinAnnotation `json:"annotations,omitempty"`
+	Tags           map[string]string  `json:"tags,omitempty"`
+}
+
+// zipkinEndpoint is a Zipkin v2 endpoint.
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName,omitempty"`
+	IPv4        string `json:"ipv4,omitempty"`
+	IPv6        string `json:"ipv6,omitempty"`
+	Port        int    `json:"port,omitempty"`
+}
+
+// zipkinAnnotation is a Zipkin v2 annotation.
+type zipkinAnnotation struct {
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// zipkinKinds are the Zipkin span kinds of the opentracing span kinds.
+var zipkinKinds = map[string]string{
+	string(ext.SpanKindRPCClientEnum): "CLIENT",
+	string(ext.SpanKindRPCServerEnum): "SERVER",
+	string(ext.SpanKindProducerEnum):  "PRODUCER",
+	string(ext.SpanKindConsumerEnum):  "CONSUMER",
+}
+
+// WriteZipkin writes the specified span records as a Zipkin v2 JSON list of
+// spans, e.g. for the Zipkin UI, with the specified local service name.
+//
+// The "span.kind" tag is written as the span kind, the peer tags as the
+// remote endpoint, the "error" tag as the error message of the first error
+// log, and the logs as annotations whose value is the event, or a JSON object
+// of the log fields. The Zipkin tags are strings, and only the parent of a
+// span is written: the other references and the baggage are not.
+func WriteZipkin(w io.Writer, spans []*Span, serviceName string) error {
+	zspans := make([]zipkinSpan, 0, len(spans))
+	for _, span := range spans {
+		zspans = append(zspans, toZipkin(span, serviceName))
+	}
+	b, err := json.MarshalIndent(zspans, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "write zipkin spans")
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// toZipkin returns the Zipkin span of the specified span record.
+func toZipkin(span *Span, serviceName string) zipkinSpan {
+	z := zipkinSpan{
+		TraceID:   span.TraceID,
+		ID:        span.SpanID,
+		ParentID:  span.ParentID,
+		Name:      span.Operation,
+		Timestamp: span.Start.UnixMicro(),
+		Duration:  span.Duration.Microseconds(),
+	}
+	if serviceName != "" {
+		z.LocalEndpoint = &zipkinEndpoint{ServiceName: serviceName}
+	}
+	remote := &zipkinEndpoint{}
+	for k, v := range span.Tags {
+		switch k {
+		case string(ext.SpanKind):
+			z.Kind = zipkinKinds[stringValue(v)]
+		case string(ext.PeerService):
+			remote.ServiceName = stringValue(v)
+		case string(ext.PeerHostIPv4):
+			remote.IPv4 = ipv4Value(v)
+		case string(ext.PeerHostIPv6):
+			remote.IPv6 = stringValue(v)
+		case string(ext.PeerPort):
+			remote.Port, _ = strconv.Atoi(stringValue(v))
+		case string(ext.Error):
+			if stringValue(v) == "true" {
+				z.setTag(k, errorMessage(span.Logs))
+			}
+		default:
+			z.setTag(k, stringValue(v))
+		}
+	}
+	if *remote != (zipkinEndpoint{}) {
+		z.RemoteEndpoint = remote
+	}
+	for _, l := range span.Logs {
+		z.Annotations = append(z.Annotations, zipkinAnnotation{
+			Timestamp: l.Timestamp.UnixMicro(),
+			Value:     annotationValue(l.Fields),
+		})
+	}
+	return z
+}
+
+// setTag sets the specified tag of the Zipkin span.
+func (z *zipkinSpan) setTag(key, value string) {
+	if z.Tags == nil {
+		z.Tags = make(map[string]string)
+	}
+	z.Tags[key] = value
+}
+
+// errorMessage returns the message of the first error log of the specified
+// logs, or "true" if there is none.
+func errorMessage(logs []Log) string {
+	for _, l := range logs {
+		if f, ok := l.Field(otexts.LogFieldEvent); !ok || f.Value != otexts.LogEventError {
+			continue
+		}
+		for _, key := range []string{otexts.LogFieldMessage, otexts.LogFieldErrorObject, "error"} {
+			if f, ok := l.Field(key); ok {
+				return stringValue(f.Value)
+			}
+		}
+	}
+	return "true"
+}
+
+// ipv4Value returns the dotted form of the specified IPv4 address tag value,
+// which is either a string or a uint32.
+func ipv4Value(v interface{}) string {
+	s := stringValue(v)
+	if n, err := strconv.ParseUint(s, 10, 32); err == nil {
+		return net.IPv4(byte(n>>24), byte(n>>16), byte(n>>8), byte(n)).String()
+	}
+	return s
+}
+
+// annotationValue returns the Zipkin annotation value of the specified log
+// fields: the event if it is the only field, or else a JSON object of the
+// fields, in order.
+func annotationValue(fields []Field) string {
+	if len(fields) == 1 && fields[0].Key == otexts.LogFieldEvent {
+		return stringValue(fields[0].Value)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(f.Key)
+		value, _ := json.Marshal(normalizeValue(f.Value))
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.String()
+}
+
+// ReadZipkin reads the span records of the specified Zipkin v2 JSON list of
+// spans, reversing the mappings of WriteZipkin: the tags are strings, except
+// the "error" and "peer.port" tags, and the span kind, remote endpoint and
+// annotations are read as tags and logs.
+func ReadZipkin(r io.Reader) ([]*Span, error) {
+	dec := json.NewDecoder(r)
+	var zspans []zipkinSpan
+	if err := dec.Decode(&zspans); err != nil {
+		return nil, errors.Wrap(err, "read zipkin spans")
+	}
+	spans := make([]*Span, 0, len(zspans))
+	for _, z := range zspans {
+		spans = append(spans, fromZipkin(z))
+	}
+	return spans, nil
+}
+
+// fromZipkin returns the span record of the specified Zipkin span.
+func fromZipkin(z zipkinSpan) *Span {
+	span := &Span{
+		TraceID:   z.TraceID,
+		SpanID:    z.ID,
+		ParentID:  z.ParentID,
+		Operation: z.Name,
+		Start:     time.UnixMicro(z.Timestamp).UTC(),
+		Duration:  time.Duration(z.Duration) * time.Microsecond,
+		Tags:      make(map[string]interface{}),
+	}
+	if z.ParentID != "" {
+		span.References = []Reference{{Type: ReferenceChildOf, TraceID: z.TraceID, SpanID: z.ParentID}}
+	}
+	for k, v := range z.Tags {
+		if k == string(ext.Error) {
+			span.Tags[k] = v != "false"
+			continue
+		}
+		span.Tags[k] = v
+	}
+	for kind, zkind := range zipkinKinds {
+		if z.Kind == zkind {
+			span.Tags[string(ext.SpanKind)] = kind
+		}
+	}
+	if e := z.RemoteEndpoint; e != nil {
+		if e.ServiceName != "" {
+			span.Tags[string(ext.PeerService)] = e.ServiceName
+		}
+		if e.IPv4 != "" {
+			span.Tags[string(ext.PeerHostIPv4)] = e.IPv4
+		}
+		if e.IPv6 != "" {
+			span.Tags[string(ext.PeerHostIPv6)] = e.IPv6
+		}
+		if e.Port != 0 {
+			span.Tags[string(ext.PeerPort)] = json.Number(strconv.Itoa(e.Port))
+		}
+	}
+	if len(span.Tags) == 0 {
+		span.Tags = nil
+	}
+	for _, a := range z.Annotations {
+		span.Logs = append(span.Logs, Log{
+			Timestamp: time.UnixMicro(a.Timestamp).UTC(),
+			Fields:    annotationFields(a.Value),
+		})
+	}
+	return span
+}
+
+// annotationFields returns the log fields of the specified Zipkin annotation
+// value: the fields of a JSON object, in order, or else an event field.
+func annotationFields(value string) []Field {
+	event := []Field{{Key: otexts.LogFieldEvent, Type: FieldString, Value: value}}
+	dec := json.NewDecoder(bytes.NewReader([]byte(value)))
+	dec.UseNumber()
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return event
+	}
+	var fields []Field
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return event
+		}
+		key, _ := t.(string)
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return event
+		}
+		typ, v := typedValue(v)
+		fields = append(fields, Field{Key: key, Type: typ, Value: v})
+	}
+	if t, err := dec.Token(); err != nil || t != json.Delim('}') || dec.More() {
+		return event
+	}
+	return fields
+}
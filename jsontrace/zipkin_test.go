@@ -0,0 +1,158 @@
+package jsontrace_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+// readFixture returns the span records of the fixture trace.
+func readFixture(t *testing.T) []*jsontrace.Span {
+	t.Helper()
+	f, err := os.Open("testdata/trace.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	spans, err := jsontrace.ReadSpans(f)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return spans
+}
+
+// ensureFile reports an error if the specified output does not match the
+// specified file.
+func ensureFile(t *testing.T, path string, got []byte) {
+	t.Helper()
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s:\n%s", path, got)
+	}
+}
+
+// marshalSpans returns the JSON encoding of the specified span records.
+func marshalSpans(t *testing.T, spans []*jsontrace.Span) string {
+	t.Helper()
+	b, err := json.Marshal(spans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestWriteZipkin(t *testing.T) {
+	var buf bytes.Buffer
+	if err := jsontrace.WriteZipkin(&buf, readFixture(t), "shop"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ensureFile(t, "testdata/trace.zipkin.json", buf.Bytes())
+}
+
+func TestZipkinRoundTrip(t *testing.T) {
+	f, err := os.Open("testdata/trace.zipkin.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	spans, err := jsontrace.ReadZipkin(f)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jsontrace.WriteZipkin(&buf, spans, "shop"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ensureFile(t, "testdata/trace.zipkin.json", buf.Bytes())
+
+	fixture := readFixture(t)
+	if got, want := len(spans), len(fixture); got != want {
+		t.Fatalf("got %d spans, want %d", got, want)
+	}
+	client, db, server := spans[0], spans[1], spans[2]
+	tt := []struct {
+		name string
+		span *jsontrace.Span
+		key  string
+		want interface{}
+	}{
+		{name: "span kind", span: client, key: "span.kind", want: "client"},
+		{name: "peer service", span: client, key: "peer.service", want: "users"},
+		{name: "peer ipv4", span: client, key: "peer.ipv4", want: "10.0.0.2"},
+		{name: "peer port", span: client, key: "peer.port", want: json.Number("8081")},
+		{name: "error", span: db, key: "error", want: true},
+		{name: "string tag", span: server, key: "http.status_code", want: "500"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.span.Tags[tc.key]; got != tc.want {
+				t.Errorf("got tag %q %#v, want %#v", tc.key, got, tc.want)
+			}
+		})
+	}
+
+	for i, span := range spans {
+		if got, want := marshalSpans(t, []*jsontrace.Span{{Start: span.Start, Duration: span.Duration, Logs: span.Logs}}),
+			marshalSpans(t, []*jsontrace.Span{{Start: fixture[i].Start, Duration: fixture[i].Duration, Logs: fixture[i].Logs}}); got != want {
+			t.Errorf("span %d: got times and logs %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestReadZipkinAnnotations(t *testing.T) {
+	tt := []struct {
+		name  string
+		value string
+		want  []jsontrace.Field
+	}{
+		{
+			name:  "event",
+			value: "cs",
+			want:  []jsontrace.Field{{Key: "event", Type: jsontrace.FieldString, Value: "cs"}},
+		},
+		{
+			name:  "fields",
+			value: `{"event":"retry","attempt":2,"ok":true,"request":{"id":"42"}}`,
+			want: []jsontrace.Field{
+				{Key: "event", Type: jsontrace.FieldString, Value: "retry"},
+				{Key: "attempt", Type: jsontrace.FieldInt64, Value: json.Number("2")},
+				{Key: "ok", Type: jsontrace.FieldBool, Value: true},
+				{Key: "request", Type: jsontrace.FieldObject, Value: map[string]interface{}{"id": "42"}},
+			},
+		},
+		{
+			name:  "invalid object",
+			value: `{"event":`,
+			want:  []jsontrace.Field{{Key: "event", Type: jsontrace.FieldString, Value: `{"event":`}},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			input, err := json.Marshal([]map[string]interface{}{{
+				"traceId":     "4bf92f3577b34da6a3ce929d0e0e4736",
+				"id":          "00f067aa0ba902b7",
+				"name":        "span",
+				"annotations": []map[string]interface{}{{"timestamp": 1, "value": tc.value}},
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			spans, err := jsontrace.ReadZipkin(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			got, _ := json.Marshal(spans[0].Logs[0].Fields)
+			want, _ := json.Marshal(tc.want)
+			if string(got) != string(want) {
+				t.Errorf("got fields %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// Level is the severity level of a span log event, set as the "level" log
+// field.
+type Level int32
+
+// Span log event severity levels.
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel parses a level name, "debug", "info", "warn" (or "warning"), or
+// "error". The name is case insensitive.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, errors.Errorf("unknown level %q", s)
+}
+
+// String returns the level name.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("Level(%d)", int32(l))
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (l *Level) UnmarshalText(text []byte) error {
+	parsed, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+// Ensure LevelVar implements the http.Handler interface.
+var _ http.Handler = (*LevelVar)(nil)
+
+// LevelVar is a minimum level that is safe to read and change concurrently.
+// The zero value is LevelInfo.
+type LevelVar struct {
+	v int32
+}
+
+// Level returns the minimum level.
+func (v *LevelVar) Level() Level {
+	return Level(atomic.LoadInt32(&v.v))
+}
+
+// Set sets the minimum level.
+func (v *LevelVar) Set(l Level) {
+	atomic.StoreInt32(&v.v, int32(l))
+}
+
+// Enabled reports whether events at the specified level are logged.
+func (v *LevelVar) Enabled(l Level) bool {
+	return l >= v.Level()
+}
+
+// String returns the minimum level name.
+func (v *LevelVar) String() string {
+	return v.Level().String()
+}
+
+// levelPayload is the JSON request and response body of the LevelVar HTTP
+// handler.
+type levelPayload struct {
+	Level Level `json:"level"`
+}
+
+// ServeHTTP implements the http.Handler interface. A GET request responds
+// with the minimum level as JSON, e.g. {"level":"info"}. A PUT or POST
+// request with the same JSON body sets the minimum level.
+func (v *LevelVar) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var p levelPayload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, errors.Wrap(err, "invalid level").Error(), http.StatusBadRequest)
+			return
+		}
+		v.Set(p.Level)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(levelPayload{Level: v.Level()})
+}
+
+// minLevel is the package minimum level for span log events.
+var minLevel LevelVar
+
+// MinLevel returns the minimum level of the span log events logged by this
+// package. Error events are always logged.
+func MinLevel() Level {
+	return minLevel.Level()
+}
+
+// SetMinLevel sets the minimum level of the span log events logged by this
+// package. The default is LevelInfo.
+func SetMinLevel(l Level) {
+	minLevel.Set(l)
+}
+
+// MinLevelHandler returns an http.Handler that reads and changes the minimum
+// level at runtime. See LevelVar.ServeHTTP.
+func MinLevelHandler() http.Handler {
+	return &minLevel
+}
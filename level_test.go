@@ -0,0 +1,147 @@
+package trace_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestParseLevel(t *testing.T) {
+	tt := []struct {
+		name  string
+		s     string
+		level otexts.Level
+		err   bool
+	}{
+		{name: "debug", s: "debug", level: otexts.LevelDebug},
+		{name: "info", s: "INFO", level: otexts.LevelInfo},
+		{name: "warn", s: "warn", level: otexts.LevelWarn},
+		{name: "warning", s: "Warning", level: otexts.LevelWarn},
+		{name: "error", s: "error", level: otexts.LevelError},
+		{name: "unknown", s: "fatal", err: true},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			level, err := otexts.ParseLevel(tc.s)
+			if got, want := err != nil, tc.err; got != want {
+				t.Fatalf("error: got %v, want error %t", err, want)
+			}
+			if tc.err {
+				return
+			}
+			if got, want := level, tc.level; got != want {
+				t.Errorf("level: got %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestLogLevelFilter(t *testing.T) {
+	defer otexts.SetMinLevel(otexts.LevelInfo)
+
+	tt := []struct {
+		name     string
+		minLevel otexts.Level
+		logs     int
+	}{
+		{name: "debug", minLevel: otexts.LevelDebug, logs: 4},
+		{name: "info", minLevel: otexts.LevelInfo, logs: 3},
+		{name: "warn", minLevel: otexts.LevelWarn, logs: 2},
+		{name: "error", minLevel: otexts.LevelError, logs: 1},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			otexts.SetMinLevel(tc.minLevel)
+			span := opentracing.StartSpan("test").(*mocktracer.MockSpan)
+			otexts.LogDebug(span, "debug", nil)
+			otexts.LogInfo(span, "info", nil)
+			otexts.LogWarning(span, "warning", nil)
+			otexts.LogError(span, errors.New("error"))
+			span.Finish()
+
+			logs := span.Logs()
+			if got, want := len(logs), tc.logs; got != want {
+				t.Fatalf("logs: got %d, want %d", got, want)
+			}
+			for _, field := range logs[0].Fields {
+				if field.Key != otexts.LogFieldLevel {
+					continue
+				}
+				level, err := otexts.ParseLevel(field.ValueString)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if level < tc.minLevel {
+					t.Errorf("log field: level: got %s, want >= %s", level, tc.minLevel)
+				}
+			}
+		})
+	}
+}
+
+func TestMinLevelHandler(t *testing.T) {
+	defer otexts.SetMinLevel(otexts.LevelInfo)
+
+	tt := []struct {
+		name   string
+		method string
+		body   string
+		code   int
+		resp   string
+		level  otexts.Level
+	}{
+		{
+			name:   "get",
+			method: http.MethodGet,
+			code:   http.StatusOK,
+			resp:   `{"level":"info"}`,
+			level:  otexts.LevelInfo,
+		},
+		{
+			name:   "put",
+			method: http.MethodPut,
+			body:   `{"level":"debug"}`,
+			code:   http.StatusOK,
+			resp:   `{"level":"debug"}`,
+			level:  otexts.LevelDebug,
+		},
+		{
+			name:   "invalid level",
+			method: http.MethodPost,
+			body:   `{"level":"fatal"}`,
+			code:   http.StatusBadRequest,
+			level:  otexts.LevelDebug,
+		},
+		{
+			name:   "invalid method",
+			method: http.MethodDelete,
+			code:   http.StatusMethodNotAllowed,
+			level:  otexts.LevelDebug,
+		},
+	}
+	h := otexts.MinLevelHandler()
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(tc.method, "/", strings.NewReader(tc.body)))
+			if got, want := w.Code, tc.code; got != want {
+				t.Errorf("status code: got %d, want %d", got, want)
+			}
+			if tc.resp != "" {
+				if got, want := strings.TrimSpace(w.Body.String()), tc.resp; got != want {
+					t.Errorf("response: got %s, want %s", got, want)
+				}
+			}
+			if got, want := otexts.MinLevel(), tc.level; got != want {
+				t.Errorf("min level: got %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package trace
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -22,6 +24,29 @@ const (
 	LogEventError = "error"
 )
 
+// Well-known log event names, used as the value of the "event" log field so
+// that events can be consistently queried across services.
+const (
+	LogEventWarning                  = "warning"
+	LogEventRetry                    = "retry"
+	LogEventCacheHit                 = "cache.hit"
+	LogEventCacheMiss                = "cache.miss"
+	LogEventCircuitBreakerTransition = "circuit_breaker.transition"
+)
+
+// Well-known log field names for the well-known log events.
+const (
+	LogFieldLevel = "level"
+
+	LogFieldRetryAttempt       = "retry.attempt"
+	LogFieldRetryDelay         = "retry.delay"
+	LogFieldCacheKey           = "cache.key"
+	LogFieldCircuitBreakerName = "circuit_breaker.name"
+	LogFieldCircuitBreakerFrom = "circuit_breaker.from"
+	LogFieldCircuitBreakerTo   = "circuit_breaker.to"
+	LogFieldBaggageKey         = "baggage.key"
+)
+
 // LogFields is a map of opentracing span log field names to values.
 type LogFields map[string]interface{}
 
@@ -48,6 +73,7 @@ func LogError(span opentracing.Span, err error) {
 	ext.Error.Set(span, true)
 	span.LogFields(
 		log.String(LogFieldEvent, LogEventError),
+		log.String(LogFieldLevel, LevelError.String()),
 		log.String(LogFieldErrorKind, fmt.Sprintf("%T", errors.Cause(err))),
 		log.String(LogFieldMessage, err.Error()),
 	)
@@ -62,6 +88,7 @@ func LogErrorf(span opentracing.Span, err error, format string, args ...interfac
 	ext.Error.Set(span, true)
 	span.LogFields(
 		log.String(LogFieldEvent, LogEventError),
+		log.String(LogFieldLevel, LevelError.String()),
 		log.String(LogFieldErrorKind, fmt.Sprintf("%T", errors.Cause(err))),
 		log.String(LogFieldMessage, errors.Wrapf(err, format, args...).Error()),
 	)
@@ -69,8 +96,8 @@ func LogErrorf(span opentracing.Span, err error, format string, args ...interfac
 
 // LogErrorWithFields logs an error with the specified extra lof fields for an
 // opentracing span, setting the standard error tags and log fields. The log
-// field names "event", "error.kind", and "message" are reserved and will be
-// ignored if set in the specified fields.
+// field names "event", "level", "error.kind", and "message" are reserved and
+// will be ignored if set in the specified fields.
 func LogErrorWithFields(span opentracing.Span, err error, fields map[string]interface{}) {
 	if span == nil || err == nil {
 		return
@@ -78,14 +105,159 @@ func LogErrorWithFields(span opentracing.Span, err error, fields map[string]inte
 	ext.Error.Set(span, true)
 	kvs := []interface{}{
 		LogFieldEvent, LogEventError,
+		LogFieldLevel, LevelError.String(),
 		LogFieldErrorKind, fmt.Sprintf("%T", errors.Cause(err)),
 		LogFieldMessage, err.Error(),
 	}
 	for k, v := range fields {
-		if k == LogFieldEvent || k == LogFieldErrorKind || k == LogFieldMessage {
+		if k == LogFieldEvent || k == LogFieldLevel || k == LogFieldErrorKind || k == LogFieldMessage {
 			continue
 		}
 		kvs = append(kvs, k, v)
 	}
 	span.LogKV(kvs...)
 }
+
+// LogEvent logs an info level event with the specified message and extra log
+// fields for an opentracing span. The event should be one of the well-known
+// LogEvent* names where applicable. The message is omitted if empty. The log
+// field names "event", "level", and "message" are reserved and will be ignored
+// if set in the specified fields.
+func LogEvent(span opentracing.Span, event, message string, fields map[string]interface{}) {
+	logEvent(span, LevelInfo, event, message, fields)
+}
+
+// LogDebug logs a debug level event with the specified message and extra log
+// fields for an opentracing span, if debug events are enabled by the minimum
+// level.
+func LogDebug(span opentracing.Span, message string, fields map[string]interface{}) {
+	logEvent(span, LevelDebug, LevelDebug.String(), message, fields)
+}
+
+// LogInfo logs an info level event with the specified message and extra log
+// fields for an opentracing span, if info events are enabled by the minimum
+// level.
+func LogInfo(span opentracing.Span, message string, fields map[string]interface{}) {
+	logEvent(span, LevelInfo, LevelInfo.String(), message, fields)
+}
+
+// logEvent logs an event at the specified level, if enabled by the minimum
+// level.
+func logEvent(span opentracing.Span, level Level, event, message string, fields map[string]interface{}) {
+	if span == nil || !minLevel.Enabled(level) {
+		return
+	}
+	kvs := []interface{}{LogFieldEvent, event, LogFieldLevel, level.String()}
+	if message != "" {
+		kvs = append(kvs, LogFieldMessage, message)
+	}
+	for k, v := range fields {
+		if k == LogFieldEvent || k == LogFieldLevel || k == LogFieldMessage {
+			continue
+		}
+		kvs = append(kvs, k, v)
+	}
+	span.LogKV(kvs...)
+}
+
+// LogWarning logs a warn level warning event with the specified message and
+// extra log fields for an opentracing span. Unlike the error logging
+// functions, the error tag is not set.
+func LogWarning(span opentracing.Span, message string, fields map[string]interface{}) {
+	logEvent(span, LevelWarn, LogEventWarning, message, fields)
+}
+
+// LogRetry logs a warn level retry event for an opentracing span, with the
+// retry attempt number, the delay before the attempt, and the error that
+// caused the retry, if any.
+func LogRetry(span opentracing.Span, attempt int, delay time.Duration, err error) {
+	if span == nil || !minLevel.Enabled(LevelWarn) {
+		return
+	}
+	fields := []log.Field{
+		log.String(LogFieldEvent, LogEventRetry),
+		log.String(LogFieldLevel, LevelWarn.String()),
+		log.Int(LogFieldRetryAttempt, attempt),
+		log.String(LogFieldRetryDelay, delay.String()),
+	}
+	if err != nil {
+		fields = append(fields,
+			log.String(LogFieldErrorKind, fmt.Sprintf("%T", errors.Cause(err))),
+			log.String(LogFieldMessage, err.Error()),
+		)
+	}
+	span.LogFields(fields...)
+}
+
+// LogCacheHit logs a debug level cache hit event for the specified cache
+// key for an opentracing span.
+func LogCacheHit(span opentracing.Span, key string) {
+	if span == nil || !minLevel.Enabled(LevelDebug) {
+		return
+	}
+	span.LogFields(
+		log.String(LogFieldEvent, LogEventCacheHit),
+		log.String(LogFieldLevel, LevelDebug.String()),
+		log.String(LogFieldCacheKey, key),
+	)
+}
+
+// LogCacheMiss logs a debug level cache miss event for the specified cache
+// key for an opentracing span.
+func LogCacheMiss(span opentracing.Span, key string) {
+	if span == nil || !minLevel.Enabled(LevelDebug) {
+		return
+	}
+	span.LogFields(
+		log.String(LogFieldEvent, LogEventCacheMiss),
+		log.String(LogFieldLevel, LevelDebug.String()),
+		log.String(LogFieldCacheKey, key),
+	)
+}
+
+// LogCircuitBreakerTransition logs a warn level circuit breaker state
+// transition event for an opentracing span, e.g. from "closed" to "open".
+func LogCircuitBreakerTransition(span opentracing.Span, name, from, to string) {
+	if span == nil || !minLevel.Enabled(LevelWarn) {
+		return
+	}
+	span.LogFields(
+		log.String(LogFieldEvent, LogEventCircuitBreakerTransition),
+		log.String(LogFieldLevel, LevelWarn.String()),
+		log.String(LogFieldCircuitBreakerName, name),
+		log.String(LogFieldCircuitBreakerFrom, from),
+		log.String(LogFieldCircuitBreakerTo, to),
+	)
+}
+
+// LogErrorCtx calls LogError with the span from the specified context, if any.
+func LogErrorCtx(ctx context.Context, err error) {
+	LogError(opentracing.SpanFromContext(ctx), err)
+}
+
+// LogErrorfCtx calls LogErrorf with the span from the specified context, if
+// any.
+func LogErrorfCtx(ctx context.Context, err error, format string, args ...interface{}) {
+	LogErrorf(opentracing.SpanFromContext(ctx), err, format, args...)
+}
+
+// LogErrorWithFieldsCtx calls LogErrorWithFields with the span from the
+// specified context, if any.
+func LogErrorWithFieldsCtx(ctx context.Context, err error, fields map[string]interface{}) {
+	LogErrorWithFields(opentracing.SpanFromContext(ctx), err, fields)
+}
+
+// LogDebugCtx calls LogDebug with the span from the specified context, if any.
+func LogDebugCtx(ctx context.Context, message string, fields map[string]interface{}) {
+	LogDebug(opentracing.SpanFromContext(ctx), message, fields)
+}
+
+// LogInfoCtx calls LogInfo with the span from the specified context, if any.
+func LogInfoCtx(ctx context.Context, message string, fields map[string]interface{}) {
+	LogInfo(opentracing.SpanFromContext(ctx), message, fields)
+}
+
+// LogEventCtx calls LogEvent with the span from the specified context, if any.
+func LogEventCtx(ctx context.Context, event, message string, fields map[string]interface{}) {
+	LogEvent(opentracing.SpanFromContext(ctx), event, message, fields)
+}
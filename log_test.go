@@ -1,8 +1,10 @@
 package trace_test
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/opentracing/opentracing-go"
 	"github.com/opentracing/opentracing-go/ext"
@@ -40,7 +42,7 @@ func TestLogError(t *testing.T) {
 				t.Fatalf("logs: got %d, want %d", got, want)
 			}
 			logFields := logs[0].Fields
-			if got, want := len(logFields), 3; got != want {
+			if got, want := len(logFields), 4; got != want {
 				t.Fatalf("log fields: got %d, want %d", got, want)
 			}
 			for _, field := range logFields {
@@ -49,6 +51,10 @@ func TestLogError(t *testing.T) {
 					if got, want := field.ValueString, otexts.LogEventError; got != want {
 						t.Errorf("log field: event: got %q, want %q\n", got, want)
 					}
+				case otexts.LogFieldLevel:
+					if got, want := field.ValueString, otexts.LevelError.String(); got != want {
+						t.Errorf("log field: level: got %q, want %q\n", got, want)
+					}
 				case otexts.LogFieldErrorKind:
 					if got, want := field.ValueString, fmt.Sprintf("%T", errors.Cause(tc.err)); got != want {
 						t.Errorf("log field: error.kind: got %q, want %q\n", got, want)
@@ -91,7 +97,7 @@ func TestLogErrorf(t *testing.T) {
 				t.Fatalf("logs: got %d, want %d", got, want)
 			}
 			logFields := logs[0].Fields
-			if got, want := len(logFields), 3; got != want {
+			if got, want := len(logFields), 4; got != want {
 				t.Fatalf("log fields: got %d, want %d", got, want)
 			}
 			for _, field := range logFields {
@@ -100,6 +106,10 @@ func TestLogErrorf(t *testing.T) {
 					if got, want := field.ValueString, otexts.LogEventError; got != want {
 						t.Errorf("log field: event: got %q, want %q\n", got, want)
 					}
+				case otexts.LogFieldLevel:
+					if got, want := field.ValueString, otexts.LevelError.String(); got != want {
+						t.Errorf("log field: level: got %q, want %q\n", got, want)
+					}
 				case otexts.LogFieldErrorKind:
 					if got, want := field.ValueString, fmt.Sprintf("%T", errors.Cause(tc.err)); got != want {
 						t.Errorf("log field: error.kind: got %q, want %q\n", got, want)
@@ -143,7 +153,7 @@ func TestLogErrorWithFields(t *testing.T) {
 				t.Fatalf("logs: got %d, want %d", got, want)
 			}
 			logFields := logs[0].Fields
-			if got, want := len(logFields), 3+len(tc.fields); got != want {
+			if got, want := len(logFields), 4+len(tc.fields); got != want {
 				t.Fatalf("log fields: got %d, want %d", got, want)
 			}
 			for _, field := range logFields {
@@ -152,6 +162,10 @@ func TestLogErrorWithFields(t *testing.T) {
 					if got, want := field.ValueString, otexts.LogEventError; got != want {
 						t.Errorf("log field: event: got %q, want %q\n", got, want)
 					}
+				case otexts.LogFieldLevel:
+					if got, want := field.ValueString, otexts.LevelError.String(); got != want {
+						t.Errorf("log field: level: got %q, want %q\n", got, want)
+					}
 				case otexts.LogFieldErrorKind:
 					if got, want := field.ValueString, fmt.Sprintf("%T", errors.Cause(tc.err)); got != want {
 						t.Errorf("log field: error.kind: got %q, want %q\n", got, want)
@@ -173,3 +187,227 @@ func TestLogErrorWithFields(t *testing.T) {
 		})
 	}
 }
+
+func TestLogEvent(t *testing.T) {
+	tt := []struct {
+		name    string
+		event   string
+		message string
+		fields  map[string]interface{}
+		want    map[string]string
+	}{
+		{
+			name:    "event",
+			event:   "retry",
+			message: "retrying",
+			fields: map[string]interface{}{
+				"foo":                  "bar",
+				otexts.LogFieldEvent:   "ignored",
+				otexts.LogFieldMessage: "ignored",
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:   "retry",
+				otexts.LogFieldLevel:   "info",
+				otexts.LogFieldMessage: "retrying",
+				"foo":                  "bar",
+			},
+		},
+		{
+			name:  "no message",
+			event: "cache.hit",
+			want: map[string]string{
+				otexts.LogFieldEvent: "cache.hit",
+				otexts.LogFieldLevel: "info",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := opentracing.StartSpan("test").(*mocktracer.MockSpan)
+			otexts.LogEvent(span, tc.event, tc.message, tc.fields)
+			span.Finish()
+
+			if span.Tag(string(ext.Error)) != nil {
+				t.Error("unexpected error tag")
+			}
+			logs := span.Logs()
+			if got, want := len(logs), 1; got != want {
+				t.Fatalf("logs: got %d, want %d", got, want)
+			}
+			logFields := logs[0].Fields
+			if got, want := len(logFields), len(tc.want); got != want {
+				t.Fatalf("log fields: got %d, want %d", got, want)
+			}
+			for _, field := range logFields {
+				if got, want := field.ValueString, tc.want[field.Key]; got != want {
+					t.Errorf("log field: %s: got %q, want %q\n", field.Key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogCtx(t *testing.T) {
+	tt := []struct {
+		name string
+		log  func(ctx context.Context)
+		err  bool
+	}{
+		{
+			name: "LogErrorCtx",
+			log: func(ctx context.Context) {
+				otexts.LogErrorCtx(ctx, errors.New("error"))
+			},
+			err: true,
+		},
+		{
+			name: "LogErrorfCtx",
+			log: func(ctx context.Context) {
+				otexts.LogErrorfCtx(ctx, errors.New("error"), "foo: %s", "bar")
+			},
+			err: true,
+		},
+		{
+			name: "LogErrorWithFieldsCtx",
+			log: func(ctx context.Context) {
+				otexts.LogErrorWithFieldsCtx(ctx, errors.New("error"), map[string]interface{}{"foo": "bar"})
+			},
+			err: true,
+		},
+		{
+			name: "LogEventCtx",
+			log: func(ctx context.Context) {
+				otexts.LogEventCtx(ctx, "retry", "retrying", nil)
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Must not panic without a span in the context.
+			tc.log(context.Background())
+
+			span := opentracing.StartSpan("test").(*mocktracer.MockSpan)
+			tc.log(opentracing.ContextWithSpan(context.Background(), span))
+			span.Finish()
+
+			if got, want := span.Tag(string(ext.Error)) != nil, tc.err; got != want {
+				t.Errorf("error tag: got %t, want %t", got, want)
+			}
+			if got, want := len(span.Logs()), 1; got != want {
+				t.Errorf("logs: got %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestLogEventHelpers(t *testing.T) {
+	otexts.SetMinLevel(otexts.LevelDebug)
+	defer otexts.SetMinLevel(otexts.LevelInfo)
+
+	tt := []struct {
+		name string
+		log  func(span opentracing.Span)
+		want map[string]string
+	}{
+		{
+			name: "LogWarning",
+			log: func(span opentracing.Span) {
+				otexts.LogWarning(span, "slow", map[string]interface{}{"foo": "bar"})
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:   otexts.LogEventWarning,
+				otexts.LogFieldLevel:   "warn",
+				otexts.LogFieldMessage: "slow",
+				"foo":                  "bar",
+			},
+		},
+		{
+			name: "LogRetry",
+			log: func(span opentracing.Span) {
+				otexts.LogRetry(span, 2, time.Second, errors.New("error"))
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:        otexts.LogEventRetry,
+				otexts.LogFieldLevel:        "warn",
+				otexts.LogFieldRetryAttempt: "2",
+				otexts.LogFieldRetryDelay:   "1s",
+				otexts.LogFieldErrorKind:    fmt.Sprintf("%T", errors.New("error")),
+				otexts.LogFieldMessage:      "error",
+			},
+		},
+		{
+			name: "LogRetry without error",
+			log: func(span opentracing.Span) {
+				otexts.LogRetry(span, 1, 0, nil)
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:        otexts.LogEventRetry,
+				otexts.LogFieldLevel:        "warn",
+				otexts.LogFieldRetryAttempt: "1",
+				otexts.LogFieldRetryDelay:   "0s",
+			},
+		},
+		{
+			name: "LogCacheHit",
+			log: func(span opentracing.Span) {
+				otexts.LogCacheHit(span, "key")
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:    otexts.LogEventCacheHit,
+				otexts.LogFieldLevel:    "debug",
+				otexts.LogFieldCacheKey: "key",
+			},
+		},
+		{
+			name: "LogCacheMiss",
+			log: func(span opentracing.Span) {
+				otexts.LogCacheMiss(span, "key")
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:    otexts.LogEventCacheMiss,
+				otexts.LogFieldLevel:    "debug",
+				otexts.LogFieldCacheKey: "key",
+			},
+		},
+		{
+			name: "LogCircuitBreakerTransition",
+			log: func(span opentracing.Span) {
+				otexts.LogCircuitBreakerTransition(span, "db", "closed", "open")
+			},
+			want: map[string]string{
+				otexts.LogFieldEvent:              otexts.LogEventCircuitBreakerTransition,
+				otexts.LogFieldLevel:              "warn",
+				otexts.LogFieldCircuitBreakerName: "db",
+				otexts.LogFieldCircuitBreakerFrom: "closed",
+				otexts.LogFieldCircuitBreakerTo:   "open",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Must not panic with a nil span.
+			tc.log(nil)
+
+			span := opentracing.StartSpan("test").(*mocktracer.MockSpan)
+			tc.log(span)
+			span.Finish()
+
+			if span.Tag(string(ext.Error)) != nil {
+				t.Error("unexpected error tag")
+			}
+			logs := span.Logs()
+			if got, want := len(logs), 1; got != want {
+				t.Fatalf("logs: got %d, want %d", got, want)
+			}
+			logFields := logs[0].Fields
+			if got, want := len(logFields), len(tc.want); got != want {
+				t.Fatalf("log fields: got %d, want %d", got, want)
+			}
+			for _, field := range logFields {
+				if got, want := field.ValueString, tc.want[field.Key]; got != want {
+					t.Errorf("log field: %s: got %q, want %q\n", field.Key, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package trace
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// KafkaHeader is a Kafka record header. It has the same fields as the record
+// headers of common Kafka clients, e.g. sarama.RecordHeader, so that their
+// headers can be converted to and from KafkaHeader.
+type KafkaHeader struct {
+	Key   []byte
+	Value []byte
+}
+
+// KafkaHeadersCarrier is an opentracing carrier over Kafka record headers.
+//
+// Example:
+//
+//	headers := otexts.KafkaHeadersCarrier{}
+//	err := tracer.Inject(span.Context(), opentracing.TextMap, &headers)
+type KafkaHeadersCarrier []KafkaHeader
+
+// Ensure KafkaHeadersCarrier implements the TextMapReader and TextMapWriter
+// interfaces.
+var (
+	_ opentracing.TextMapReader = KafkaHeadersCarrier{}
+	_ opentracing.TextMapWriter = &KafkaHeadersCarrier{}
+)
+
+// ForeachKey implements the opentracing.TextMapReader interface.
+func (c KafkaHeadersCarrier) ForeachKey(handler func(key, val string) error) error {
+	for _, h := range c {
+		if err := handler(string(h.Key), string(h.Value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set implements the opentracing.TextMapWriter interface. It replaces the
+// headers with the specified key, if any.
+func (c *KafkaHeadersCarrier) Set(key, val string) {
+	headers := (*c)[:0]
+	for _, h := range *c {
+		if string(h.Key) != key {
+			headers = append(headers, h)
+		}
+	}
+	*c = append(headers, KafkaHeader{Key: []byte(key), Value: []byte(val)})
+}
+
+// AMQPHeadersCarrier is an opentracing carrier over an AMQP header table, e.g.
+// an amqp.Table. Only the string and []byte header values are read.
+type AMQPHeadersCarrier map[string]interface{}
+
+// Ensure AMQPHeadersCarrier implements the TextMapReader and TextMapWriter
+// interfaces.
+var (
+	_ opentracing.TextMapReader = AMQPHeadersCarrier{}
+	_ opentracing.TextMapWriter = AMQPHeadersCarrier{}
+)
+
+// ForeachKey implements the opentracing.TextMapReader interface.
+func (c AMQPHeadersCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, v := range c {
+		var s string
+		switch v := v.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		default:
+			continue
+		}
+		if err := handler(k, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set implements the opentracing.TextMapWriter interface.
+func (c AMQPHeadersCarrier) Set(key, val string) {
+	c[key] = val
+}
+
+// NATSHeadersCarrier is an opentracing carrier over NATS message headers, e.g.
+// a nats.Header. Unlike HTTP headers, NATS header keys are case-sensitive.
+type NATSHeadersCarrier map[string][]string
+
+// Ensure NATSHeadersCarrier implements the TextMapReader and TextMapWriter
+// interfaces.
+var (
+	_ opentracing.TextMapReader = NATSHeadersCarrier{}
+	_ opentracing.TextMapWriter = NATSHeadersCarrier{}
+)
+
+// ForeachKey implements the opentracing.TextMapReader interface.
+func (c NATSHeadersCarrier) ForeachKey(handler func(key, val string) error) error {
+	for k, vals := range c {
+		for _, v := range vals {
+			if err := handler(k, v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Set implements the opentracing.TextMapWriter interface.
+func (c NATSHeadersCarrier) Set(key, val string) {
+	c[key] = []string{val}
+}
+
+// StartProducerSpan starts a producer span that is a child of the span in the
+// specified context, if any, with the message_bus.destination tag set to the
+// specified destination, and injects its span context in the headers of the
+// message carrier. It returns the span and a copy of the context with the
+// span. If the span context cannot be injected, a warning is logged for the
+// span.
+//
+// Example:
+//
+//	headers := otexts.KafkaHeadersCarrier{}
+//	span, ctx := otexts.StartProducerSpan(ctx, "send", "orders", &headers)
+//	defer span.Finish()
+func StartProducerSpan(ctx context.Context, operationName, destination string, carrier opentracing.TextMapWriter, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	opts = append([]opentracing.StartSpanOption{
+		ext.SpanKindProducer,
+		opentracing.Tag{Key: string(ext.MessageBusDestination), Value: destination},
+	}, opts...)
+	span, ctx := startSpanFromContext(ctx, opentracing.ChildOfRef, operationName, opts...)
+	if err := span.Tracer().Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		LogWarning(span, "inject span context in message headers", map[string]interface{}{
+			LogFieldErrorObject: err,
+		})
+	}
+	return span, ctx
+}
+
+// StartConsumerSpan starts a consumer span with the message_bus.destination
+// tag set to the specified destination. The span follows from the span
+// context extracted from the headers of the message carrier, or, if there is
+// none, from the span in the specified context, if any. It returns the span
+// and a copy of the context with the span. If the headers have an invalid span
+// context, a warning is logged for the span.
+//
+// The span context is extracted with the tracer of the span in the specified
+// context, if any, or the global tracer otherwise.
+func StartConsumerSpan(ctx context.Context, operationName, destination string, carrier opentracing.TextMapReader, opts ...opentracing.StartSpanOption) (opentracing.Span, context.Context) {
+	opts = append([]opentracing.StartSpanOption{
+		ext.SpanKindConsumer,
+		opentracing.Tag{Key: string(ext.MessageBusDestination), Value: destination},
+	}, opts...)
+	tracer := opentracing.GlobalTracer()
+	if parent := opentracing.SpanFromContext(ctx); parent != nil {
+		tracer = parent.Tracer()
+	}
+	producer, err := tracer.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		span, ctx := startSpanFromContext(ctx, opentracing.FollowsFromRef, operationName, opts...)
+		if err != opentracing.ErrSpanContextNotFound {
+			LogWarning(span, "extract span context from message headers", map[string]interface{}{
+				LogFieldErrorObject: err,
+			})
+		}
+		return span, ctx
+	}
+	span := tracer.StartSpan(operationName, append([]opentracing.StartSpanOption{opentracing.FollowsFrom(producer)}, opts...)...)
+	return span, opentracing.ContextWithSpan(ctx, span)
+}
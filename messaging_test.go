@@ -0,0 +1,128 @@
+package trace_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+type messageCarrier interface {
+	opentracing.TextMapReader
+	opentracing.TextMapWriter
+}
+
+func TestMessageCarriers(t *testing.T) {
+	tt := []struct {
+		name    string
+		carrier messageCarrier
+	}{
+		{name: "Kafka", carrier: &otexts.KafkaHeadersCarrier{{Key: []byte("other"), Value: []byte("value")}}},
+		{name: "AMQP", carrier: otexts.AMQPHeadersCarrier{"other": []byte("value"), "count": 1}},
+		{name: "NATS", carrier: otexts.NATSHeadersCarrier{"other": {"value"}}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.carrier.Set("key", "old")
+			tc.carrier.Set("key", "new")
+			tc.carrier.Set("Key", "case")
+
+			var got []string
+			tc.carrier.ForeachKey(func(k, v string) error {
+				got = append(got, k+"="+v)
+				return nil
+			})
+			sort.Strings(got)
+			if want := []string{"Key=case", "key=new", "other=value"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("headers: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestStartProducerConsumerSpan(t *testing.T) {
+	tracer := mocktracer.New()
+	request := tracer.StartSpan("request")
+	ctx := opentracing.ContextWithSpan(context.Background(), request)
+
+	headers := otexts.KafkaHeadersCarrier{}
+	producer, producerCtx := otexts.StartProducerSpan(ctx, "send", "orders", &headers)
+	if got, want := opentracing.SpanFromContext(producerCtx), producer; got != want {
+		t.Errorf("producer context span: got %v, want %v", got, want)
+	}
+	producer.Finish()
+	request.Finish()
+
+	consumerCtx := opentracing.ContextWithSpan(context.Background(), tracer.StartSpan("poll"))
+	consumer, consumerCtx := otexts.StartConsumerSpan(consumerCtx, "receive", "orders", headers)
+	if got, want := opentracing.SpanFromContext(consumerCtx), consumer; got != want {
+		t.Errorf("consumer context span: got %v, want %v", got, want)
+	}
+	consumer.Finish()
+
+	producerSpan := producer.(*mocktracer.MockSpan)
+	consumerSpan := consumer.(*mocktracer.MockSpan)
+	if got, want := producerSpan.ParentID, request.(*mocktracer.MockSpan).SpanContext.SpanID; got != want {
+		t.Errorf("producer parent ID: got %d, want %d", got, want)
+	}
+	if got, want := consumerSpan.ParentID, producerSpan.SpanContext.SpanID; got != want {
+		t.Errorf("consumer parent ID: got %d, want %d", got, want)
+	}
+	if got, want := consumerSpan.SpanContext.TraceID, producerSpan.SpanContext.TraceID; got != want {
+		t.Errorf("consumer trace ID: got %d, want %d", got, want)
+	}
+
+	tt := []struct {
+		name string
+		span *mocktracer.MockSpan
+		kind ext.SpanKindEnum
+	}{
+		{name: "producer", span: producerSpan, kind: ext.SpanKindProducerEnum},
+		{name: "consumer", span: consumerSpan, kind: ext.SpanKindConsumerEnum},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ensureTagsSet(t, map[string]string{
+				string(ext.SpanKind):              string(tc.kind),
+				string(ext.MessageBusDestination): "orders",
+			}, tc.span.Tags())
+		})
+	}
+}
+
+func TestStartConsumerSpanNoProducer(t *testing.T) {
+	tracer := mocktracer.New()
+	poll := tracer.StartSpan("poll")
+	ctx := opentracing.ContextWithSpan(context.Background(), poll)
+
+	consumer, _ := otexts.StartConsumerSpan(ctx, "receive", "orders", otexts.NATSHeadersCarrier{})
+	consumer.Finish()
+	consumerSpan := consumer.(*mocktracer.MockSpan)
+	if got, want := consumerSpan.ParentID, poll.(*mocktracer.MockSpan).SpanContext.SpanID; got != want {
+		t.Errorf("consumer parent ID: got %d, want %d", got, want)
+	}
+	if got, want := len(consumerSpan.Logs()), 0; got != want {
+		t.Errorf("logs: got %d, want %d", got, want)
+	}
+
+	// The mocktracer stops at the first invalid header, so the IDs must be
+	// read before the invalid sampled header.
+	corrupted := &otexts.KafkaHeadersCarrier{
+		{Key: []byte("mockpfx-ids-traceid"), Value: []byte("1")},
+		{Key: []byte("mockpfx-ids-spanid"), Value: []byte("2")},
+		{Key: []byte("mockpfx-ids-sampled"), Value: []byte("invalid")},
+	}
+	consumer, _ = otexts.StartConsumerSpan(ctx, "receive", "orders", corrupted)
+	consumer.Finish()
+	if got, want := len(consumer.(*mocktracer.MockSpan).Logs()), 1; got != want {
+		t.Errorf("corrupted headers logs: got %d, want %d", got, want)
+	}
+}
@@ -0,0 +1,263 @@
+package trace
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Ensure MultiTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &MultiTracer{}
+
+// MultiTracer is an opentracing.Tracer that reports every span to multiple
+// tracers, e.g. while migrating between tracing backends. Each span starts a
+// span in every tracer, with the tags and start time of its span options, and
+// forwards the tags, logs, baggage items, and finish to all of them.
+//
+// References to span contexts of a MultiTracer reference the span context of
+// each tracer. Other span contexts, e.g. extracted by one of the tracers, are
+// referenced only in the tracers that can inject them, since tracers do not
+// accept the span contexts of other tracers.
+type MultiTracer struct {
+	// Tracers are the tracers to report spans to. The first tracer is the
+	// primary tracer, used to read baggage items and to inject span contexts.
+	Tracers []opentracing.Tracer
+
+	// InjectAll injects span contexts with every tracer, in order, rather
+	// than only with the primary tracer. Tracers that use the same carrier
+	// keys overwrite the keys injected by the previous tracers.
+	InjectAll bool
+}
+
+// NewMultiTracer returns a new tracer that reports every span to the
+// specified tracers, the first being the primary tracer.
+func NewMultiTracer(tracers ...opentracing.Tracer) *MultiTracer {
+	return &MultiTracer{Tracers: tracers}
+}
+
+// tracers returns the tracers of the tracer, or a no-op tracer if it has
+// none.
+func (t *MultiTracer) tracers() []opentracing.Tracer {
+	if len(t.Tracers) == 0 {
+		return []opentracing.Tracer{opentracing.NoopTracer{}}
+	}
+	return t.Tracers
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *MultiTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	tracers := t.tracers()
+	span := &multiSpan{spans: make([]opentracing.Span, len(tracers)), tracer: t}
+	for i, tracer := range tracers {
+		childOpts := make([]opentracing.StartSpanOption, 0, len(sso.References)+2)
+		for _, ref := range sso.References {
+			sc := ref.ReferencedContext
+			if c, ok := sc.(multiSpanContext); ok {
+				sc = c.ctx(i)
+			} else if sc != nil && !canReference(tracer, sc) {
+				sc = nil
+			}
+			if sc != nil {
+				childOpts = append(childOpts, opentracing.SpanReference{Type: ref.Type, ReferencedContext: sc})
+			}
+		}
+		if !sso.StartTime.IsZero() {
+			childOpts = append(childOpts, opentracing.StartTime(sso.StartTime))
+		}
+		if len(sso.Tags) > 0 {
+			tags := make(opentracing.Tags, len(sso.Tags))
+			for k, v := range sso.Tags {
+				tags[k] = v
+			}
+			childOpts = append(childOpts, tags)
+		}
+		span.spans[i] = tracer.StartSpan(operationName, childOpts...)
+	}
+	return span
+}
+
+// canReference reports whether the specified tracer can reference the
+// specified span context, that is not a MultiTracer span context. Tracers
+// reject the span contexts of other tracers when injecting them, e.g. with
+// opentracing.ErrInvalidSpanContext.
+func canReference(tracer opentracing.Tracer, sc opentracing.SpanContext) bool {
+	return tracer.Inject(sc, opentracing.TextMap, opentracing.TextMapCarrier{}) == nil
+}
+
+// Inject implements the opentracing.Tracer interface.
+func (t *MultiTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	tracers := t.tracers()
+	c, ok := sc.(multiSpanContext)
+	if !ok {
+		return tracers[0].Inject(sc, format, carrier)
+	}
+	if !t.InjectAll {
+		if c.ctx(0) == nil {
+			return opentracing.ErrInvalidSpanContext
+		}
+		return tracers[0].Inject(c.ctx(0), format, carrier)
+	}
+	var err error
+	for i, tracer := range tracers {
+		if c.ctx(i) == nil {
+			continue
+		}
+		if injectErr := tracer.Inject(c.ctx(i), format, carrier); injectErr != nil && err == nil {
+			err = injectErr
+		}
+	}
+	return err
+}
+
+// Extract implements the opentracing.Tracer interface. It extracts the span
+// context of every tracer that succeeds, and returns the error of the primary
+// tracer if none does.
+func (t *MultiTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	tracers := t.tracers()
+	c := multiSpanContext{ctxs: make([]opentracing.SpanContext, len(tracers))}
+	var found bool
+	var primaryErr error
+	for i, tracer := range tracers {
+		sc, err := tracer.Extract(format, carrier)
+		if err != nil {
+			if i == 0 {
+				primaryErr = err
+			}
+			continue
+		}
+		c.ctxs[i] = sc
+		found = true
+	}
+	if !found {
+		return nil, primaryErr
+	}
+	return c, nil
+}
+
+// multiSpanContext is the span context of a MultiTracer span, with the span
+// context of each tracer, or nil if it has none.
+type multiSpanContext struct {
+	ctxs []opentracing.SpanContext
+}
+
+// ctx returns the span context of the tracer with the specified index, or nil
+// if it has none.
+func (c multiSpanContext) ctx(i int) opentracing.SpanContext {
+	if i < len(c.ctxs) {
+		return c.ctxs[i]
+	}
+	return nil
+}
+
+// ForeachBaggageItem implements the opentracing.SpanContext interface. It
+// iterates the baggage items of the first tracer with a span context.
+func (c multiSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for _, sc := range c.ctxs {
+		if sc != nil {
+			sc.ForeachBaggageItem(handler)
+			return
+		}
+	}
+}
+
+// multiSpan is a span of a MultiTracer, with the span of each tracer.
+type multiSpan struct {
+	spans  []opentracing.Span
+	tracer *MultiTracer
+}
+
+// Finish implements the opentracing.Span interface.
+func (s *multiSpan) Finish() {
+	for _, span := range s.spans {
+		span.Finish()
+	}
+}
+
+// FinishWithOptions implements the opentracing.Span interface.
+func (s *multiSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	for _, span := range s.spans {
+		span.FinishWithOptions(opts)
+	}
+}
+
+// Context implements the opentracing.Span interface.
+func (s *multiSpan) Context() opentracing.SpanContext {
+	c := multiSpanContext{ctxs: make([]opentracing.SpanContext, len(s.spans))}
+	for i, span := range s.spans {
+		c.ctxs[i] = span.Context()
+	}
+	return c
+}
+
+// SetOperationName implements the opentracing.Span interface.
+func (s *multiSpan) SetOperationName(operationName string) opentracing.Span {
+	for _, span := range s.spans {
+		span.SetOperationName(operationName)
+	}
+	return s
+}
+
+// SetTag implements the opentracing.Span interface.
+func (s *multiSpan) SetTag(key string, value interface{}) opentracing.Span {
+	for _, span := range s.spans {
+		span.SetTag(key, value)
+	}
+	return s
+}
+
+// LogFields implements the opentracing.Span interface.
+func (s *multiSpan) LogFields(fields ...log.Field) {
+	for _, span := range s.spans {
+		span.LogFields(fields...)
+	}
+}
+
+// LogKV implements the opentracing.Span interface.
+func (s *multiSpan) LogKV(alternatingKeyValues ...interface{}) {
+	for _, span := range s.spans {
+		span.LogKV(alternatingKeyValues...)
+	}
+}
+
+// SetBaggageItem implements the opentracing.Span interface.
+func (s *multiSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	for _, span := range s.spans {
+		span.SetBaggageItem(restrictedKey, value)
+	}
+	return s
+}
+
+// BaggageItem implements the opentracing.Span interface. It returns the
+// baggage item of the primary tracer's span.
+func (s *multiSpan) BaggageItem(restrictedKey string) string {
+	return s.spans[0].BaggageItem(restrictedKey)
+}
+
+// Tracer implements the opentracing.Span interface.
+func (s *multiSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent implements the deprecated opentracing.Span method.
+func (s *multiSpan) LogEvent(event string) {
+	for _, span := range s.spans {
+		span.LogEvent(event)
+	}
+}
+
+// LogEventWithPayload implements the deprecated opentracing.Span method.
+func (s *multiSpan) LogEventWithPayload(event string, payload interface{}) {
+	for _, span := range s.spans {
+		span.LogEventWithPayload(event, payload)
+	}
+}
+
+// Log implements the deprecated opentracing.Span method.
+func (s *multiSpan) Log(data opentracing.LogData) {
+	for _, span := range s.spans {
+		span.Log(data)
+	}
+}
@@ -0,0 +1,208 @@
+package trace_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+	"github.com/code-willing/opentracing-exts/jsontrace"
+)
+
+func TestMultiTracer(t *testing.T) {
+	primary, secondary := mocktracer.New(), mocktracer.New()
+	tracer := otexts.NewMultiTracer(primary, secondary)
+
+	parent := tracer.StartSpan("parent", otexts.RPCTags{Kind: ext.SpanKindRPCServerEnum})
+	parent.SetBaggageItem("item", "value")
+	child := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()), otexts.HTTPTags{Method: http.MethodGet})
+	child.SetOperationName("renamed")
+	otexts.LogError(child, errors.New("error"))
+	if got, want := child.BaggageItem("item"), "value"; got != want {
+		t.Errorf("baggage item: got %q, want %q", got, want)
+	}
+	if got, want := child.Tracer(), opentracing.Tracer(tracer); got != want {
+		t.Errorf("span tracer: got %v, want %v", got, want)
+	}
+	child.Finish()
+	parent.Finish()
+
+	for name, mock := range map[string]*mocktracer.MockTracer{"primary": primary, "secondary": secondary} {
+		spans := mock.FinishedSpans()
+		if got, want := len(spans), 2; got != want {
+			t.Fatalf("%s finished spans: got %d, want %d", name, got, want)
+		}
+		child, parent := spans[0], spans[1]
+		if got, want := child.OperationName, "renamed"; got != want {
+			t.Errorf("%s operation name: got %q, want %q", name, got, want)
+		}
+		if got, want := child.ParentID, parent.SpanContext.SpanID; got != want {
+			t.Errorf("%s parent ID: got %d, want %d", name, got, want)
+		}
+		if got, want := parent.Tag(string(ext.SpanKind)), string(ext.SpanKindRPCServerEnum); got != want {
+			t.Errorf("%s span kind tag: got %v, want %v", name, got, want)
+		}
+		if got, want := child.Tag(string(ext.HTTPMethod)), http.MethodGet; got != want {
+			t.Errorf("%s http method tag: got %v, want %v", name, got, want)
+		}
+		if got, want := child.Tag(string(ext.Error)), true; got != want {
+			t.Errorf("%s error tag: got %v, want %v", name, got, want)
+		}
+		if got, want := len(child.Logs()), 1; got != want {
+			t.Errorf("%s logs: got %d, want %d", name, got, want)
+		}
+	}
+}
+
+func TestMultiTracer_propagation(t *testing.T) {
+	tt := []struct {
+		name      string
+		injectAll bool
+		extracted []bool // Whether each tracer extracts the span context.
+	}{
+		{
+			name:      "inject primary",
+			extracted: []bool{true, false},
+		},
+		{
+			name:      "inject all",
+			injectAll: true,
+			extracted: []bool{true, true},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// The tracers use different carrier keys.
+			mocks := []*mocktracer.MockTracer{mocktracer.New(), mocktracer.New()}
+			for i, prefix := range []string{"primary-", "secondary-"} {
+				propagator := mocktracer.TextMapPropagator{}
+				mocks[i].RegisterInjector(opentracing.TextMap, prefixPropagator{prefix: prefix, p: &propagator})
+				mocks[i].RegisterExtractor(opentracing.TextMap, prefixPropagator{prefix: prefix, p: &propagator})
+			}
+			tracer := &otexts.MultiTracer{
+				Tracers:   []opentracing.Tracer{mocks[0], mocks[1]},
+				InjectAll: tc.injectAll,
+			}
+
+			span := tracer.StartSpan("client")
+			carrier := opentracing.TextMapCarrier{}
+			if err := tracer.Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+				t.Fatalf("inject: %v", err)
+			}
+			sc, err := tracer.Extract(opentracing.TextMap, carrier)
+			if err != nil {
+				t.Fatalf("extract: %v", err)
+			}
+			tracer.StartSpan("server", ext.RPCServerOption(sc)).Finish()
+			span.Finish()
+
+			for i, mock := range mocks {
+				spans := mock.FinishedSpans()
+				server, client := spans[0], spans[1]
+				if got, want := server.ParentID == client.SpanContext.SpanID, tc.extracted[i]; got != want {
+					t.Errorf("tracer %d: child of client: got %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMultiTracer_extractFallback(t *testing.T) {
+	primary, secondary := mocktracer.New(), mocktracer.New()
+	tracer := otexts.NewMultiTracer(primary, secondary)
+
+	carrier := opentracing.TextMapCarrier{}
+	if _, err := tracer.Extract(opentracing.TextMap, carrier); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("extract: got error %v, want %v", err, opentracing.ErrSpanContextNotFound)
+	}
+
+	span := secondary.StartSpan("client")
+	if err := secondary.Inject(span.Context(), opentracing.TextMap, carrier); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	// Both mock tracers use the same carrier keys, so fail the primary's extraction.
+	primary.RegisterExtractor(opentracing.TextMap, failingExtractor{})
+	sc, err := tracer.Extract(opentracing.TextMap, carrier)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if err := tracer.Inject(sc, opentracing.TextMap, opentracing.TextMapCarrier{}); err != opentracing.ErrInvalidSpanContext {
+		t.Errorf("inject: got error %v, want %v", err, opentracing.ErrInvalidSpanContext)
+	}
+	tracer.StartSpan("server", opentracing.ChildOf(sc)).Finish()
+
+	if got, want := primary.FinishedSpans()[0].ParentID, 0; got != want {
+		t.Errorf("primary parent ID: got %d, want %d", got, want)
+	}
+	if got, want := secondary.FinishedSpans()[0].ParentID, span.Context().(mocktracer.MockSpanContext).SpanID; got != want {
+		t.Errorf("secondary parent ID: got %d, want %d", got, want)
+	}
+}
+
+// prefixPropagator is a mocktracer propagator that prefixes the carrier keys.
+type prefixPropagator struct {
+	prefix string
+	p      *mocktracer.TextMapPropagator
+}
+
+func (p prefixPropagator) Inject(sc mocktracer.MockSpanContext, carrier interface{}) error {
+	tm := opentracing.TextMapCarrier{}
+	if err := p.p.Inject(sc, tm); err != nil {
+		return err
+	}
+	w := carrier.(opentracing.TextMapWriter)
+	return tm.ForeachKey(func(k, v string) error {
+		w.Set(p.prefix+k, v)
+		return nil
+	})
+}
+
+func (p prefixPropagator) Extract(carrier interface{}) (mocktracer.MockSpanContext, error) {
+	tm := opentracing.TextMapCarrier{}
+	err := carrier.(opentracing.TextMapReader).ForeachKey(func(k, v string) error {
+		if len(k) > len(p.prefix) && k[:len(p.prefix)] == p.prefix {
+			tm.Set(k[len(p.prefix):], v)
+		}
+		return nil
+	})
+	if err != nil {
+		return mocktracer.MockSpanContext{}, err
+	}
+	return p.p.Extract(tm)
+}
+
+// failingExtractor is a mocktracer extractor that never finds a span context.
+type failingExtractor struct{}
+
+func (failingExtractor) Extract(carrier interface{}) (mocktracer.MockSpanContext, error) {
+	return mocktracer.MockSpanContext{}, opentracing.ErrSpanContextNotFound
+}
+
+func TestMultiTracer_foreignReference(t *testing.T) {
+	mock := mocktracer.New()
+	var buf bytes.Buffer
+	json := jsontrace.NewTracer(&buf, nil)
+	tracer := otexts.NewMultiTracer(mock, json)
+
+	// A span context of one of the tracers, e.g. extracted by it.
+	client := json.StartSpan("client")
+	tracer.StartSpan("server", opentracing.ChildOf(client.Context())).Finish()
+	client.Finish()
+
+	if got, want := mock.FinishedSpans()[0].ParentID, 0; got != want {
+		t.Errorf("mock parent ID: got %d, want %d", got, want)
+	}
+	spans, err := jsontrace.ReadSpans(&buf)
+	if err != nil {
+		t.Fatalf("read spans: %v", err)
+	}
+	server, parent := spans[0], spans[1]
+	if got, want := server.ParentID, parent.SpanID; got != want {
+		t.Errorf("json parent ID: got %q, want %q", got, want)
+	}
+}
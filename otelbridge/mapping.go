@@ -0,0 +1,207 @@
+// Package otelbridge provides an opentracing.Tracer that records its spans as
+// OpenTelemetry spans, mapping the standard opentracing tags and the error
+// logs of this module to the OpenTelemetry semantic conventions.
+package otelbridge
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/opentracing/opentracing-go/ext"
+	"go.opentelemetry.io/otel/attribute"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// OpenTelemetry semantic convention attribute names.
+// See https://opentelemetry.io/docs/specs/semconv/.
+const (
+	AttrHTTPRequestMethod        = "http.request.method"
+	AttrHTTPResponseStatusCode   = "http.response.status_code"
+	AttrURLFull                  = "url.full"
+	AttrDBSystem                 = "db.system"
+	AttrDBNamespace              = "db.namespace"
+	AttrDBQueryText              = "db.query.text"
+	AttrDBUser                   = "db.user"
+	AttrServerAddress            = "server.address"
+	AttrServerPort               = "server.port"
+	AttrNetworkPeerAddress       = "network.peer.address"
+	AttrPeerService              = "peer.service"
+	AttrMessagingDestinationName = "messaging.destination.name"
+	AttrExceptionType            = "exception.type"
+	AttrExceptionMessage         = "exception.message"
+	AttrExceptionStacktrace      = "exception.stacktrace"
+)
+
+// Mapping maps the value of an opentracing tag or log field to OpenTelemetry
+// attributes.
+type Mapping func(value interface{}) []attribute.KeyValue
+
+// Mappings are the mappings of opentracing tags or log fields, by key. A nil
+// mapping drops the tag or log field. Tags and log fields without a mapping
+// are recorded as attributes with the same key.
+type Mappings map[string]Mapping
+
+// Rename returns a mapping to the attribute with the specified key.
+func Rename(key string) Mapping {
+	return func(value interface{}) []attribute.KeyValue {
+		return []attribute.KeyValue{Attribute(key, value)}
+	}
+}
+
+// clone returns a copy of the mappings.
+func (m Mappings) clone() Mappings {
+	c := make(Mappings, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// attributes returns the attributes of the specified opentracing tag or log
+// field.
+func (m Mappings) attributes(key string, value interface{}) []attribute.KeyValue {
+	mapping, ok := m[key]
+	if !ok {
+		return []attribute.KeyValue{Attribute(key, value)}
+	}
+	if mapping == nil {
+		return nil
+	}
+	return mapping(value)
+}
+
+// attributePrecedence are the opentracing tags and log fields mapped to the
+// same attribute by the default mappings, in order of precedence: the
+// attribute is set by the first of them that is set, e.g. the
+// "network.peer.address" attribute by the "peer.ipv4" tag.
+var attributePrecedence = []string{
+	string(ext.PeerHostIPv4),
+	string(ext.PeerHostIPv6),
+	otexts.LogFieldMessage,
+	otexts.LogFieldErrorObject,
+}
+
+// precedence returns the precedence of the specified tag or log field key, the
+// lower the higher.
+func precedence(key string) int {
+	for i, k := range attributePrecedence {
+		if k == key {
+			return i
+		}
+	}
+	return len(attributePrecedence)
+}
+
+// keyValue is an opentracing tag or log field.
+type keyValue struct {
+	key   string
+	value interface{}
+}
+
+// attributesOf returns the attributes of the specified opentracing tags or log
+// fields. An attribute mapped from more than one of them is set by the one of
+// highest precedence, then by the first one, so that the attributes are
+// deterministic.
+func (m Mappings) attributesOf(kvs []keyValue) []attribute.KeyValue {
+	sort.SliceStable(kvs, func(i, j int) bool {
+		return precedence(kvs[i].key) < precedence(kvs[j].key)
+	})
+	var attrs []attribute.KeyValue
+	set := make(map[attribute.Key]bool, len(kvs))
+	for _, kv := range kvs {
+		for _, attr := range m.attributes(kv.key, kv.value) {
+			if !set[attr.Key] {
+				set[attr.Key] = true
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+	return attrs
+}
+
+// defaultTagMappings are the default mappings of the standard opentracing
+// tags to their OpenTelemetry attribute names, from otexts.OTelTagKeys. The
+// other tags are recorded with their names, except the "sampling.priority"
+// tag, which is dropped.
+var defaultTagMappings = func() Mappings {
+	m := Mappings{
+		string(ext.SamplingPriority): nil,
+	}
+	for k, v := range otexts.OTelTagKeys() {
+		m[k] = Rename(v)
+	}
+	return m
+}()
+
+// DefaultTagMappings returns a copy of the default mappings of the standard
+// opentracing tags, which may be extended. The "span.kind" and "error" tags are
+// not mapped, they set the OpenTelemetry span kind and status.
+func DefaultTagMappings() Mappings {
+	return defaultTagMappings.clone()
+}
+
+// defaultErrorLogMappings are the default mappings of the log fields of the
+// error events logged by LogError.
+var defaultErrorLogMappings = Mappings{
+	otexts.LogFieldEvent:       nil,
+	otexts.LogFieldLevel:       nil,
+	otexts.LogFieldErrorKind:   Rename(AttrExceptionType),
+	otexts.LogFieldMessage:     Rename(AttrExceptionMessage),
+	otexts.LogFieldStack:       Rename(AttrExceptionStacktrace),
+	otexts.LogFieldErrorObject: Rename(AttrExceptionMessage),
+}
+
+// DefaultErrorLogMappings returns a copy of the default mappings of the log
+// fields of the error events logged by LogError and the other error logging
+// functions of this module, which may be extended.
+func DefaultErrorLogMappings() Mappings {
+	return defaultErrorLogMappings.clone()
+}
+
+// Attribute returns the OpenTelemetry attribute with the specified key and the
+// specified opentracing tag or log field value. Values of types unsupported by
+// OpenTelemetry attributes are formatted with fmt.Sprint.
+func Attribute(key string, value interface{}) attribute.KeyValue {
+	k := attribute.Key(key)
+	switch v := value.(type) {
+	case string:
+		return k.String(v)
+	case bool:
+		return k.Bool(v)
+	case int:
+		return k.Int(v)
+	case int8:
+		return k.Int64(int64(v))
+	case int16:
+		return k.Int64(int64(v))
+	case int32:
+		return k.Int64(int64(v))
+	case int64:
+		return k.Int64(v)
+	case uint8:
+		return k.Int64(int64(v))
+	case uint16:
+		return k.Int64(int64(v))
+	case uint32:
+		return k.Int64(int64(v))
+	case uint:
+		if uint64(v) <= math.MaxInt64 {
+			return k.Int64(int64(v))
+		}
+	case uint64:
+		if v <= math.MaxInt64 {
+			return k.Int64(int64(v))
+		}
+	case float32:
+		return k.Float64(float64(v))
+	case float64:
+		return k.Float64(v)
+	case []string:
+		return k.StringSlice(v)
+	case error:
+		return k.String(v.Error())
+	}
+	return k.String(fmt.Sprint(value))
+}
@@ -0,0 +1,441 @@
+package otelbridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// ExceptionEventName is the name of the OpenTelemetry events recorded for the
+// error events logged by LogError.
+const ExceptionEventName = "exception"
+
+// LogEventName is the name of the OpenTelemetry events recorded for the logs
+// without an "event" log field.
+const LogEventName = "log"
+
+// Options are the options of a Tracer.
+type Options struct {
+	// TagMappings are the mappings of the span tags, DefaultTagMappings if
+	// nil.
+	TagMappings Mappings
+
+	// ErrorLogMappings are the mappings of the log fields of the error events,
+	// DefaultErrorLogMappings if nil. The log fields of the other events are
+	// recorded as attributes with the same key.
+	ErrorLogMappings Mappings
+
+	// Propagator injects and extracts the span contexts for the TextMap and
+	// HTTPHeaders formats, the W3C Trace Context and Baggage propagators if
+	// nil.
+	Propagator propagation.TextMapPropagator
+}
+
+// Ensure Tracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &Tracer{}
+
+// Tracer is an opentracing.Tracer that records its spans as OpenTelemetry
+// spans, so that the code instrumented with opentracing and this module can
+// be migrated to OpenTelemetry incrementally:
+//
+//	opentracing.SetGlobalTracer(otelbridge.NewTracer(otel.Tracer("app"), nil))
+//
+// The span tags are recorded as attributes, mapped to the OpenTelemetry
+// semantic conventions by the tag mappings, e.g. the "http.url" tag set by
+// HTTPTags is recorded as the "url.full" attribute. The tags mapped to the
+// same attribute set it with a fixed precedence, e.g. the "peer.ipv4" tag
+// over the "peer.ipv6" tag for the "network.peer.address" attribute. The
+// "span.kind" tag sets the span kind when the span is started, and the
+// "error" tag sets the span status to Error.
+//
+// The logs are recorded as events named by their "event" log field. The error
+// events logged by LogError are recorded as exception events, with their log
+// fields mapped by the error log mappings, and set the span status to Error
+// with the error message.
+//
+// The first ChildOf reference of a span, or its first reference if it has
+// none, is its parent, and the other references are recorded as links.
+type Tracer struct {
+	tracer           trace.Tracer
+	tagMappings      Mappings
+	errorLogMappings Mappings
+	propagator       propagation.TextMapPropagator
+}
+
+// NewTracer returns a new tracer that records its spans with the specified
+// OpenTelemetry tracer. The options may be nil.
+func NewTracer(tracer trace.Tracer, opts *Options) *Tracer {
+	if opts == nil {
+		opts = &Options{}
+	}
+	t := &Tracer{
+		tracer:           tracer,
+		tagMappings:      opts.TagMappings,
+		errorLogMappings: opts.ErrorLogMappings,
+		propagator:       opts.Propagator,
+	}
+	if t.tagMappings == nil {
+		t.tagMappings = DefaultTagMappings()
+	}
+	if t.errorLogMappings == nil {
+		t.errorLogMappings = DefaultErrorLogMappings()
+	}
+	if t.propagator == nil {
+		t.propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	return t
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *Tracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+
+	ctx := context.Background()
+	baggage := make(map[string]string)
+	var parent *spanContext
+	var refs []spanContext
+	for _, ref := range sso.References {
+		sc, ok := ref.ReferencedContext.(spanContext)
+		if !ok {
+			continue
+		}
+		for k, v := range sc.baggage {
+			baggage[k] = v
+		}
+		if ref.Type == opentracing.ChildOfRef && parent == nil {
+			parent = &sc
+			continue
+		}
+		refs = append(refs, sc)
+	}
+	if parent == nil && len(refs) > 0 {
+		parent, refs = &refs[0], refs[1:]
+	}
+	if parent != nil {
+		ctx = trace.ContextWithSpanContext(ctx, parent.sc)
+	}
+
+	spanOpts := []trace.SpanStartOption{trace.WithSpanKind(spanKind(sso.Tags[string(ext.SpanKind)]))}
+	for _, ref := range refs {
+		spanOpts = append(spanOpts, trace.WithLinks(trace.Link{SpanContext: ref.sc}))
+	}
+	if !sso.StartTime.IsZero() {
+		spanOpts = append(spanOpts, trace.WithTimestamp(sso.StartTime))
+	}
+	tags := make([]keyValue, 0, len(sso.Tags))
+	for k, v := range sso.Tags {
+		if k != string(ext.SpanKind) && k != string(ext.Error) {
+			tags = append(tags, keyValue{key: k, value: v})
+		}
+	}
+	// Sort the tags, so that the attributes do not depend on the map order.
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].key < tags[j].key
+	})
+	if attrs := t.tagMappings.attributesOf(tags); len(attrs) > 0 {
+		spanOpts = append(spanOpts, trace.WithAttributes(attrs...))
+	}
+
+	_, otelSpan := t.tracer.Start(ctx, operationName, spanOpts...)
+	s := &span{span: otelSpan, tracer: t, baggage: baggage}
+	if isError(sso.Tags[string(ext.Error)]) {
+		otelSpan.SetStatus(codes.Error, "")
+	}
+	return s
+}
+
+// spanKind returns the OpenTelemetry span kind of the specified "span.kind"
+// tag value.
+func spanKind(kind interface{}) trace.SpanKind {
+	if kind == nil {
+		return trace.SpanKindInternal
+	}
+	switch ext.SpanKindEnum(fmt.Sprint(kind)) {
+	case ext.SpanKindRPCClientEnum:
+		return trace.SpanKindClient
+	case ext.SpanKindRPCServerEnum:
+		return trace.SpanKindServer
+	case ext.SpanKindProducerEnum:
+		return trace.SpanKindProducer
+	case ext.SpanKindConsumerEnum:
+		return trace.SpanKindConsumer
+	}
+	return trace.SpanKindInternal
+}
+
+// isError reports whether the specified "error" tag value is true.
+func isError(value interface{}) bool {
+	b, ok := value.(bool)
+	return ok && b
+}
+
+// Inject implements the opentracing.Tracer interface. The TextMap and
+// HTTPHeaders formats are supported.
+func (t *Tracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	c, ok := sc.(spanContext)
+	if !ok || !c.sc.IsValid() {
+		return opentracing.ErrInvalidSpanContext
+	}
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return opentracing.ErrUnsupportedFormat
+	}
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok {
+		return opentracing.ErrInvalidCarrier
+	}
+	ctx := trace.ContextWithSpanContext(context.Background(), c.sc)
+	if len(c.baggage) > 0 {
+		var members []baggage.Member
+		for k, v := range c.baggage {
+			if m, err := baggage.NewMemberRaw(k, v); err == nil {
+				members = append(members, m)
+			}
+		}
+		if b, err := baggage.New(members...); err == nil {
+			ctx = baggage.ContextWithBaggage(ctx, b)
+		}
+	}
+	t.propagator.Inject(ctx, injectCarrier{w: w})
+	return nil
+}
+
+// Extract implements the opentracing.Tracer interface. The TextMap and
+// HTTPHeaders formats are supported.
+func (t *Tracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	if format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return nil, opentracing.ErrUnsupportedFormat
+	}
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok {
+		return nil, opentracing.ErrInvalidCarrier
+	}
+	headers := propagation.MapCarrier{}
+	err := r.ForeachKey(func(k, v string) error {
+		headers[strings.ToLower(k)] = v
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx := t.propagator.Extract(context.Background(), headers)
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return nil, opentracing.ErrSpanContextNotFound
+	}
+	c := spanContext{sc: sc, baggage: make(map[string]string)}
+	for _, m := range baggage.FromContext(ctx).Members() {
+		c.baggage[m.Key()] = m.Value()
+	}
+	return c, nil
+}
+
+// injectCarrier is an OpenTelemetry text map carrier that sets the values of
+// an opentracing carrier.
+type injectCarrier struct {
+	w opentracing.TextMapWriter
+}
+
+// Get implements the propagation.TextMapCarrier interface.
+func (c injectCarrier) Get(key string) string {
+	return ""
+}
+
+// Set implements the propagation.TextMapCarrier interface.
+func (c injectCarrier) Set(key, value string) {
+	c.w.Set(key, value)
+}
+
+// Keys implements the propagation.TextMapCarrier interface.
+func (c injectCarrier) Keys() []string {
+	return nil
+}
+
+// spanContext is the span context of a Tracer span.
+type spanContext struct {
+	sc      trace.SpanContext
+	baggage map[string]string
+}
+
+// ForeachBaggageItem implements the opentracing.SpanContext interface.
+func (c spanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	for k, v := range c.baggage {
+		if !handler(k, v) {
+			return
+		}
+	}
+}
+
+// SpanContext returns the OpenTelemetry span context of the specified span
+// context of a Tracer, and whether it is one.
+func SpanContext(sc opentracing.SpanContext) (trace.SpanContext, bool) {
+	c, ok := sc.(spanContext)
+	return c.sc, ok
+}
+
+// span is a span of a Tracer.
+type span struct {
+	span   trace.Span
+	tracer *Tracer
+
+	mu      sync.Mutex
+	baggage map[string]string
+}
+
+// Finish implements the opentracing.Span interface.
+func (s *span) Finish() {
+	s.span.End()
+}
+
+// FinishWithOptions implements the opentracing.Span interface.
+func (s *span) FinishWithOptions(opts opentracing.FinishOptions) {
+	for _, r := range opts.LogRecords {
+		s.log(r.Timestamp, r.Fields)
+	}
+	for _, data := range opts.BulkLogData {
+		r := data.ToLogRecord()
+		s.log(r.Timestamp, r.Fields)
+	}
+	if opts.FinishTime.IsZero() {
+		s.span.End()
+		return
+	}
+	s.span.End(trace.WithTimestamp(opts.FinishTime))
+}
+
+// Context implements the opentracing.Span interface.
+func (s *span) Context() opentracing.SpanContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	baggage := make(map[string]string, len(s.baggage))
+	for k, v := range s.baggage {
+		baggage[k] = v
+	}
+	return spanContext{sc: s.span.SpanContext(), baggage: baggage}
+}
+
+// SetOperationName implements the opentracing.Span interface.
+func (s *span) SetOperationName(operationName string) opentracing.Span {
+	s.span.SetName(operationName)
+	return s
+}
+
+// SetTag implements the opentracing.Span interface. The "span.kind" tag is
+// ignored, the OpenTelemetry span kind is only set when a span is started.
+func (s *span) SetTag(key string, value interface{}) opentracing.Span {
+	switch key {
+	case string(ext.SpanKind):
+	case string(ext.Error):
+		if isError(value) {
+			s.span.SetStatus(codes.Error, "")
+		}
+	default:
+		s.span.SetAttributes(s.tracer.tagMappings.attributes(key, value)...)
+	}
+	return s
+}
+
+// LogFields implements the opentracing.Span interface.
+func (s *span) LogFields(fields ...log.Field) {
+	s.log(time.Time{}, fields)
+}
+
+// LogKV implements the opentracing.Span interface.
+func (s *span) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+// log records the specified log fields as an event at the specified time, or
+// now if zero.
+func (s *span) log(timestamp time.Time, fields []log.Field) {
+	event := LogEventName
+	for _, f := range fields {
+		if f.Key() == otexts.LogFieldEvent {
+			event = fmt.Sprint(f.Value())
+		}
+	}
+	var opts []trace.EventOption
+	if !timestamp.IsZero() {
+		opts = append(opts, trace.WithTimestamp(timestamp))
+	}
+
+	if event != otexts.LogEventError {
+		attrs := make([]attribute.KeyValue, 0, len(fields))
+		for _, f := range fields {
+			if f.Key() != otexts.LogFieldEvent {
+				attrs = append(attrs, Attribute(f.Key(), f.Value()))
+			}
+		}
+		s.span.AddEvent(event, append(opts, trace.WithAttributes(attrs...))...)
+		return
+	}
+
+	kvs := make([]keyValue, len(fields))
+	var message string
+	for i, f := range fields {
+		kvs[i] = keyValue{key: f.Key(), value: f.Value()}
+		if f.Key() == otexts.LogFieldMessage {
+			message = fmt.Sprint(f.Value())
+		}
+	}
+	attrs := s.tracer.errorLogMappings.attributesOf(kvs)
+	s.span.AddEvent(ExceptionEventName, append(opts, trace.WithAttributes(attrs...))...)
+	s.span.SetStatus(codes.Error, message)
+}
+
+// SetBaggageItem implements the opentracing.Span interface.
+func (s *span) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baggage[restrictedKey] = value
+	return s
+}
+
+// BaggageItem implements the opentracing.Span interface.
+func (s *span) BaggageItem(restrictedKey string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.baggage[restrictedKey]
+}
+
+// Tracer implements the opentracing.Span interface.
+func (s *span) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent implements the deprecated opentracing.Span method.
+func (s *span) LogEvent(event string) {
+	s.LogFields(log.String(otexts.LogFieldEvent, event))
+}
+
+// LogEventWithPayload implements the deprecated opentracing.Span method.
+func (s *span) LogEventWithPayload(event string, payload interface{}) {
+	s.LogFields(log.String(otexts.LogFieldEvent, event), log.Object("payload", payload))
+}
+
+// Log implements the deprecated opentracing.Span method.
+func (s *span) Log(data opentracing.LogData) {
+	r := data.ToLogRecord()
+	s.log(r.Timestamp, r.Fields)
+}
@@ -0,0 +1,317 @@
+package otelbridge_test
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	otexts "github.com/code-willing/opentracing-exts"
+	"github.com/code-willing/opentracing-exts/otelbridge"
+)
+
+func newTestTracer(opts *otelbridge.Options) (*otelbridge.Tracer, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return otelbridge.NewTracer(provider.Tracer("test"), opts), recorder
+}
+
+func ensureAttributes(t *testing.T, want map[string]attribute.Value, attrs []attribute.KeyValue) {
+	t.Helper()
+	got := make(map[string]attribute.Value, len(attrs))
+	for _, kv := range attrs {
+		got[string(kv.Key)] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("attribute %q: got %v, want %v", k, got[k].Emit(), v.Emit())
+		}
+	}
+}
+
+func TestTracerTags(t *testing.T) {
+	tt := []struct {
+		name string
+		opt  opentracing.StartSpanOption
+		kind trace.SpanKind
+		want map[string]attribute.Value
+	}{
+		{
+			name: "RPC",
+			opt: otexts.RPCTags{
+				Kind:         ext.SpanKindRPCClientEnum,
+				PeerHostname: "api.example.com",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerPort:     8080,
+				PeerService:  "api",
+			},
+			kind: trace.SpanKindClient,
+			want: map[string]attribute.Value{
+				"server.address":       attribute.StringValue("api.example.com"),
+				"server.port":          attribute.Int64Value(8080),
+				"network.peer.address": attribute.StringValue("127.0.0.1"),
+				"peer.service":         attribute.StringValue("api"),
+			},
+		},
+		{
+			name: "DB",
+			opt: otexts.DBTags{
+				Type:         "PostgreSQL",
+				Instance:     "orders",
+				User:         "app",
+				Statement:    "SELECT 1",
+				PeerHostname: "db.example.com",
+			},
+			kind: trace.SpanKindClient,
+			want: map[string]attribute.Value{
+				"db.system":      attribute.StringValue("postgresql"),
+				"db.namespace":   attribute.StringValue("orders"),
+				"db.user":        attribute.StringValue("app"),
+				"db.query.text":  attribute.StringValue("SELECT 1"),
+				"server.address": attribute.StringValue("db.example.com"),
+			},
+		},
+		{
+			name: "HTTP",
+			opt: otexts.HTTPTags{
+				Method:     http.MethodGet,
+				URL:        "https://example.com/orders",
+				StatusCode: http.StatusOK,
+			},
+			kind: trace.SpanKindInternal,
+			want: map[string]attribute.Value{
+				"http.request.method":       attribute.StringValue("GET"),
+				"url.full":                  attribute.StringValue("https://example.com/orders"),
+				"http.response.status_code": attribute.Int64Value(200),
+			},
+		},
+		{
+			name: "standard",
+			opt:  opentracing.Tags{string(ext.MessageBusDestination): "orders", string(ext.PeerAddress): "db:5432"},
+			kind: trace.SpanKindInternal,
+			want: map[string]attribute.Value{
+				"messaging.destination.name": attribute.StringValue("orders"),
+				"peer.address":               attribute.StringValue("db:5432"),
+			},
+		},
+		{
+			name: "unmapped",
+			opt:  opentracing.Tags{"custom": "value", string(ext.SpanKind): ext.SpanKindProducerEnum},
+			kind: trace.SpanKindProducer,
+			want: map[string]attribute.Value{
+				"custom": attribute.StringValue("value"),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, recorder := newTestTracer(nil)
+			tracer.StartSpan("op", tc.opt).Finish()
+
+			spans := recorder.Ended()
+			if got, want := len(spans), 1; got != want {
+				t.Fatalf("spans: got %d, want %d", got, want)
+			}
+			if got, want := spans[0].SpanKind(), tc.kind; got != want {
+				t.Errorf("span kind: got %v, want %v", got, want)
+			}
+			ensureAttributes(t, tc.want, spans[0].Attributes())
+		})
+	}
+}
+
+func TestDefaultTagMappings(t *testing.T) {
+	mappings := otelbridge.DefaultTagMappings()
+	for k, otel := range otexts.OTelTagKeys() {
+		mapping, ok := mappings[k]
+		if !ok || mapping == nil {
+			t.Errorf("tag %q: no mapping", k)
+			continue
+		}
+		if got, want := mapping("value")[0].Key, attribute.Key(otel); got != want {
+			t.Errorf("tag %q: got attribute %q, want %q", k, got, want)
+		}
+	}
+	// The "sampling.priority" tag is dropped.
+	if got, want := len(mappings), len(otexts.OTelTagKeys())+1; got != want {
+		t.Errorf("mappings: got %d, want %d", got, want)
+	}
+}
+
+func TestTracerAttributePrecedence(t *testing.T) {
+	tracer, recorder := newTestTracer(nil)
+	for i := 0; i < 50; i++ {
+		span := tracer.StartSpan("op", otexts.RPCTags{
+			PeerAddr: "db:5432",
+			PeerIPv4: net.IPv4(10, 0, 0, 1),
+			PeerIPv6: net.IPv6loopback,
+		})
+		span.LogFields(
+			log.String(otexts.LogFieldEvent, otexts.LogEventError),
+			log.Error(errors.New("error object")),
+			log.String(otexts.LogFieldMessage, "message"),
+		)
+		span.Finish()
+	}
+
+	for _, s := range recorder.Ended() {
+		ensureAttributes(t, map[string]attribute.Value{
+			"network.peer.address": attribute.StringValue("10.0.0.1"),
+		}, s.Attributes())
+		ensureAttributes(t, map[string]attribute.Value{
+			"exception.message": attribute.StringValue("message"),
+		}, s.Events()[0].Attributes)
+	}
+}
+
+func TestTracerLogError(t *testing.T) {
+	tracer, recorder := newTestTracer(nil)
+	span := tracer.StartSpan("op")
+	span.SetTag("http.url", "https://example.com")
+	otexts.LogError(span, errors.New("failed"))
+	otexts.LogEvent(span, otexts.LogEventCacheHit, "", map[string]interface{}{otexts.LogFieldCacheKey: "key"})
+	span.Finish()
+
+	s := recorder.Ended()[0]
+	if got, want := s.Status().Code, codes.Error; got != want {
+		t.Errorf("status code: got %v, want %v", got, want)
+	}
+	if got, want := s.Status().Description, "failed"; got != want {
+		t.Errorf("status description: got %q, want %q", got, want)
+	}
+	ensureAttributes(t, map[string]attribute.Value{
+		"url.full": attribute.StringValue("https://example.com"),
+	}, s.Attributes())
+
+	events := s.Events()
+	if got, want := len(events), 2; got != want {
+		t.Fatalf("events: got %d, want %d", got, want)
+	}
+	if got, want := events[0].Name, otelbridge.ExceptionEventName; got != want {
+		t.Errorf("exception event name: got %q, want %q", got, want)
+	}
+	ensureAttributes(t, map[string]attribute.Value{
+		"exception.type":    attribute.StringValue("*errors.fundamental"),
+		"exception.message": attribute.StringValue("failed"),
+	}, events[0].Attributes)
+	for _, kv := range events[0].Attributes {
+		if kv.Key == otexts.LogFieldEvent || kv.Key == otexts.LogFieldLevel {
+			t.Errorf("exception event attribute %q is set", kv.Key)
+		}
+	}
+	if got, want := events[1].Name, otexts.LogEventCacheHit; got != want {
+		t.Errorf("event name: got %q, want %q", got, want)
+	}
+	ensureAttributes(t, map[string]attribute.Value{
+		otexts.LogFieldCacheKey: attribute.StringValue("key"),
+	}, events[1].Attributes)
+}
+
+func TestTracerMappings(t *testing.T) {
+	tags := otelbridge.DefaultTagMappings()
+	tags["component"] = otelbridge.Rename("code.namespace")
+	tags[string(ext.HTTPUrl)] = nil
+	errorLogs := otelbridge.DefaultErrorLogMappings()
+	errorLogs["request.id"] = otelbridge.Rename("exception.request_id")
+	tracer, recorder := newTestTracer(&otelbridge.Options{TagMappings: tags, ErrorLogMappings: errorLogs})
+
+	span := tracer.StartSpan("op", opentracing.Tags{"component": "orders", "http.url": "https://example.com"})
+	otexts.LogErrorWithFields(span, errors.New("failed"), map[string]interface{}{"request.id": "1"})
+	span.Finish()
+
+	s := recorder.Ended()[0]
+	ensureAttributes(t, map[string]attribute.Value{
+		"code.namespace": attribute.StringValue("orders"),
+	}, s.Attributes())
+	for _, kv := range s.Attributes() {
+		if kv.Key == "url.full" || kv.Key == "http.url" {
+			t.Errorf("dropped attribute %q is set", kv.Key)
+		}
+	}
+	ensureAttributes(t, map[string]attribute.Value{
+		"exception.request_id": attribute.StringValue("1"),
+	}, s.Events()[0].Attributes)
+
+	if _, ok := otelbridge.DefaultTagMappings()["component"]; ok {
+		t.Errorf("default tag mappings modified")
+	}
+}
+
+func TestTracerReferences(t *testing.T) {
+	tracer, recorder := newTestTracer(nil)
+	parent := tracer.StartSpan("parent")
+	parent.SetBaggageItem("user", "test")
+	other := tracer.StartSpan("other")
+	child := tracer.StartSpan("child", opentracing.FollowsFrom(other.Context()), opentracing.ChildOf(parent.Context()))
+	if got, want := child.BaggageItem("user"), "test"; got != want {
+		t.Errorf("baggage item: got %q, want %q", got, want)
+	}
+	child.Finish()
+	other.Finish()
+	parent.Finish()
+
+	spans := recorder.Ended()
+	childSpan, otherSpan, parentSpan := spans[0], spans[1], spans[2]
+	if got, want := childSpan.Parent().SpanID(), parentSpan.SpanContext().SpanID(); got != want {
+		t.Errorf("parent span ID: got %v, want %v", got, want)
+	}
+	links := childSpan.Links()
+	if got, want := len(links), 1; got != want {
+		t.Fatalf("links: got %d, want %d", got, want)
+	}
+	if got, want := links[0].SpanContext.SpanID(), otherSpan.SpanContext().SpanID(); got != want {
+		t.Errorf("link span ID: got %v, want %v", got, want)
+	}
+}
+
+func TestTracerInjectExtract(t *testing.T) {
+	tracer, _ := newTestTracer(nil)
+	span := tracer.StartSpan("op")
+	span.SetBaggageItem("user", "test")
+	defer span.Finish()
+
+	header := http.Header{}
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	if header.Get("traceparent") == "" {
+		t.Errorf("traceparent header not set")
+	}
+
+	sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header))
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	got, _ := otelbridge.SpanContext(sc)
+	want, _ := otelbridge.SpanContext(span.Context())
+	if got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+		t.Errorf("span context: got %v, want %v", got, want)
+	}
+	var user string
+	sc.ForeachBaggageItem(func(k, v string) bool {
+		if k == "user" {
+			user = v
+		}
+		return true
+	})
+	if got, want := user, "test"; got != want {
+		t.Errorf("baggage item: got %q, want %q", got, want)
+	}
+
+	if _, err := tracer.Extract(opentracing.TextMap, opentracing.TextMapCarrier{}); err != opentracing.ErrSpanContextNotFound {
+		t.Errorf("extract empty carrier error: got %v, want %v", err, opentracing.ErrSpanContextNotFound)
+	}
+	if err := tracer.Inject(span.Context(), opentracing.Binary, nil); err != opentracing.ErrUnsupportedFormat {
+		t.Errorf("inject binary error: got %v, want %v", err, opentracing.ErrUnsupportedFormat)
+	}
+}
@@ -0,0 +1,242 @@
+package trace
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// TruncatedSuffix is the suffix of string values truncated by a Policy.
+const TruncatedSuffix = "..."
+
+// DefaultMaxValueLength is the maximum length of string values of the
+// default policy.
+const DefaultMaxValueLength = 1024
+
+// Policy is a set of rules applied to the tag values and log field values of
+// spans, see PolicyTracer. The rules are applied in order: redaction, URL
+// sanitization, then truncation.
+type Policy struct {
+	// RedactKeys are the names of the tags and log fields whose values are
+	// replaced by RedactedValue. A name matches a key that equals it, or
+	// ends with it preceded by a ".", ignoring case, e.g. "password" matches
+	// the "db.password" key.
+	RedactKeys []string
+
+	// URLKeys are the names of the tags and log fields with URL string
+	// values, which are sanitized by removing their user information and
+	// replacing the values of the RedactQueryParams query parameters by
+	// RedactedValue.
+	URLKeys []string
+
+	// RedactQueryParams are the names of the URL query parameters whose
+	// values are redacted, ignoring case.
+	RedactQueryParams []string
+
+	// MaxValueLength is the maximum length in bytes of string values, longer
+	// values are truncated to the maximum length, including the
+	// TruncatedSuffix. Zero means no maximum length.
+	MaxValueLength int
+}
+
+// DefaultPolicy returns the default policy, which redacts common credential
+// tags and log fields and URL query parameters, sanitizes the "http.url" tag,
+// and truncates values to DefaultMaxValueLength.
+func DefaultPolicy() Policy {
+	return Policy{
+		RedactKeys: []string{"authorization", "cookie", "password", "secret", "token"},
+		URLKeys:    []string{string(ext.HTTPUrl)},
+		RedactQueryParams: []string{
+			"access_token", "api_key", "apikey", "password", "secret", "signature", "token",
+		},
+		MaxValueLength: DefaultMaxValueLength,
+	}
+}
+
+// compiledPolicy is a Policy with its names lowercased for matching.
+type compiledPolicy struct {
+	redactKeys  []string
+	urlKeys     map[string]bool
+	queryParams map[string]bool
+	maxLen      int
+}
+
+// compile returns the compiled policy.
+func (p Policy) compile() *compiledPolicy {
+	c := &compiledPolicy{
+		urlKeys:     make(map[string]bool, len(p.URLKeys)),
+		queryParams: make(map[string]bool, len(p.RedactQueryParams)),
+		maxLen:      p.MaxValueLength,
+	}
+	for _, k := range p.RedactKeys {
+		c.redactKeys = append(c.redactKeys, strings.ToLower(k))
+	}
+	for _, k := range p.URLKeys {
+		c.urlKeys[k] = true
+	}
+	for _, k := range p.RedactQueryParams {
+		c.queryParams[strings.ToLower(k)] = true
+	}
+	return c
+}
+
+// redacts reports whether the value of the specified key is redacted.
+func (p *compiledPolicy) redacts(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range p.redactKeys {
+		if key == k || strings.HasSuffix(key, "."+k) {
+			return true
+		}
+	}
+	return false
+}
+
+// value returns the value of the specified tag or log field with the policy
+// applied.
+func (p *compiledPolicy) value(key string, v interface{}) interface{} {
+	if p.redacts(key) {
+		return RedactedValue
+	}
+	s, ok := v.(string)
+	if !ok {
+		return v
+	}
+	return p.stringValue(key, s)
+}
+
+// stringValue returns the string value of the specified tag or log field
+// with the URL sanitization and truncation rules applied.
+func (p *compiledPolicy) stringValue(key, s string) string {
+	if p.urlKeys[key] {
+		s = p.sanitizeURL(s)
+	}
+	if p.maxLen > 0 && len(s) > p.maxLen {
+		n := p.maxLen - len(TruncatedSuffix)
+		if n < 0 {
+			return truncateRunes(s, p.maxLen)
+		}
+		s = truncateRunes(s, n) + TruncatedSuffix
+	}
+	return s
+}
+
+// truncateRunes returns the longest prefix of the specified string of at most
+// n bytes that does not split a UTF-8 encoded rune.
+func truncateRunes(s string, n int) string {
+	if n >= len(s) {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// sanitizeURL removes the user information of the specified URL and redacts
+// the values of its sensitive query parameters. Values that are not URLs are
+// returned unchanged.
+func (p *compiledPolicy) sanitizeURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	u.User = nil
+	if u.RawQuery != "" && len(p.queryParams) > 0 {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			name := param
+			if j := strings.IndexByte(param, '='); j >= 0 {
+				name = param[:j]
+			}
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+			if p.queryParams[strings.ToLower(name)] {
+				params[i] = strings.SplitN(param, "=", 2)[0] + "=" + RedactedValue
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	return u.String()
+}
+
+// field returns the specified log field with the policy applied.
+func (p *compiledPolicy) field(f log.Field) log.Field {
+	if p.redacts(f.Key()) {
+		return log.String(f.Key(), RedactedValue)
+	}
+	if s, ok := f.Value().(string); ok {
+		if v := p.stringValue(f.Key(), s); v != s {
+			return log.String(f.Key(), v)
+		}
+	}
+	return f
+}
+
+// fields returns the specified log fields with the policy applied.
+func (p *compiledPolicy) fields(fields []log.Field) []log.Field {
+	applied := make([]log.Field, len(fields))
+	for i, f := range fields {
+		applied[i] = p.field(f)
+	}
+	return applied
+}
+
+// keyValues returns the specified alternating log keys and values with the
+// policy applied.
+func (p *compiledPolicy) keyValues(kv []interface{}) []interface{} {
+	applied := make([]interface{}, len(kv))
+	copy(applied, kv)
+	for i := 0; i+1 < len(applied); i += 2 {
+		if key, ok := applied[i].(string); ok {
+			applied[i+1] = p.value(key, applied[i+1])
+		}
+	}
+	return applied
+}
+
+// logData returns the specified deprecated log data with the policy applied.
+// The payload is applied as the "payload" log field, and the values of maps
+// with string keys as the log fields of their keys.
+func (p *compiledPolicy) logData(ld opentracing.LogData) opentracing.LogData {
+	ld.Event = p.stringValue(LogFieldEvent, ld.Event)
+	if p.redacts(logFieldPayload) {
+		ld.Payload = RedactedValue
+		return ld
+	}
+	switch payload := ld.Payload.(type) {
+	case string:
+		ld.Payload = p.stringValue(logFieldPayload, payload)
+	case map[string]interface{}:
+		ld.Payload = p.tags(payload)
+	case opentracing.Tags:
+		ld.Payload = p.tags(payload)
+	case map[string]string:
+		applied := make(map[string]string, len(payload))
+		for k, v := range payload {
+			if p.redacts(k) {
+				v = RedactedValue
+			}
+			applied[k] = p.stringValue(k, v)
+		}
+		ld.Payload = applied
+	}
+	return ld
+}
+
+// logFieldPayload is the log field name of the payload of the deprecated log
+// data, see opentracing.LogData.ToLogRecord.
+const logFieldPayload = "payload"
+
+// tags returns the specified tags with the policy applied.
+func (p *compiledPolicy) tags(tags map[string]interface{}) opentracing.Tags {
+	applied := make(opentracing.Tags, len(tags))
+	for k, v := range tags {
+		applied[k] = p.value(k, v)
+	}
+	return applied
+}
@@ -0,0 +1,152 @@
+package trace
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Ensure PolicyTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &PolicyTracer{}
+
+// PolicyTracer is an opentracing.Tracer that wraps another tracer, applying a
+// Policy to the tags and log fields of every span it starts, including the
+// tags set by span options, SetTag, LogFields, LogKV, the deprecated logging
+// methods, and the log records of FinishWithOptions. Because it applies the
+// policy to every span, including the spans started by third-party
+// libraries, it is usually set as the global tracer:
+//
+//	opentracing.SetGlobalTracer(trace.NewPolicyTracer(tracer, trace.DefaultPolicy()))
+//
+// The spans of a PolicyTracer wrap the spans of the wrapped tracer, their span
+// contexts are the span contexts of the wrapped tracer.
+type PolicyTracer struct {
+	tracer opentracing.Tracer
+	policy *compiledPolicy
+}
+
+// NewPolicyTracer returns a new tracer that wraps the specified tracer,
+// applying the specified policy to every span.
+func NewPolicyTracer(tracer opentracing.Tracer, policy Policy) *PolicyTracer {
+	return &PolicyTracer{tracer: tracer, policy: policy.compile()}
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *PolicyTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	wrapped := make([]opentracing.StartSpanOption, 0, len(sso.References)+2)
+	for _, ref := range sso.References {
+		wrapped = append(wrapped, ref)
+	}
+	if !sso.StartTime.IsZero() {
+		wrapped = append(wrapped, opentracing.StartTime(sso.StartTime))
+	}
+	if len(sso.Tags) > 0 {
+		wrapped = append(wrapped, t.policy.tags(sso.Tags))
+	}
+	return &policySpan{span: t.tracer.StartSpan(operationName, wrapped...), tracer: t}
+}
+
+// Inject implements the opentracing.Tracer interface.
+func (t *PolicyTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	return t.tracer.Inject(sc, format, carrier)
+}
+
+// Extract implements the opentracing.Tracer interface.
+func (t *PolicyTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	return t.tracer.Extract(format, carrier)
+}
+
+// policySpan is a span of a PolicyTracer.
+type policySpan struct {
+	span   opentracing.Span
+	tracer *PolicyTracer
+}
+
+// Finish implements the opentracing.Span interface.
+func (s *policySpan) Finish() {
+	s.span.Finish()
+}
+
+// FinishWithOptions implements the opentracing.Span interface.
+func (s *policySpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	if len(opts.LogRecords) > 0 {
+		records := make([]opentracing.LogRecord, len(opts.LogRecords))
+		for i, r := range opts.LogRecords {
+			records[i] = opentracing.LogRecord{
+				Timestamp: r.Timestamp,
+				Fields:    s.tracer.policy.fields(r.Fields),
+			}
+		}
+		opts.LogRecords = records
+	}
+	if len(opts.BulkLogData) > 0 {
+		data := make([]opentracing.LogData, len(opts.BulkLogData))
+		for i, ld := range opts.BulkLogData {
+			data[i] = s.tracer.policy.logData(ld)
+		}
+		opts.BulkLogData = data
+	}
+	s.span.FinishWithOptions(opts)
+}
+
+// Context implements the opentracing.Span interface.
+func (s *policySpan) Context() opentracing.SpanContext {
+	return s.span.Context()
+}
+
+// SetOperationName implements the opentracing.Span interface.
+func (s *policySpan) SetOperationName(operationName string) opentracing.Span {
+	s.span.SetOperationName(operationName)
+	return s
+}
+
+// SetTag implements the opentracing.Span interface.
+func (s *policySpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.span.SetTag(key, s.tracer.policy.value(key, value))
+	return s
+}
+
+// LogFields implements the opentracing.Span interface.
+func (s *policySpan) LogFields(fields ...log.Field) {
+	s.span.LogFields(s.tracer.policy.fields(fields)...)
+}
+
+// LogKV implements the opentracing.Span interface.
+func (s *policySpan) LogKV(alternatingKeyValues ...interface{}) {
+	s.span.LogKV(s.tracer.policy.keyValues(alternatingKeyValues)...)
+}
+
+// SetBaggageItem implements the opentracing.Span interface.
+func (s *policySpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.span.SetBaggageItem(restrictedKey, value)
+	return s
+}
+
+// BaggageItem implements the opentracing.Span interface.
+func (s *policySpan) BaggageItem(restrictedKey string) string {
+	return s.span.BaggageItem(restrictedKey)
+}
+
+// Tracer implements the opentracing.Span interface.
+func (s *policySpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent implements the deprecated opentracing.Span method.
+func (s *policySpan) LogEvent(event string) {
+	s.span.LogEvent(s.tracer.policy.stringValue(LogFieldEvent, event))
+}
+
+// LogEventWithPayload implements the deprecated opentracing.Span method.
+func (s *policySpan) LogEventWithPayload(event string, payload interface{}) {
+	ld := s.tracer.policy.logData(opentracing.LogData{Event: event, Payload: payload})
+	s.span.LogEventWithPayload(ld.Event, ld.Payload)
+}
+
+// Log implements the deprecated opentracing.Span method.
+func (s *policySpan) Log(data opentracing.LogData) {
+	s.span.Log(s.tracer.policy.logData(data))
+}
@@ -0,0 +1,554 @@
+package trace
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+// TraceID is a 128-bit trace ID.
+type TraceID [16]byte
+
+// IsValid reports whether the trace ID is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// String returns the trace ID as 32 lowercase hex characters.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ParseTraceID parses a trace ID of 32, or 16 for 64-bit trace IDs, lowercase
+// hex characters.
+func ParseTraceID(s string) (TraceID, error) {
+	var id TraceID
+	if len(s) != 32 && len(s) != 16 || !isLowerHex(s) {
+		return id, errors.Errorf("invalid trace ID %q", s)
+	}
+	hex.Decode(id[len(id)-len(s)/2:], []byte(s))
+	return id, nil
+}
+
+// SpanID is a 64-bit span ID.
+type SpanID [8]byte
+
+// IsValid reports whether the span ID is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+// String returns the span ID as 16 lowercase hex characters.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// ParseSpanID parses a span ID of 16 lowercase hex characters.
+func ParseSpanID(s string) (SpanID, error) {
+	var id SpanID
+	if len(s) != 16 || !isLowerHex(s) {
+		return id, errors.Errorf("invalid span ID %q", s)
+	}
+	hex.Decode(id[:], []byte(s))
+	return id, nil
+}
+
+// isLowerHex reports whether the specified string only has lowercase hex
+// characters.
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// Sampling is the sampling decision propagated with a trace context.
+type Sampling int
+
+// Sampling decisions.
+const (
+	SamplingUnset  Sampling = iota // The sampling decision is deferred.
+	SamplingAccept                 // The trace is sampled.
+	SamplingDeny                   // The trace is not sampled.
+	SamplingDebug                  // The trace is sampled and debugged.
+)
+
+// TraceContext is the tracer independent trace context propagated by the
+// header propagators. A PropagatorTracer converts it from and to the span
+// contexts of a tracer.
+type TraceContext struct {
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID // The parent span ID, only propagated by B3.
+	Sampling     Sampling
+	TraceState   TraceState // The W3C vendor-specific trace state.
+}
+
+// IsValid reports whether the trace context has valid trace and span IDs.
+func (c TraceContext) IsValid() bool {
+	return c.TraceID.IsValid() && c.SpanID.IsValid()
+}
+
+// Propagator injects and extracts trace contexts in the headers of a carrier,
+// e.g. an opentracing.HTTPHeadersCarrier.
+type Propagator interface {
+	// Inject injects the trace context in the carrier.
+	Inject(c TraceContext, carrier opentracing.TextMapWriter) error
+
+	// Extract extracts a trace context from the carrier. It returns
+	// opentracing.ErrSpanContextNotFound if the carrier has no trace context,
+	// and opentracing.ErrSpanContextCorrupted if it is invalid.
+	Extract(carrier opentracing.TextMapReader) (TraceContext, error)
+}
+
+// readHeaders returns the specified header values of the carrier, by header
+// name. Header names are case-insensitive.
+func readHeaders(carrier opentracing.TextMapReader, names ...string) (map[string]string, error) {
+	headers := make(map[string]string, len(names))
+	err := carrier.ForeachKey(func(k, v string) error {
+		for _, name := range names {
+			if strings.EqualFold(k, name) {
+				headers[name] = v
+			}
+		}
+		return nil
+	})
+	return headers, err
+}
+
+// W3C Trace Context header names.
+const (
+	W3CTraceParentHeader = "traceparent"
+	W3CTraceStateHeader  = "tracestate"
+)
+
+// Ensure W3CPropagator implements the Propagator interface.
+var _ Propagator = W3CPropagator{}
+
+// W3CPropagator is the W3C Trace Context propagator, using the "traceparent"
+// and "tracestate" headers.
+//
+// See https://www.w3.org/TR/trace-context/.
+type W3CPropagator struct{}
+
+// Inject implements the Propagator interface.
+func (W3CPropagator) Inject(c TraceContext, carrier opentracing.TextMapWriter) error {
+	if !c.IsValid() {
+		return opentracing.ErrInvalidSpanContext
+	}
+	flags := "00"
+	if c.Sampling == SamplingAccept || c.Sampling == SamplingDebug {
+		flags = "01"
+	}
+	carrier.Set(W3CTraceParentHeader, "00-"+c.TraceID.String()+"-"+c.SpanID.String()+"-"+flags)
+	if len(c.TraceState) > 0 {
+		carrier.Set(W3CTraceStateHeader, c.TraceState.String())
+	}
+	return nil
+}
+
+// Extract implements the Propagator interface. An invalid "tracestate"
+// header is ignored.
+func (W3CPropagator) Extract(carrier opentracing.TextMapReader) (TraceContext, error) {
+	headers, err := readHeaders(carrier, W3CTraceParentHeader, W3CTraceStateHeader)
+	if err != nil {
+		return TraceContext{}, err
+	}
+	parent, ok := headers[W3CTraceParentHeader]
+	if !ok {
+		return TraceContext{}, opentracing.ErrSpanContextNotFound
+	}
+	c, err := parseTraceParent(strings.TrimSpace(parent))
+	if err != nil {
+		return TraceContext{}, opentracing.ErrSpanContextCorrupted
+	}
+	if state, ok := headers[W3CTraceStateHeader]; ok {
+		c.TraceState, _ = ParseTraceState(state)
+	}
+	return c, nil
+}
+
+// parseTraceParent parses a "traceparent" header value.
+func parseTraceParent(s string) (TraceContext, error) {
+	var c TraceContext
+	// version "-" trace-id "-" parent-id "-" trace-flags
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return c, errors.Errorf("invalid traceparent %q", s)
+	}
+	version := s[:2]
+	if !isLowerHex(version) || version == "ff" {
+		return c, errors.Errorf("invalid traceparent version %q", version)
+	}
+	// Future versions may append fields, version 00 may not.
+	if len(s) > 55 && (version == "00" || s[55] != '-') {
+		return c, errors.Errorf("invalid traceparent %q", s)
+	}
+	var err error
+	if c.TraceID, err = ParseTraceID(s[3:35]); err != nil || !c.TraceID.IsValid() {
+		return c, errors.Errorf("invalid traceparent trace ID %q", s[3:35])
+	}
+	if c.SpanID, err = ParseSpanID(s[36:52]); err != nil || !c.SpanID.IsValid() {
+		return c, errors.Errorf("invalid traceparent parent ID %q", s[36:52])
+	}
+	flags := s[53:55]
+	if !isLowerHex(flags) {
+		return c, errors.Errorf("invalid traceparent flags %q", flags)
+	}
+	var b [1]byte
+	hex.Decode(b[:], []byte(flags))
+	c.Sampling = SamplingDeny
+	if b[0]&1 == 1 {
+		c.Sampling = SamplingAccept
+	}
+	return c, nil
+}
+
+// maxTraceStateMembers is the maximum number of W3C trace state list members.
+const maxTraceStateMembers = 32
+
+// TraceStateMember is a vendor-specific key and value of a W3C trace state.
+type TraceStateMember struct {
+	Key   string
+	Value string
+}
+
+// TraceState is a W3C trace state, a list of vendor-specific keys and values,
+// most recently updated first.
+type TraceState []TraceStateMember
+
+// ParseTraceState parses a "tracestate" header value. It returns an error if
+// any of its list members is invalid, has a duplicate key, or if it has more
+// than 32 list members.
+func ParseTraceState(s string) (TraceState, error) {
+	var ts TraceState
+	keys := make(map[string]bool)
+	for _, member := range strings.Split(s, ",") {
+		member = strings.Trim(member, " \t")
+		if member == "" {
+			continue
+		}
+		i := strings.IndexByte(member, '=')
+		if i < 0 {
+			return nil, errors.Errorf("invalid tracestate member %q", member)
+		}
+		key, value := member[:i], member[i+1:]
+		if !isTraceStateKey(key) || !isTraceStateValue(value) {
+			return nil, errors.Errorf("invalid tracestate member %q", member)
+		}
+		if keys[key] {
+			return nil, errors.Errorf("duplicate tracestate key %q", key)
+		}
+		keys[key] = true
+		ts = append(ts, TraceStateMember{Key: key, Value: value})
+	}
+	if len(ts) > maxTraceStateMembers {
+		return nil, errors.Errorf("too many tracestate members: %d", len(ts))
+	}
+	return ts, nil
+}
+
+// isTraceStateKey reports whether the specified string is a valid trace state
+// key, a simple key or a multi-tenant key "tenant@system".
+func isTraceStateKey(s string) bool {
+	tenant, system := s, ""
+	if i := strings.IndexByte(s, '@'); i >= 0 {
+		tenant, system = s[:i], s[i+1:]
+		if len(tenant) == 0 || len(tenant) > 241 || len(system) == 0 || len(system) > 14 {
+			return false
+		}
+		if !isTraceStateKeyPart(system, false) {
+			return false
+		}
+		return isTraceStateKeyPart(tenant, true)
+	}
+	return len(s) > 0 && len(s) <= 256 && isTraceStateKeyPart(tenant, false)
+}
+
+// isTraceStateKeyPart reports whether the specified string only has lowercase
+// letters, digits, "_", "-", "*", and "/", starting with a lowercase letter,
+// or a digit if allowed.
+func isTraceStateKeyPart(s string, digitFirst bool) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z':
+		case '0' <= c && c <= '9':
+			if i == 0 && !digitFirst {
+				return false
+			}
+		case i > 0 && (c == '_' || c == '-' || c == '*' || c == '/'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// isTraceStateValue reports whether the specified string is a valid trace
+// state value.
+func isTraceStateValue(s string) bool {
+	if len(s) == 0 || len(s) > 256 || s[len(s)-1] == ' ' {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c < 0x20 || c > 0x7e || c == ',' || c == '=' {
+			return false
+		}
+	}
+	return true
+}
+
+// Get returns the value of the specified key.
+func (ts TraceState) Get(key string) (string, bool) {
+	for _, m := range ts {
+		if m.Key == key {
+			return m.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set returns a copy of the trace state with the specified key set to the
+// specified value, moved first as the most recently updated. If the list is
+// full, the last member is removed.
+func (ts TraceState) Set(key, value string) (TraceState, error) {
+	if !isTraceStateKey(key) || !isTraceStateValue(value) {
+		return ts, errors.Errorf("invalid tracestate member %q", key+"="+value)
+	}
+	updated := append(TraceState{{Key: key, Value: value}}, ts.Delete(key)...)
+	if len(updated) > maxTraceStateMembers {
+		updated = updated[:maxTraceStateMembers]
+	}
+	return updated, nil
+}
+
+// Delete returns a copy of the trace state without the specified key.
+func (ts TraceState) Delete(key string) TraceState {
+	var deleted TraceState
+	for _, m := range ts {
+		if m.Key != key {
+			deleted = append(deleted, m)
+		}
+	}
+	return deleted
+}
+
+// String returns the "tracestate" header value.
+func (ts TraceState) String() string {
+	members := make([]string, len(ts))
+	for i, m := range ts {
+		members[i] = m.Key + "=" + m.Value
+	}
+	return strings.Join(members, ",")
+}
+
+// B3 header names.
+const (
+	B3Header             = "b3"
+	B3TraceIDHeader      = "X-B3-TraceId"
+	B3SpanIDHeader       = "X-B3-SpanId"
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+	B3SampledHeader      = "X-B3-Sampled"
+	B3FlagsHeader        = "X-B3-Flags"
+)
+
+// Ensure B3Propagator implements the Propagator interface.
+var _ Propagator = B3Propagator{}
+
+// B3Propagator is the B3 propagator, using either the single "b3" header or
+// the multiple "X-B3-*" headers. A trace context with only a sampling
+// decision, e.g. "b3: 0", is extracted with invalid IDs.
+//
+// See https://github.com/openzipkin/b3-propagation.
+type B3Propagator struct {
+	// SingleHeader injects the single "b3" header rather than the multiple
+	// headers. Both are extracted, the single header first.
+	SingleHeader bool
+}
+
+// b3TraceID returns the B3 encoding of the specified trace ID, 16 hex
+// characters for 64-bit trace IDs.
+func b3TraceID(id TraceID) string {
+	if [8]byte(id[:8]) == [8]byte{} {
+		return hex.EncodeToString(id[8:])
+	}
+	return id.String()
+}
+
+// Inject implements the Propagator interface.
+func (p B3Propagator) Inject(c TraceContext, carrier opentracing.TextMapWriter) error {
+	if !c.IsValid() {
+		return opentracing.ErrInvalidSpanContext
+	}
+	if p.SingleHeader {
+		v := b3TraceID(c.TraceID) + "-" + c.SpanID.String()
+		switch c.Sampling {
+		case SamplingAccept:
+			v += "-1"
+		case SamplingDeny:
+			v += "-0"
+		case SamplingDebug:
+			v += "-d"
+		}
+		if c.Sampling != SamplingUnset && c.ParentSpanID.IsValid() {
+			v += "-" + c.ParentSpanID.String()
+		}
+		carrier.Set(B3Header, v)
+		return nil
+	}
+	carrier.Set(B3TraceIDHeader, b3TraceID(c.TraceID))
+	carrier.Set(B3SpanIDHeader, c.SpanID.String())
+	if c.ParentSpanID.IsValid() {
+		carrier.Set(B3ParentSpanIDHeader, c.ParentSpanID.String())
+	}
+	switch c.Sampling {
+	case SamplingAccept:
+		carrier.Set(B3SampledHeader, "1")
+	case SamplingDeny:
+		carrier.Set(B3SampledHeader, "0")
+	case SamplingDebug:
+		carrier.Set(B3FlagsHeader, "1")
+	}
+	return nil
+}
+
+// Extract implements the Propagator interface.
+func (p B3Propagator) Extract(carrier opentracing.TextMapReader) (TraceContext, error) {
+	headers, err := readHeaders(carrier, B3Header, B3TraceIDHeader, B3SpanIDHeader,
+		B3ParentSpanIDHeader, B3SampledHeader, B3FlagsHeader)
+	if err != nil {
+		return TraceContext{}, err
+	}
+	if single, ok := headers[B3Header]; ok {
+		return parseB3Single(strings.TrimSpace(single))
+	}
+	return parseB3Multi(headers)
+}
+
+// b3SingleSampling are the sampling states of the single "b3" header.
+var b3SingleSampling = map[string]Sampling{
+	"1": SamplingAccept,
+	"0": SamplingDeny,
+	"d": SamplingDebug,
+}
+
+// b3Sampled are the values of the "X-B3-Sampled" header, including the
+// legacy "true" and "false".
+var b3Sampled = map[string]Sampling{
+	"1":     SamplingAccept,
+	"0":     SamplingDeny,
+	"true":  SamplingAccept,
+	"false": SamplingDeny,
+}
+
+// parseB3Single parses a single "b3" header value.
+func parseB3Single(s string) (TraceContext, error) {
+	var c TraceContext
+	parts := strings.Split(s, "-")
+	if len(parts) == 1 {
+		var ok bool
+		if c.Sampling, ok = b3SingleSampling[parts[0]]; !ok {
+			return c, opentracing.ErrSpanContextCorrupted
+		}
+		return c, nil
+	}
+	if len(parts) > 4 {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	var err error
+	if c.TraceID, err = ParseTraceID(parts[0]); err != nil || !c.TraceID.IsValid() {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if c.SpanID, err = ParseSpanID(parts[1]); err != nil || !c.SpanID.IsValid() {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if len(parts) > 2 {
+		var ok bool
+		if c.Sampling, ok = b3SingleSampling[parts[2]]; !ok {
+			return c, opentracing.ErrSpanContextCorrupted
+		}
+	}
+	if len(parts) > 3 {
+		if c.ParentSpanID, err = ParseSpanID(parts[3]); err != nil || !c.ParentSpanID.IsValid() {
+			return c, opentracing.ErrSpanContextCorrupted
+		}
+	}
+	return c, nil
+}
+
+// parseB3Multi parses the multiple "X-B3-*" header values.
+func parseB3Multi(headers map[string]string) (TraceContext, error) {
+	var c TraceContext
+	if flags, ok := headers[B3FlagsHeader]; ok && flags == "1" {
+		c.Sampling = SamplingDebug
+	} else if sampled, ok := headers[B3SampledHeader]; ok {
+		var ok bool
+		if c.Sampling, ok = b3Sampled[sampled]; !ok {
+			return c, opentracing.ErrSpanContextCorrupted
+		}
+	}
+	traceID, hasTraceID := headers[B3TraceIDHeader]
+	spanID, hasSpanID := headers[B3SpanIDHeader]
+	switch {
+	case !hasTraceID && !hasSpanID:
+		if c.Sampling == SamplingUnset {
+			return c, opentracing.ErrSpanContextNotFound
+		}
+		return c, nil
+	case !hasTraceID || !hasSpanID:
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	var err error
+	if c.TraceID, err = ParseTraceID(traceID); err != nil || !c.TraceID.IsValid() {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if c.SpanID, err = ParseSpanID(spanID); err != nil || !c.SpanID.IsValid() {
+		return c, opentracing.ErrSpanContextCorrupted
+	}
+	if parent, ok := headers[B3ParentSpanIDHeader]; ok {
+		if c.ParentSpanID, err = ParseSpanID(parent); err != nil || !c.ParentSpanID.IsValid() {
+			return c, opentracing.ErrSpanContextCorrupted
+		}
+	}
+	return c, nil
+}
+
+// Ensure CompositePropagator implements the Propagator interface.
+var _ Propagator = CompositePropagator{}
+
+// CompositePropagator is a propagator that injects a trace context with every
+// propagator, and extracts it with the first propagator that succeeds.
+type CompositePropagator []Propagator
+
+// Inject implements the Propagator interface. It returns the first error.
+func (p CompositePropagator) Inject(c TraceContext, carrier opentracing.TextMapWriter) error {
+	var err error
+	for _, propagator := range p {
+		if injectErr := propagator.Inject(c, carrier); injectErr != nil && err == nil {
+			err = injectErr
+		}
+	}
+	return err
+}
+
+// Extract implements the Propagator interface. If no propagator succeeds, it
+// returns the first error other than opentracing.ErrSpanContextNotFound, if
+// any.
+func (p CompositePropagator) Extract(carrier opentracing.TextMapReader) (TraceContext, error) {
+	err := opentracing.ErrSpanContextNotFound
+	for _, propagator := range p {
+		c, extractErr := propagator.Extract(carrier)
+		if extractErr == nil {
+			return c, nil
+		}
+		if err == opentracing.ErrSpanContextNotFound {
+			err = extractErr
+		}
+	}
+	return TraceContext{}, err
+}
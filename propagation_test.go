@@ -0,0 +1,437 @@
+package trace_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func mustTraceID(t *testing.T, s string) otexts.TraceID {
+	t.Helper()
+	id, err := otexts.ParseTraceID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func mustSpanID(t *testing.T, s string) otexts.SpanID {
+	t.Helper()
+	id, err := otexts.ParseSpanID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestPropagatorInject(t *testing.T) {
+	traceID := mustTraceID(t, "4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID := mustSpanID(t, "00f067aa0ba902b7")
+	parentID := mustSpanID(t, "05e3ac9a4f6e3b90")
+	state := otexts.TraceState{{Key: "congo", Value: "t61rcWkgMzE"}, {Key: "rojo", Value: "00f067aa0ba902b7"}}
+
+	tt := []struct {
+		name       string
+		propagator otexts.Propagator
+		ctx        otexts.TraceContext
+		want       map[string]string
+	}{
+		{
+			name:       "W3C sampled",
+			propagator: otexts.W3CPropagator{},
+			ctx:        otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingAccept, TraceState: state},
+			want: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"tracestate":  "congo=t61rcWkgMzE,rojo=00f067aa0ba902b7",
+			},
+		},
+		{
+			name:       "W3C unsampled",
+			propagator: otexts.W3CPropagator{},
+			ctx:        otexts.TraceContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID},
+			want: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			},
+		},
+		{
+			name:       "B3 multiple headers",
+			propagator: otexts.B3Propagator{},
+			ctx:        otexts.TraceContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID, Sampling: otexts.SamplingAccept},
+			want: map[string]string{
+				"X-B3-TraceId":      "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-SpanId":       "00f067aa0ba902b7",
+				"X-B3-ParentSpanId": "05e3ac9a4f6e3b90",
+				"X-B3-Sampled":      "1",
+			},
+		},
+		{
+			name:       "B3 multiple headers debug 64-bit",
+			propagator: otexts.B3Propagator{},
+			ctx:        otexts.TraceContext{TraceID: mustTraceID(t, "a3ce929d0e0e4736"), SpanID: spanID, Sampling: otexts.SamplingDebug},
+			want: map[string]string{
+				"X-B3-TraceId": "a3ce929d0e0e4736",
+				"X-B3-SpanId":  "00f067aa0ba902b7",
+				"X-B3-Flags":   "1",
+			},
+		},
+		{
+			name:       "B3 single header",
+			propagator: otexts.B3Propagator{SingleHeader: true},
+			ctx:        otexts.TraceContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID, Sampling: otexts.SamplingDeny},
+			want: map[string]string{
+				"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0-05e3ac9a4f6e3b90",
+			},
+		},
+		{
+			name:       "B3 single header deferred",
+			propagator: otexts.B3Propagator{SingleHeader: true},
+			ctx:        otexts.TraceContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID},
+			want: map[string]string{
+				"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+			},
+		},
+		{
+			name:       "composite",
+			propagator: otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{SingleHeader: true}},
+			ctx:        otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingDebug},
+			want: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"b3":          "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-d",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			carrier := opentracing.TextMapCarrier{}
+			if err := tc.propagator.Inject(tc.ctx, carrier); err != nil {
+				t.Fatalf("inject: %v", err)
+			}
+			if got, want := map[string]string(carrier), tc.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("headers: got %v, want %v", got, want)
+			}
+		})
+	}
+
+	err := otexts.W3CPropagator{}.Inject(otexts.TraceContext{}, opentracing.TextMapCarrier{})
+	if got, want := err, opentracing.ErrInvalidSpanContext; got != want {
+		t.Errorf("invalid context error: got %v, want %v", got, want)
+	}
+}
+
+func TestPropagatorExtract(t *testing.T) {
+	traceID := mustTraceID(t, "4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID := mustSpanID(t, "00f067aa0ba902b7")
+	parentID := mustSpanID(t, "05e3ac9a4f6e3b90")
+
+	tt := []struct {
+		name       string
+		propagator otexts.Propagator
+		headers    map[string]string
+		want       otexts.TraceContext
+		wantErr    error
+	}{
+		{
+			name:       "W3C sampled",
+			propagator: otexts.W3CPropagator{},
+			headers: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"tracestate":  "rojo=00f067aa0ba902b7, ,congo=t61rcWkgMzE,tenant@vendor=x",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingAccept, TraceState: otexts.TraceState{
+				{Key: "rojo", Value: "00f067aa0ba902b7"},
+				{Key: "congo", Value: "t61rcWkgMzE"},
+				{Key: "tenant@vendor", Value: "x"},
+			}},
+		},
+		{
+			name:       "W3C unsampled other flags",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"Traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-02"},
+			want:       otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingDeny},
+		},
+		{
+			name:       "W3C future version",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"traceparent": "cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-what-the-future-will-be-like"},
+			want:       otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingAccept},
+		},
+		{
+			name:       "W3C invalid tracestate",
+			propagator: otexts.W3CPropagator{},
+			headers: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"tracestate":  "rojo=1,rojo=2",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingAccept},
+		},
+		{
+			name:       "W3C missing",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"tracestate": "rojo=1"},
+			wantErr:    opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:       "W3C version ff",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"traceparent": "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "W3C version 00 with extra fields",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "W3C uppercase",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-4BF92F3577B34DA6A3CE929D0E0E4736-00F067AA0BA902B7-01"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "W3C zero trace ID",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "W3C zero parent ID",
+			propagator: otexts.W3CPropagator{},
+			headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "B3 multiple headers",
+			propagator: otexts.B3Propagator{},
+			headers: map[string]string{
+				"X-B3-TraceId":      "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-SpanId":       "00f067aa0ba902b7",
+				"X-B3-ParentSpanId": "05e3ac9a4f6e3b90",
+				"X-B3-Sampled":      "1",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID, Sampling: otexts.SamplingAccept},
+		},
+		{
+			name:       "B3 multiple headers lowercase legacy sampled",
+			propagator: otexts.B3Propagator{},
+			headers: map[string]string{
+				"x-b3-traceid": "a3ce929d0e0e4736",
+				"x-b3-spanid":  "00f067aa0ba902b7",
+				"x-b3-sampled": "false",
+			},
+			want: otexts.TraceContext{TraceID: mustTraceID(t, "a3ce929d0e0e4736"), SpanID: spanID, Sampling: otexts.SamplingDeny},
+		},
+		{
+			name:       "B3 multiple headers debug",
+			propagator: otexts.B3Propagator{},
+			headers: map[string]string{
+				"X-B3-TraceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-SpanId":  "00f067aa0ba902b7",
+				"X-B3-Flags":   "1",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingDebug},
+		},
+		{
+			name:       "B3 multiple headers sampling only",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"X-B3-Sampled": "0"},
+			want:       otexts.TraceContext{Sampling: otexts.SamplingDeny},
+		},
+		{
+			name:       "B3 multiple headers missing span ID",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"X-B3-TraceId": "4bf92f3577b34da6a3ce929d0e0e4736"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "B3 multiple headers invalid sampled",
+			propagator: otexts.B3Propagator{},
+			headers: map[string]string{
+				"X-B3-TraceId": "4bf92f3577b34da6a3ce929d0e0e4736",
+				"X-B3-SpanId":  "00f067aa0ba902b7",
+				"X-B3-Sampled": "d",
+			},
+			wantErr: opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "B3 missing",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+			wantErr:    opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:       "B3 single header",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1-05e3ac9a4f6e3b90"},
+			want:       otexts.TraceContext{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID, Sampling: otexts.SamplingAccept},
+		},
+		{
+			name:       "B3 single header deferred",
+			propagator: otexts.B3Propagator{SingleHeader: true},
+			headers:    map[string]string{"B3": "a3ce929d0e0e4736-00f067aa0ba902b7"},
+			want:       otexts.TraceContext{TraceID: mustTraceID(t, "a3ce929d0e0e4736"), SpanID: spanID},
+		},
+		{
+			name:       "B3 single header debug",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-d"},
+			want:       otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingDebug},
+		},
+		{
+			name:       "B3 single header sampling only",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"b3": "0"},
+			want:       otexts.TraceContext{Sampling: otexts.SamplingDeny},
+		},
+		{
+			name:       "B3 single header preferred",
+			propagator: otexts.B3Propagator{},
+			headers: map[string]string{
+				"b3":           "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-1",
+				"X-B3-TraceId": "a3ce929d0e0e4736",
+				"X-B3-SpanId":  "05e3ac9a4f6e3b90",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingAccept},
+		},
+		{
+			name:       "B3 single header invalid sampling",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-true"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "B3 single header invalid span ID",
+			propagator: otexts.B3Propagator{},
+			headers:    map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-f067aa0ba902b7"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "composite first",
+			propagator: otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{}},
+			headers: map[string]string{
+				"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				"b3":          "a3ce929d0e0e4736-05e3ac9a4f6e3b90-0",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingAccept},
+		},
+		{
+			name:       "composite fallback",
+			propagator: otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{}},
+			headers: map[string]string{
+				"traceparent": "00-invalid",
+				"b3":          "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-d",
+			},
+			want: otexts.TraceContext{TraceID: traceID, SpanID: spanID, Sampling: otexts.SamplingDebug},
+		},
+		{
+			name:       "composite corrupted",
+			propagator: otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{}},
+			headers:    map[string]string{"b3": "invalid"},
+			wantErr:    opentracing.ErrSpanContextCorrupted,
+		},
+		{
+			name:       "composite missing",
+			propagator: otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{}},
+			headers:    map[string]string{},
+			wantErr:    opentracing.ErrSpanContextNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.propagator.Extract(opentracing.TextMapCarrier(tc.headers))
+			if err != tc.wantErr {
+				t.Fatalf("error: got %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if want := tc.want; !reflect.DeepEqual(got, want) {
+				t.Errorf("trace context: got %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestPropagatorHTTPHeaders(t *testing.T) {
+	propagator := otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{}}
+	want := otexts.TraceContext{
+		TraceID:  mustTraceID(t, "4bf92f3577b34da6a3ce929d0e0e4736"),
+		SpanID:   mustSpanID(t, "00f067aa0ba902b7"),
+		Sampling: otexts.SamplingAccept,
+	}
+	header := http.Header{}
+	if err := propagator.Inject(want, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	if got, want := header.Get("X-B3-TraceId"), "4bf92f3577b34da6a3ce929d0e0e4736"; got != want {
+		t.Errorf("X-B3-TraceId header: got %q, want %q", got, want)
+	}
+
+	for _, p := range propagator {
+		got, err := p.Extract(opentracing.HTTPHeadersCarrier(header))
+		if err != nil {
+			t.Fatalf("%T extract: %v", p, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%T trace context: got %+v, want %+v", p, got, want)
+		}
+	}
+}
+
+func TestTraceState(t *testing.T) {
+	ts, err := otexts.ParseTraceState("rojo=00f067aa0ba902b7,congo=t61rcWkgMzE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, err = ts.Set("congo", "ucfJifl5GOE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ts.String(), "congo=ucfJifl5GOE,rojo=00f067aa0ba902b7"; got != want {
+		t.Errorf("tracestate: got %q, want %q", got, want)
+	}
+	if got, ok := ts.Get("rojo"); !ok || got != "00f067aa0ba902b7" {
+		t.Errorf("rojo value: got %q, %v, want %q", got, ok, "00f067aa0ba902b7")
+	}
+	if got, want := ts.Delete("rojo").String(), "congo=ucfJifl5GOE"; got != want {
+		t.Errorf("deleted tracestate: got %q, want %q", got, want)
+	}
+	if _, err := ts.Set("Invalid", "value"); err == nil {
+		t.Errorf("set invalid key: got nil error")
+	}
+
+	for i := 0; i < 40; i++ {
+		if ts, err = ts.Set("k"+string(rune('a'+i%26))+string(rune('a'+i/26)), "v"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := len(ts), 32; got != want {
+		t.Errorf("tracestate members: got %d, want %d", got, want)
+	}
+
+	tt := []struct {
+		name  string
+		state string
+	}{
+		{name: "no equals", state: "rojo"},
+		{name: "uppercase key", state: "Rojo=1"},
+		{name: "digit first key", state: "1rojo=1"},
+		{name: "empty value", state: "rojo="},
+		{name: "equals in value", state: "rojo=1=2"},
+		{name: "long system", state: "tenant@abcdefghijklmnop=1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := otexts.ParseTraceState(tc.state); err == nil {
+				t.Errorf("parse %q: got nil error", tc.state)
+			}
+		})
+	}
+}
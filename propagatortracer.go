@@ -0,0 +1,107 @@
+package trace
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+)
+
+// Ensure PropagatorTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &PropagatorTracer{}
+
+// PropagatorTracer is an opentracing.Tracer that wraps another tracer,
+// injecting and extracting the span contexts of the TextMap and HTTPHeaders
+// formats with a Propagator in addition to the wrapped tracer, e.g. to
+// accept the W3C Trace Context and B3 headers of the upstream services of a
+// mixed environment:
+//
+//	opentracing.SetGlobalTracer(trace.NewPropagatorTracer(tracer, trace.B3Propagator{},
+//		trace.CompositePropagator{trace.W3CPropagator{}, trace.B3Propagator{}}))
+//
+// The span contexts of the wrapped tracer are converted to and from trace
+// contexts through its own headers, read and written by the native
+// propagator: the wrapped tracer must inject and extract headers that the
+// native propagator supports, e.g. the B3 headers of a Zipkin tracer, or the
+// W3C Trace Context headers of an otelbridge.Tracer.
+//
+// The spans and span contexts of a PropagatorTracer are the spans and span
+// contexts of the wrapped tracer.
+type PropagatorTracer struct {
+	tracer     opentracing.Tracer
+	native     Propagator
+	propagator Propagator
+}
+
+// NewPropagatorTracer returns a new tracer that wraps the specified tracer,
+// whose headers are read and written by the specified native propagator,
+// injecting and extracting the span contexts with the specified propagator.
+func NewPropagatorTracer(tracer opentracing.Tracer, native, propagator Propagator) *PropagatorTracer {
+	return &PropagatorTracer{tracer: tracer, native: native, propagator: propagator}
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *PropagatorTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	return t.tracer.StartSpan(operationName, opts...)
+}
+
+// Inject implements the opentracing.Tracer interface. The TextMap and
+// HTTPHeaders formats are injected with the headers of the wrapped tracer,
+// then with the propagator. The other formats are injected by the wrapped
+// tracer.
+func (t *PropagatorTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	w, ok := carrier.(opentracing.TextMapWriter)
+	if !ok || format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return t.tracer.Inject(sc, format, carrier)
+	}
+	headers := opentracing.TextMapCarrier{}
+	if err := t.tracer.Inject(sc, format, headers); err != nil {
+		return err
+	}
+	for k, v := range headers {
+		w.Set(k, v)
+	}
+	c, err := t.native.Extract(headers)
+	if err != nil {
+		return errors.Wrap(err, "convert span context")
+	}
+	return t.propagator.Inject(c, w)
+}
+
+// Extract implements the opentracing.Tracer interface. The TextMap and
+// HTTPHeaders formats are extracted by the wrapped tracer, or with the
+// propagator if the wrapped tracer finds no span context. The other formats
+// are extracted by the wrapped tracer.
+func (t *PropagatorTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	sc, err := t.tracer.Extract(format, carrier)
+	if err != opentracing.ErrSpanContextNotFound {
+		return sc, err
+	}
+	r, ok := carrier.(opentracing.TextMapReader)
+	if !ok || format != opentracing.TextMap && format != opentracing.HTTPHeaders {
+		return nil, err
+	}
+	c, err := t.propagator.Extract(r)
+	if err != nil {
+		return nil, err
+	}
+	return t.SpanContext(c)
+}
+
+// TraceContext returns the trace context of the specified span context of
+// the wrapped tracer.
+func (t *PropagatorTracer) TraceContext(sc opentracing.SpanContext) (TraceContext, error) {
+	headers := opentracing.TextMapCarrier{}
+	if err := t.tracer.Inject(sc, opentracing.TextMap, headers); err != nil {
+		return TraceContext{}, err
+	}
+	return t.native.Extract(headers)
+}
+
+// SpanContext returns the span context of the wrapped tracer of the
+// specified trace context.
+func (t *PropagatorTracer) SpanContext(c TraceContext) (opentracing.SpanContext, error) {
+	headers := opentracing.TextMapCarrier{}
+	if err := t.native.Inject(c, headers); err != nil {
+		return nil, err
+	}
+	return t.tracer.Extract(opentracing.TextMap, headers)
+}
@@ -0,0 +1,161 @@
+package trace_test
+
+import (
+	"encoding/binary"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// mockPropagator is the propagator of the mocktracer headers, with 64-bit
+// decimal IDs.
+type mockPropagator struct{}
+
+func (mockPropagator) Inject(c otexts.TraceContext, carrier opentracing.TextMapWriter) error {
+	carrier.Set("mockpfx-ids-traceid", strconv.FormatUint(binary.BigEndian.Uint64(c.TraceID[8:]), 10))
+	carrier.Set("mockpfx-ids-spanid", strconv.FormatUint(binary.BigEndian.Uint64(c.SpanID[:]), 10))
+	carrier.Set("mockpfx-ids-sampled", strconv.FormatBool(c.Sampling == otexts.SamplingAccept || c.Sampling == otexts.SamplingDebug))
+	return nil
+}
+
+func (mockPropagator) Extract(carrier opentracing.TextMapReader) (otexts.TraceContext, error) {
+	var c otexts.TraceContext
+	err := carrier.ForeachKey(func(k, v string) error {
+		switch k {
+		case "mockpfx-ids-traceid", "mockpfx-ids-spanid":
+			id, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				return opentracing.ErrSpanContextCorrupted
+			}
+			if k == "mockpfx-ids-traceid" {
+				binary.BigEndian.PutUint64(c.TraceID[8:], id)
+			} else {
+				binary.BigEndian.PutUint64(c.SpanID[:], id)
+			}
+		case "mockpfx-ids-sampled":
+			c.Sampling = otexts.SamplingDeny
+			if v == "true" {
+				c.Sampling = otexts.SamplingAccept
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return c, err
+	}
+	if !c.IsValid() {
+		return c, opentracing.ErrSpanContextNotFound
+	}
+	return c, nil
+}
+
+// newTestPropagatorTracer returns a new PropagatorTracer of a mocktracer,
+// propagating the W3C Trace Context and B3 headers.
+func newTestPropagatorTracer() (*otexts.PropagatorTracer, *mocktracer.MockTracer) {
+	mock := mocktracer.New()
+	propagator := otexts.CompositePropagator{otexts.W3CPropagator{}, otexts.B3Propagator{}}
+	return otexts.NewPropagatorTracer(mock, mockPropagator{}, propagator), mock
+}
+
+func TestPropagatorTracer_inject(t *testing.T) {
+	tracer, _ := newTestPropagatorTracer()
+	span := tracer.StartSpan("test")
+	span.SetBaggageItem("item", "value")
+	sc := span.Context().(mocktracer.MockSpanContext)
+
+	header := http.Header{}
+	if err := tracer.Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(header)); err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	traceID := "0000000000000000" + otexts.SpanID(uint64Bytes(uint64(sc.TraceID))).String()
+	spanID := otexts.SpanID(uint64Bytes(uint64(sc.SpanID))).String()
+	want := map[string]string{
+		"Traceparent":          "00-" + traceID + "-" + spanID + "-01",
+		"X-B3-Traceid":         traceID[16:],
+		"X-B3-Spanid":          spanID,
+		"X-B3-Sampled":         "1",
+		"Mockpfx-Ids-Traceid":  strconv.Itoa(sc.TraceID),
+		"Mockpfx-Baggage-Item": "value",
+	}
+	for k, v := range want {
+		if got := header.Get(k); got != v {
+			t.Errorf("%s header: got %q, want %q", k, got, v)
+		}
+	}
+
+	c, err := tracer.TraceContext(span.Context())
+	if err != nil {
+		t.Fatalf("trace context: %v", err)
+	}
+	if got, want := c.SpanID.String(), spanID; got != want {
+		t.Errorf("trace context span ID: got %s, want %s", got, want)
+	}
+}
+
+func TestPropagatorTracer_extract(t *testing.T) {
+	tt := []struct {
+		name   string
+		header http.Header
+		want   mocktracer.MockSpanContext
+		err    error
+	}{
+		{
+			name:   "w3c",
+			header: http.Header{"Traceparent": {"00-000000000000000000000000000004d2-000000000000162e-01"}},
+			want:   mocktracer.MockSpanContext{TraceID: 1234, SpanID: 5678, Sampled: true},
+		},
+		{
+			name:   "b3",
+			header: http.Header{"B3": {"00000000000004d2-000000000000162e-0"}},
+			want:   mocktracer.MockSpanContext{TraceID: 1234, SpanID: 5678},
+		},
+		{
+			name: "native first",
+			header: http.Header{
+				"Traceparent":         {"00-000000000000000000000000000004d2-000000000000162e-01"},
+				"Mockpfx-Ids-Traceid": {"1"},
+				"Mockpfx-Ids-Spanid":  {"2"},
+				"Mockpfx-Ids-Sampled": {"false"},
+			},
+			want: mocktracer.MockSpanContext{TraceID: 1, SpanID: 2},
+		},
+		{
+			name:   "not found",
+			header: http.Header{},
+			err:    opentracing.ErrSpanContextNotFound,
+		},
+		{
+			name:   "corrupted",
+			header: http.Header{"Traceparent": {"00-invalid"}},
+			err:    opentracing.ErrSpanContextCorrupted,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer, _ := newTestPropagatorTracer()
+			sc, err := tracer.Extract(opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(tc.header))
+			if err != tc.err {
+				t.Fatalf("extract: got error %v, want %v", err, tc.err)
+			}
+			if err != nil {
+				return
+			}
+			got := sc.(mocktracer.MockSpanContext)
+			if got.TraceID != tc.want.TraceID || got.SpanID != tc.want.SpanID || got.Sampled != tc.want.Sampled {
+				t.Errorf("span context: got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+// uint64Bytes returns the big-endian bytes of the specified integer.
+func uint64Bytes(v uint64) [8]byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return b
+}
@@ -0,0 +1,398 @@
+package trace
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Default RetentionTracer options.
+const (
+	DefaultRetentionMaxSpans     = 10000
+	DefaultRetentionTraceTimeout = time.Minute
+)
+
+// RetentionOptions are the options of a RetentionTracer.
+type RetentionOptions struct {
+	// LatencyThreshold forwards the traces with a span that lasts at least
+	// the threshold, or zero to only forward the traces with errors.
+	LatencyThreshold time.Duration
+
+	// MaxSpans is the maximum number of buffered spans, defaults to
+	// DefaultRetentionMaxSpans. When it is exceeded, the oldest traces are
+	// evicted.
+	MaxSpans int
+
+	// TraceTimeout is the maximum duration a trace is buffered, defaults to
+	// DefaultRetentionTraceTimeout. When it is exceeded, the trace is
+	// forwarded if it has an error or exceeds the latency threshold, and
+	// evicted otherwise.
+	TraceTimeout time.Duration
+
+	// Now returns the current time, defaults to time.Now.
+	Now func() time.Time
+}
+
+// RetentionStats are the statistics of a RetentionTracer.
+type RetentionStats struct {
+	Traces         int    // The number of buffered traces.
+	Spans          int    // The number of buffered spans.
+	Forwarded      uint64 // The number of traces forwarded.
+	Dropped        uint64 // The number of traces dropped when their root span finished.
+	EvictedTimeout uint64 // The number of traces evicted by the trace timeout.
+	EvictedMemory  uint64 // The number of traces evicted by the maximum number of spans.
+}
+
+// Ensure RetentionTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &RetentionTracer{}
+
+// RetentionTracer is an opentracing.Tracer that implements tail-based sampling
+// of local traces: it buffers the spans of a trace until its root span
+// finishes, then forwards the whole trace to the wrapped tracer only if a
+// span has the "error" tag set, e.g. by LogError, or lasts at least the
+// latency threshold. The spans of a forwarded trace that are not finished are
+// forwarded when they finish.
+//
+// The spans are started in the wrapped tracer when they start, so their span
+// contexts can always be injected, but they are only finished, and reported,
+// once their trace is forwarded: the spans of a dropped trace are never
+// finished. The wrapped tracer must only report the spans that finish, as
+// most tracers do. The log records of the spans are buffered until they
+// finish.
+//
+// The root span of a trace is a span started without a reference to a span
+// of the tracer, it may reference an extracted span context.
+type RetentionTracer struct {
+	tracer opentracing.Tracer
+	opts   RetentionOptions
+	mu     sync.Mutex
+	traces list.List // The buffered *retainedTrace, oldest, and first to expire, first.
+	spans  int       // The number of buffered spans.
+	stats  RetentionStats
+}
+
+// NewRetentionTracer returns a new tracer that wraps the specified tracer,
+// forwarding only the traces with errors or high latency. The options may be
+// nil.
+func NewRetentionTracer(tracer opentracing.Tracer, opts *RetentionOptions) *RetentionTracer {
+	t := &RetentionTracer{tracer: tracer}
+	if opts != nil {
+		t.opts = *opts
+	}
+	if t.opts.MaxSpans <= 0 {
+		t.opts.MaxSpans = DefaultRetentionMaxSpans
+	}
+	if t.opts.TraceTimeout <= 0 {
+		t.opts.TraceTimeout = DefaultRetentionTraceTimeout
+	}
+	if t.opts.Now == nil {
+		t.opts.Now = time.Now
+	}
+	return t
+}
+
+// Stats returns the statistics of the tracer.
+func (t *RetentionTracer) Stats() RetentionStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := t.stats
+	stats.Traces = t.traces.Len()
+	stats.Spans = t.spans
+	return stats
+}
+
+// traceState is the state of a retained trace.
+type traceState int
+
+// Retained trace states.
+const (
+	traceBuffered traceState = iota
+	traceForwarded
+	traceDropped
+)
+
+// retainedTrace is a trace of a RetentionTracer.
+type retainedTrace struct {
+	state  traceState
+	start  time.Time       // The time the trace was started.
+	elem   *list.Element   // The element of the buffered traces.
+	spans  []*retainedSpan // The buffered spans, in start order.
+	retain bool            // Whether the trace has an error or exceeds the latency threshold.
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *RetentionTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	now := t.opts.Now()
+	s := &retainedSpan{tracer: t, start: sso.StartTime}
+	if s.start.IsZero() {
+		s.start = now
+	}
+	var parent *retainedSpan
+	wrapped := []opentracing.StartSpanOption{opentracing.StartTime(s.start), opentracing.Tags(sso.Tags)}
+	for _, ref := range sso.References {
+		if c, ok := ref.ReferencedContext.(retainedSpanContext); ok {
+			if parent == nil {
+				parent = c.span
+			}
+			ref.ReferencedContext = c.span.real.Context()
+		}
+		wrapped = append(wrapped, ref)
+	}
+	s.real = t.tracer.StartSpan(operationName, wrapped...)
+
+	t.mu.Lock()
+	reported := t.evictLocked(now, nil)
+	if parent != nil {
+		s.trace = parent.trace
+	} else {
+		s.trace = &retainedTrace{start: now}
+		s.trace.elem = t.traces.PushBack(s.trace)
+	}
+	if isErr, _ := sso.Tags[string(ext.Error)].(bool); isErr {
+		s.trace.retain = true
+	}
+	if s.trace.state == traceBuffered {
+		s.trace.spans = append(s.trace.spans, s)
+		t.spans++
+		reported = t.evictLocked(now, reported)
+	}
+	t.mu.Unlock()
+
+	report(reported)
+	return s
+}
+
+// Inject implements the opentracing.Tracer interface.
+func (t *RetentionTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	if c, ok := sc.(retainedSpanContext); ok {
+		sc = c.span.real.Context()
+	}
+	return t.tracer.Inject(sc, format, carrier)
+}
+
+// Extract implements the opentracing.Tracer interface.
+func (t *RetentionTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	return t.tracer.Extract(format, carrier)
+}
+
+// evictLocked evicts the traces that exceed the trace timeout, then the
+// oldest traces while the maximum number of spans is exceeded. It returns the
+// specified spans to report with the finished spans of the forwarded traces
+// appended.
+func (t *RetentionTracer) evictLocked(now time.Time, reported []*retainedSpan) []*retainedSpan {
+	for e := t.traces.Front(); e != nil; e = t.traces.Front() {
+		tr := e.Value.(*retainedTrace)
+		if now.Sub(tr.start) < t.opts.TraceTimeout {
+			break
+		}
+		if tr.retain {
+			reported = t.forwardLocked(tr, reported)
+		} else {
+			t.dropLocked(tr, &t.stats.EvictedTimeout)
+		}
+	}
+	for t.spans > t.opts.MaxSpans && t.traces.Len() > 0 {
+		t.dropLocked(t.traces.Front().Value.(*retainedTrace), &t.stats.EvictedMemory)
+	}
+	return reported
+}
+
+// forwardLocked forwards the specified buffered trace to the wrapped tracer.
+// It returns the specified spans to report with the finished spans of the
+// trace appended.
+func (t *RetentionTracer) forwardLocked(tr *retainedTrace, reported []*retainedSpan) []*retainedSpan {
+	t.removeLocked(tr)
+	tr.state = traceForwarded
+	t.stats.Forwarded++
+	for _, s := range tr.spans {
+		if s.finished {
+			reported = append(reported, s)
+		}
+	}
+	tr.spans = nil
+	return reported
+}
+
+// dropLocked drops the specified buffered trace, incrementing the specified
+// counter.
+func (t *RetentionTracer) dropLocked(tr *retainedTrace, counter *uint64) {
+	t.removeLocked(tr)
+	tr.state = traceDropped
+	for _, s := range tr.spans {
+		s.logs = nil
+	}
+	tr.spans = nil
+	*counter++
+}
+
+// removeLocked removes the specified trace from the buffered traces.
+func (t *RetentionTracer) removeLocked(tr *retainedTrace) {
+	t.traces.Remove(tr.elem)
+	t.spans -= len(tr.spans)
+}
+
+// report finishes the specified spans in the wrapped tracer. It must be
+// called without holding the tracer's mutex.
+func report(spans []*retainedSpan) {
+	for _, s := range spans {
+		s.real.FinishWithOptions(opentracing.FinishOptions{FinishTime: s.finish, LogRecords: s.logs})
+	}
+}
+
+// retainedSpanContext is the span context of a RetentionTracer span.
+type retainedSpanContext struct {
+	span *retainedSpan
+}
+
+// ForeachBaggageItem implements the opentracing.SpanContext interface.
+func (c retainedSpanContext) ForeachBaggageItem(handler func(k, v string) bool) {
+	c.span.real.Context().ForeachBaggageItem(handler)
+}
+
+// retainedSpan is a span of a RetentionTracer. Its trace and its finish state
+// and logs are guarded by the tracer's mutex, the logs are immutable once the
+// span finishes.
+type retainedSpan struct {
+	tracer   *RetentionTracer
+	trace    *retainedTrace
+	real     opentracing.Span // The span of the wrapped tracer.
+	start    time.Time
+	logs     []opentracing.LogRecord
+	finished bool
+	finish   time.Time
+}
+
+// Finish implements the opentracing.Span interface.
+func (s *retainedSpan) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions implements the opentracing.Span interface.
+func (s *retainedSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	t := s.tracer
+	now := t.opts.Now()
+	t.mu.Lock()
+	if s.finished {
+		t.mu.Unlock()
+		return
+	}
+	s.finished = true
+	s.finish = opts.FinishTime
+	if s.finish.IsZero() {
+		s.finish = now
+	}
+	if s.trace.state != traceDropped {
+		s.logs = append(s.logs, opts.LogRecords...)
+		for _, ld := range opts.BulkLogData {
+			s.logs = append(s.logs, ld.ToLogRecord())
+		}
+	}
+	var reported []*retainedSpan
+	switch s.trace.state {
+	case traceBuffered:
+		if t.opts.LatencyThreshold > 0 && s.finish.Sub(s.start) >= t.opts.LatencyThreshold {
+			s.trace.retain = true
+		}
+		if s.trace.spans[0] == s {
+			if s.trace.retain {
+				reported = t.forwardLocked(s.trace, reported)
+			} else {
+				t.dropLocked(s.trace, &t.stats.Dropped)
+			}
+		}
+	case traceForwarded:
+		reported = append(reported, s)
+	}
+	reported = t.evictLocked(now, reported)
+	t.mu.Unlock()
+
+	report(reported)
+}
+
+// Context implements the opentracing.Span interface.
+func (s *retainedSpan) Context() opentracing.SpanContext {
+	return retainedSpanContext{span: s}
+}
+
+// SetOperationName implements the opentracing.Span interface.
+func (s *retainedSpan) SetOperationName(operationName string) opentracing.Span {
+	s.real.SetOperationName(operationName)
+	return s
+}
+
+// SetTag implements the opentracing.Span interface.
+func (s *retainedSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.real.SetTag(key, value)
+	if isErr, _ := value.(bool); isErr && key == string(ext.Error) {
+		s.tracer.mu.Lock()
+		s.trace.retain = true
+		s.tracer.mu.Unlock()
+	}
+	return s
+}
+
+// LogFields implements the opentracing.Span interface.
+func (s *retainedSpan) LogFields(fields ...log.Field) {
+	s.log(opentracing.LogRecord{Timestamp: s.tracer.opts.Now(), Fields: fields})
+}
+
+// LogKV implements the opentracing.Span interface.
+func (s *retainedSpan) LogKV(alternatingKeyValues ...interface{}) {
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		s.LogFields(log.Error(err), log.String("function", "LogKV"))
+		return
+	}
+	s.LogFields(fields...)
+}
+
+// log buffers the specified log record until the span finishes.
+func (s *retainedSpan) log(record opentracing.LogRecord) {
+	s.tracer.mu.Lock()
+	defer s.tracer.mu.Unlock()
+	if !s.finished && s.trace.state != traceDropped {
+		s.logs = append(s.logs, record)
+	}
+}
+
+// SetBaggageItem implements the opentracing.Span interface.
+func (s *retainedSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.real.SetBaggageItem(restrictedKey, value)
+	return s
+}
+
+// BaggageItem implements the opentracing.Span interface.
+func (s *retainedSpan) BaggageItem(restrictedKey string) string {
+	return s.real.BaggageItem(restrictedKey)
+}
+
+// Tracer implements the opentracing.Span interface.
+func (s *retainedSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent implements the deprecated opentracing.Span method.
+func (s *retainedSpan) LogEvent(event string) {
+	s.Log(opentracing.LogData{Event: event})
+}
+
+// LogEventWithPayload implements the deprecated opentracing.Span method.
+func (s *retainedSpan) LogEventWithPayload(event string, payload interface{}) {
+	s.Log(opentracing.LogData{Event: event, Payload: payload})
+}
+
+// Log implements the deprecated opentracing.Span method.
+func (s *retainedSpan) Log(data opentracing.LogData) {
+	if data.Timestamp.IsZero() {
+		data.Timestamp = s.tracer.opts.Now()
+	}
+	s.log(data.ToLogRecord())
+}
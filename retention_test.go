@@ -0,0 +1,262 @@
+package trace_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// fakeClock is a clock that only advances when told to.
+type fakeClock struct {
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func TestRetentionTracer(t *testing.T) {
+	tt := []struct {
+		name      string
+		trace     func(tracer opentracing.Tracer, clock *fakeClock)
+		forwarded bool
+	}{
+		{
+			name: "no error",
+			trace: func(tracer opentracing.Tracer, clock *fakeClock) {
+				root := tracer.StartSpan("root")
+				tracer.StartSpan("child", opentracing.ChildOf(root.Context())).Finish()
+				root.Finish()
+			},
+		},
+		{
+			name: "error",
+			trace: func(tracer opentracing.Tracer, clock *fakeClock) {
+				root := tracer.StartSpan("root")
+				child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+				otexts.LogError(child, errors.New("error"))
+				child.Finish()
+				root.Finish()
+			},
+			forwarded: true,
+		},
+		{
+			name: "error tag option",
+			trace: func(tracer opentracing.Tracer, clock *fakeClock) {
+				root := tracer.StartSpan("root")
+				tracer.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: string(ext.Error), Value: true}).Finish()
+				root.Finish()
+			},
+			forwarded: true,
+		},
+		{
+			name: "latency",
+			trace: func(tracer opentracing.Tracer, clock *fakeClock) {
+				root := tracer.StartSpan("root")
+				child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+				clock.Advance(2 * time.Second)
+				child.Finish()
+				root.Finish()
+			},
+			forwarded: true,
+		},
+		{
+			name: "below latency threshold",
+			trace: func(tracer opentracing.Tracer, clock *fakeClock) {
+				root := tracer.StartSpan("root")
+				child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+				clock.Advance(500 * time.Millisecond)
+				child.Finish()
+				root.Finish()
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mock, clock := mocktracer.New(), newFakeClock()
+			tracer := otexts.NewRetentionTracer(mock, &otexts.RetentionOptions{
+				LatencyThreshold: time.Second,
+				Now:              clock.Now,
+			})
+			start := clock.Now()
+			tc.trace(tracer, clock)
+
+			want := otexts.RetentionStats{Dropped: 1}
+			if tc.forwarded {
+				want = otexts.RetentionStats{Forwarded: 1}
+			}
+			if got := tracer.Stats(); got != want {
+				t.Errorf("stats: got %+v, want %+v", got, want)
+			}
+			spans := mock.FinishedSpans()
+			if !tc.forwarded {
+				if got, want := len(spans), 0; got != want {
+					t.Errorf("finished spans: got %d, want %d", got, want)
+				}
+				return
+			}
+			if got, want := len(spans), 2; got != want {
+				t.Fatalf("finished spans: got %d, want %d", got, want)
+			}
+			// The spans are forwarded in start order.
+			root, child := spans[0], spans[1]
+			if got, want := child.ParentID, root.SpanContext.SpanID; got != want {
+				t.Errorf("child parent ID: got %d, want %d", got, want)
+			}
+			if got, want := root.StartTime, start; !got.Equal(want) {
+				t.Errorf("root start time: got %v, want %v", got, want)
+			}
+			if got, want := root.FinishTime, clock.Now(); !got.Equal(want) {
+				t.Errorf("root finish time: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestRetentionTracer_unfinishedSpans(t *testing.T) {
+	mock, clock := mocktracer.New(), newFakeClock()
+	tracer := otexts.NewRetentionTracer(mock, &otexts.RetentionOptions{Now: clock.Now})
+
+	root := tracer.StartSpan("root")
+	root.SetBaggageItem("item", "value")
+	child := tracer.StartSpan("child", opentracing.ChildOf(root.Context()))
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(child.Context(), opentracing.TextMap, carrier); err != nil {
+		t.Errorf("inject buffered: %v", err)
+	}
+	if got, want := carrier["mockpfx-baggage-item"], "value"; got != want {
+		t.Errorf("injected baggage item: got %q, want %q", got, want)
+	}
+	otexts.LogError(root, errors.New("error"))
+	root.Finish()
+	if got, want := len(mock.FinishedSpans()), 1; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+
+	clock.Advance(time.Second)
+	child.LogKV("event", "test")
+	grandchild := tracer.StartSpan("grandchild", opentracing.ChildOf(child.Context()))
+	if got, want := grandchild.BaggageItem("item"), "value"; got != want {
+		t.Errorf("baggage item: got %q, want %q", got, want)
+	}
+	if err := tracer.Inject(grandchild.Context(), opentracing.TextMap, opentracing.TextMapCarrier{}); err != nil {
+		t.Errorf("inject forwarded: %v", err)
+	}
+	grandchild.Finish()
+	child.Finish()
+
+	spans := mock.FinishedSpans()
+	if got, want := len(spans), 3; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	rootSpan, grandchildSpan, childSpan := spans[0], spans[1], spans[2]
+	if got, want := childSpan.ParentID, rootSpan.SpanContext.SpanID; got != want {
+		t.Errorf("child parent ID: got %d, want %d", got, want)
+	}
+	if got, want := grandchildSpan.ParentID, childSpan.SpanContext.SpanID; got != want {
+		t.Errorf("grandchild parent ID: got %d, want %d", got, want)
+	}
+	logs := childSpan.Logs()
+	if got, want := len(logs), 1; got != want {
+		t.Fatalf("child logs: got %d, want %d", got, want)
+	}
+	if got, want := logs[0].Timestamp, clock.Now(); !got.Equal(want) {
+		t.Errorf("child log timestamp: got %v, want %v", got, want)
+	}
+}
+
+func TestRetentionTracer_eviction(t *testing.T) {
+	mock, clock := mocktracer.New(), newFakeClock()
+	tracer := otexts.NewRetentionTracer(mock, &otexts.RetentionOptions{
+		MaxSpans:     3,
+		TraceTimeout: time.Minute,
+		Now:          clock.Now,
+	})
+
+	expired := tracer.StartSpan("expired")
+	failed := tracer.StartSpan("failed")
+	otexts.LogError(failed, errors.New("error"))
+	clock.Advance(time.Minute)
+
+	first := tracer.StartSpan("first")
+	tracer.StartSpan("child", opentracing.ChildOf(first.Context()))
+	tracer.StartSpan("second")
+	tracer.StartSpan("third")
+
+	want := otexts.RetentionStats{Traces: 2, Spans: 2, Forwarded: 1, EvictedTimeout: 1, EvictedMemory: 1}
+	if got := tracer.Stats(); got != want {
+		t.Errorf("stats: got %+v, want %+v", got, want)
+	}
+
+	// Evicted traces are not forwarded when they finish.
+	otexts.LogError(expired, errors.New("error"))
+	expired.Finish()
+	failed.Finish()
+	first.Finish()
+
+	spans := mock.FinishedSpans()
+	if got, want := len(spans), 1; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	if got, want := spans[0].OperationName, "failed"; got != want {
+		t.Errorf("operation name: got %q, want %q", got, want)
+	}
+}
+
+// hookTracer is a tracer that calls a hook when it starts and finishes spans.
+type hookTracer struct {
+	*mocktracer.MockTracer
+	hook func()
+}
+
+func (t *hookTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	t.hook()
+	return &hookSpan{Span: t.MockTracer.StartSpan(operationName, opts...), hook: t.hook}
+}
+
+// hookSpan is a span that calls a hook when it finishes.
+type hookSpan struct {
+	opentracing.Span
+	hook func()
+}
+
+func (s *hookSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	s.hook()
+	s.Span.FinishWithOptions(opts)
+}
+
+func TestRetentionTracer_wrappedTracerUnlocked(t *testing.T) {
+	var tracer *otexts.RetentionTracer
+	calls := 0
+	mock := &hookTracer{MockTracer: mocktracer.New(), hook: func() {
+		// Deadlocks if the wrapped tracer is called with the mutex held.
+		tracer.Stats()
+		calls++
+	}}
+	tracer = otexts.NewRetentionTracer(mock, nil)
+
+	root := tracer.StartSpan("root")
+	otexts.LogError(root, errors.New("error"))
+	root.Finish()
+
+	if got, want := calls, 2; got != want {
+		t.Errorf("wrapped tracer calls: got %d, want %d", got, want)
+	}
+	if got, want := len(mock.FinishedSpans()), 1; got != want {
+		t.Errorf("finished spans: got %d, want %d", got, want)
+	}
+}
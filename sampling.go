@@ -0,0 +1,323 @@
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/pkg/errors"
+)
+
+// SamplingRule is a head-based sampling rule matching the operation name and
+// the tags set by the span options when a span starts, e.g. by RPCTags,
+// DBTags, and HTTPTags.
+type SamplingRule struct {
+	// Operation is the operation name of the matched spans, or empty to match
+	// any operation.
+	Operation string `json:"operation,omitempty"`
+
+	// Tags are the tag values of the matched spans, compared to the tag
+	// values formatted with fmt.Sprint, e.g. "http.method": "GET". The tags
+	// match in every tag mode: a tag not set is looked up by its
+	// OpenTelemetry attribute name, e.g. "http.request.method", and the
+	// reverse.
+	Tags map[string]string `json:"tags,omitempty"`
+
+	// Error matches only spans started with the "error" tag set to true by
+	// the span options. The sampling decision is made when a span starts, so
+	// the errors logged afterwards, e.g. by LogError, are not matched: use a
+	// RetentionTracer to keep the traces with errors.
+	Error bool `json:"error,omitempty"`
+
+	// Rate is the probability that a matched span is sampled, from 0, never
+	// sampled, to 1, always sampled.
+	Rate float64 `json:"rate"`
+
+	// MaxPerSecond is the maximum number of sampled spans per second of each
+	// operation matched by the rule, or zero for no maximum. See
+	// SamplingOptions.MaxRateLimits.
+	MaxPerSecond float64 `json:"maxPerSecond,omitempty"`
+}
+
+// SamplingConfig is the configuration of a SamplingTracer.
+type SamplingConfig struct {
+	// Rules are the sampling rules, in order. The first rule that matches a
+	// span determines if it is sampled. The spans that no rule matches are
+	// started unchanged, leaving the sampling decision to the wrapped tracer.
+	Rules []SamplingRule `json:"rules"`
+
+	// Noop starts no-op spans for the spans that are not sampled, rather than
+	// setting their "sampling.priority" tag to 0.
+	Noop bool `json:"noop,omitempty"`
+}
+
+// ParseSamplingConfig parses and validates a JSON sampling configuration, e.g.
+//
+//	{
+//		"rules": [
+//			{"operation": "checkout", "rate": 1},
+//			{"tags": {"http.method": "GET", "http.url": "/healthz"}, "rate": 0.01},
+//			{"tags": {"db.type": "redis"}, "rate": 0},
+//			{"operation": "poll", "rate": 1, "maxPerSecond": 10}
+//		]
+//	}
+func ParseSamplingConfig(r io.Reader) (SamplingConfig, error) {
+	var config SamplingConfig
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&config); err != nil {
+		return SamplingConfig{}, errors.Wrap(err, "invalid sampling config")
+	}
+	if err := config.validate(); err != nil {
+		return SamplingConfig{}, err
+	}
+	return config, nil
+}
+
+// validate validates the sampling rules.
+func (c SamplingConfig) validate() error {
+	for i, r := range c.Rules {
+		if r.Rate < 0 || r.Rate > 1 {
+			return errors.Errorf("sampling rule %d: rate %v is not between 0 and 1", i, r.Rate)
+		}
+		if r.MaxPerSecond < 0 {
+			return errors.Errorf("sampling rule %d: negative max per second %v", i, r.MaxPerSecond)
+		}
+	}
+	return nil
+}
+
+// DefaultSamplingMaxRateLimits is the default maximum number of operation
+// rate limits of a SamplingTracer.
+const DefaultSamplingMaxRateLimits = 1000
+
+// SamplingOptions are the options of a SamplingTracer.
+type SamplingOptions struct {
+	// MaxRateLimits is the maximum number of operation rate limits, defaults
+	// to DefaultSamplingMaxRateLimits. When it is exceeded, the operations
+	// without a rate limit share a rate limit for each rule, guarding
+	// against operation names of unbounded cardinality.
+	MaxRateLimits int
+
+	// Rand returns a pseudo-random number in [0, 1), defaults to
+	// math/rand.Float64.
+	Rand func() float64
+
+	// Now returns the current time used by the rate limits, defaults to
+	// time.Now.
+	Now func() time.Time
+}
+
+// Ensure SamplingTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &SamplingTracer{}
+
+// SamplingTracer is an opentracing.Tracer that wraps another tracer, applying
+// head-based sampling rules to the spans it starts without references. A
+// sampled span has its "sampling.priority" tag set to 1, a span that is not
+// sampled has it set to 0, or is a no-op span if the configuration is Noop.
+// Spans with references or an explicit "sampling.priority" tag are started
+// unchanged, except for the children of no-op spans, which are no-op spans.
+type SamplingTracer struct {
+	tracer opentracing.Tracer
+	config SamplingConfig
+
+	mu         sync.Mutex
+	rand       func() float64
+	now        func() time.Time
+	maxBuckets int
+	buckets    map[samplingBucketKey]*tokenBucket
+	overflow   map[int]*tokenBucket // The shared rate limits of each rule.
+}
+
+// samplingBucketKey is the key of the rate limit of an operation matched by a
+// sampling rule.
+type samplingBucketKey struct {
+	rule      int
+	operation string
+}
+
+// NewSamplingTracer returns a new tracer that wraps the specified tracer,
+// applying the sampling rules of the specified configuration. The options may
+// be nil.
+func NewSamplingTracer(tracer opentracing.Tracer, config SamplingConfig, opts *SamplingOptions) (*SamplingTracer, error) {
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	t := &SamplingTracer{
+		tracer:     tracer,
+		config:     config,
+		rand:       rand.Float64,
+		now:        time.Now,
+		maxBuckets: DefaultSamplingMaxRateLimits,
+		buckets:    make(map[samplingBucketKey]*tokenBucket),
+		overflow:   make(map[int]*tokenBucket),
+	}
+	if opts != nil && opts.Rand != nil {
+		t.rand = opts.Rand
+	}
+	if opts != nil && opts.Now != nil {
+		t.now = opts.Now
+	}
+	if opts != nil && opts.MaxRateLimits > 0 {
+		t.maxBuckets = opts.MaxRateLimits
+	}
+	return t, nil
+}
+
+// noopSpanContextType is the type of the span context of the no-op spans.
+var noopSpanContextType = reflect.TypeOf(opentracing.NoopTracer{}.StartSpan("").Context())
+
+// isNoopSpanContext reports whether the specified span context is the span
+// context of a no-op span.
+func isNoopSpanContext(sc opentracing.SpanContext) bool {
+	return reflect.TypeOf(sc) == noopSpanContextType
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *SamplingTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	if len(sso.References) > 0 {
+		for _, ref := range sso.References {
+			if isNoopSpanContext(ref.ReferencedContext) {
+				return opentracing.NoopTracer{}.StartSpan(operationName)
+			}
+		}
+		return t.tracer.StartSpan(operationName, opts...)
+	}
+	if _, ok := sso.Tags[string(ext.SamplingPriority)]; ok {
+		return t.tracer.StartSpan(operationName, opts...)
+	}
+
+	sampled, ok := t.sample(operationName, sso.Tags)
+	switch {
+	case !ok:
+		return t.tracer.StartSpan(operationName, opts...)
+	case !sampled && t.config.Noop:
+		return opentracing.NoopTracer{}.StartSpan(operationName)
+	}
+	priority := uint16(0)
+	if sampled {
+		priority = 1
+	}
+	opts = append(opts[:len(opts):len(opts)], opentracing.Tag{Key: string(ext.SamplingPriority), Value: priority})
+	return t.tracer.StartSpan(operationName, opts...)
+}
+
+// sample returns whether a span with the specified operation name and tags is
+// sampled, and whether a sampling rule matches it.
+func (t *SamplingTracer) sample(operationName string, tags map[string]interface{}) (sampled, ok bool) {
+	for i, r := range t.config.Rules {
+		if !r.matches(operationName, tags) {
+			continue
+		}
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if r.Rate < 1 && t.rand() >= r.Rate {
+			return false, true
+		}
+		if r.MaxPerSecond > 0 {
+			return t.bucketLocked(i, operationName).take(t.now()), true
+		}
+		return true, true
+	}
+	return false, false
+}
+
+// bucketLocked returns the rate limit of the specified operation matched by
+// the specified rule, or the shared rate limit of the rule if the maximum
+// number of rate limits is exceeded.
+func (t *SamplingTracer) bucketLocked(rule int, operationName string) *tokenBucket {
+	key := samplingBucketKey{rule: rule, operation: operationName}
+	if b, ok := t.buckets[key]; ok {
+		return b
+	}
+	rate := t.config.Rules[rule].MaxPerSecond
+	if len(t.buckets) < t.maxBuckets {
+		b := newTokenBucket(rate, t.now())
+		t.buckets[key] = b
+		return b
+	}
+	b, ok := t.overflow[rule]
+	if !ok {
+		b = newTokenBucket(rate, t.now())
+		t.overflow[rule] = b
+	}
+	return b
+}
+
+// matches reports whether the rule matches a span with the specified
+// operation name and tags.
+func (r SamplingRule) matches(operationName string, tags map[string]interface{}) bool {
+	if r.Operation != "" && r.Operation != operationName {
+		return false
+	}
+	if r.Error {
+		if isErr, _ := tags[string(ext.Error)].(bool); !isErr {
+			return false
+		}
+	}
+	for k, want := range r.Tags {
+		v, ok := lookupTag(tags, k)
+		if !ok || fmt.Sprint(v) != want {
+			return false
+		}
+	}
+	return true
+}
+
+// Inject implements the opentracing.Tracer interface. Injecting the span
+// context of a no-op span injects nothing.
+func (t *SamplingTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	if isNoopSpanContext(sc) {
+		return nil
+	}
+	return t.tracer.Inject(sc, format, carrier)
+}
+
+// Extract implements the opentracing.Tracer interface.
+func (t *SamplingTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	return t.tracer.Extract(format, carrier)
+}
+
+// tokenBucket is a token bucket rate limiter.
+type tokenBucket struct {
+	rate   float64   // The tokens added per second.
+	burst  float64   // The maximum number of tokens.
+	tokens float64   // The available tokens.
+	last   time.Time // The time the tokens were last updated.
+}
+
+// newTokenBucket returns a new full token bucket with the specified rate, and
+// a burst of the rate, or one token if the rate is lower.
+func newTokenBucket(rate float64, now time.Time) *tokenBucket {
+	burst := rate
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+// take takes a token, if one is available at the specified time.
+func (b *tokenBucket) take(now time.Time) bool {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,209 @@
+package trace_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+const testSamplingConfig = `{
+	"rules": [
+		{"error": true, "rate": 1},
+		{"tags": {"http.method": "GET", "http.url": "/healthz"}, "rate": 0.01},
+		{"tags": {"db.type": "redis"}, "rate": 0},
+		{"operation": "poll", "rate": 1, "maxPerSecond": 2}
+	]
+}`
+
+func TestSamplingTracer(t *testing.T) {
+	tt := []struct {
+		name     string
+		rand     float64
+		opts     []opentracing.StartSpanOption
+		priority interface{} // The sampling priority tag, or nil if not set.
+	}{
+		{
+			name:     "error",
+			rand:     0.5,
+			opts:     []opentracing.StartSpanOption{otexts.DBTags{Type: "redis"}, opentracing.Tag{Key: string(ext.Error), Value: true}},
+			priority: uint16(1),
+		},
+		{
+			name:     "health check sampled",
+			rand:     0.005,
+			opts:     []opentracing.StartSpanOption{otexts.HTTPTags{Method: http.MethodGet, URL: "/healthz"}},
+			priority: uint16(1),
+		},
+		{
+			name:     "health check sampled otel",
+			rand:     0.005,
+			opts:     []opentracing.StartSpanOption{otexts.HTTPTags{Method: http.MethodGet, URL: "/healthz", Mode: otexts.TagModeOTel}},
+			priority: uint16(1),
+		},
+		{
+			name:     "health check not sampled",
+			rand:     0.5,
+			opts:     []opentracing.StartSpanOption{otexts.HTTPTags{Method: http.MethodGet, URL: "/healthz"}},
+			priority: uint16(0),
+		},
+		{
+			name:     "never",
+			opts:     []opentracing.StartSpanOption{otexts.DBTags{Type: "Redis"}},
+			priority: uint16(0),
+		},
+		{
+			name: "no match",
+			opts: []opentracing.StartSpanOption{otexts.HTTPTags{Method: http.MethodPost, URL: "/healthz"}},
+		},
+		{
+			name:     "never otel",
+			opts:     []opentracing.StartSpanOption{otexts.DBTags{Type: "Redis", Mode: otexts.TagModeOTel}},
+			priority: uint16(0),
+		},
+		{
+			name:     "explicit priority",
+			opts:     []opentracing.StartSpanOption{otexts.DBTags{Type: "redis"}, opentracing.Tag{Key: string(ext.SamplingPriority), Value: uint16(2)}},
+			priority: uint16(2),
+		},
+	}
+	config, err := otexts.ParseSamplingConfig(strings.NewReader(testSamplingConfig))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := mocktracer.New()
+			tracer, err := otexts.NewSamplingTracer(mock, config, &otexts.SamplingOptions{
+				Rand: func() float64 { return tc.rand },
+			})
+			if err != nil {
+				t.Fatalf("new sampling tracer: %v", err)
+			}
+			tracer.StartSpan("test", tc.opts...).Finish()
+
+			if got, want := mock.FinishedSpans()[0].Tag(string(ext.SamplingPriority)), tc.priority; got != want {
+				t.Errorf("sampling priority: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestSamplingTracer_noop(t *testing.T) {
+	mock := mocktracer.New()
+	config := otexts.SamplingConfig{
+		Rules: []otexts.SamplingRule{{Operation: "dropped", Rate: 0}},
+		Noop:  true,
+	}
+	tracer, err := otexts.NewSamplingTracer(mock, config, nil)
+	if err != nil {
+		t.Fatalf("new sampling tracer: %v", err)
+	}
+
+	span := tracer.StartSpan("dropped")
+	child := tracer.StartSpan("child", opentracing.ChildOf(span.Context()))
+	carrier := opentracing.TextMapCarrier{}
+	if err := tracer.Inject(child.Context(), opentracing.TextMap, carrier); err != nil {
+		t.Errorf("inject: %v", err)
+	}
+	if got, want := len(carrier), 0; got != want {
+		t.Errorf("injected keys: got %d, want %d", got, want)
+	}
+	child.Finish()
+	span.Finish()
+
+	sampled := tracer.StartSpan("sampled")
+	tracer.StartSpan("child", opentracing.ChildOf(sampled.Context())).Finish()
+	sampled.Finish()
+
+	spans := mock.FinishedSpans()
+	if got, want := len(spans), 2; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	if got, want := spans[0].OperationName, "child"; got != want {
+		t.Errorf("operation name: got %q, want %q", got, want)
+	}
+}
+
+func TestSamplingTracer_rateLimit(t *testing.T) {
+	now := time.Unix(0, 0)
+	mock := mocktracer.New()
+	config, err := otexts.ParseSamplingConfig(strings.NewReader(testSamplingConfig))
+	if err != nil {
+		t.Fatalf("parse config: %v", err)
+	}
+	tracer, err := otexts.NewSamplingTracer(mock, config, &otexts.SamplingOptions{
+		Now: func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("new sampling tracer: %v", err)
+	}
+
+	var got []interface{}
+	for _, d := range []time.Duration{0, 0, 0, 250 * time.Millisecond, 250 * time.Millisecond, 10 * time.Second, 0, 0} {
+		now = now.Add(d)
+		span := tracer.StartSpan("poll")
+		span.Finish()
+		got = append(got, mock.FinishedSpans()[len(mock.FinishedSpans())-1].Tag(string(ext.SamplingPriority)))
+	}
+	want := []interface{}{uint16(1), uint16(1), uint16(0), uint16(0), uint16(1), uint16(1), uint16(1), uint16(0)}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("span %d sampling priority: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestParseSamplingConfig(t *testing.T) {
+	tt := []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{name: "invalid rate", config: `{"rules": [{"rate": 2}]}`, err: "rate 2 is not between 0 and 1"},
+		{name: "invalid max per second", config: `{"rules": [{"rate": 1, "maxPerSecond": -1}]}`, err: "negative max per second"},
+		{name: "unknown field", config: `{"rules": [{"rate": 1, "operations": "test"}]}`, err: "unknown field"},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := otexts.ParseSamplingConfig(strings.NewReader(tc.config))
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("error: got %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestSamplingTracer_maxRateLimits(t *testing.T) {
+	now := time.Unix(0, 0)
+	mock := mocktracer.New()
+	config := otexts.SamplingConfig{
+		Rules: []otexts.SamplingRule{{Rate: 1, MaxPerSecond: 1}},
+	}
+	tracer, err := otexts.NewSamplingTracer(mock, config, &otexts.SamplingOptions{
+		Now:           func() time.Time { return now },
+		MaxRateLimits: 2,
+	})
+	if err != nil {
+		t.Fatalf("new sampling tracer: %v", err)
+	}
+
+	var got []interface{}
+	for _, op := range []string{"a", "b", "c", "d", "a", "b"} {
+		tracer.StartSpan(op).Finish()
+		got = append(got, mock.FinishedSpans()[len(mock.FinishedSpans())-1].Tag(string(ext.SamplingPriority)))
+	}
+	// The operations "c" and "d" share the rate limit of the rule.
+	want := []interface{}{uint16(1), uint16(1), uint16(1), uint16(0), uint16(0), uint16(0)}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("span %d sampling priority: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/pkg/errors"
+)
+
+// Ensure SlogHandler implements the slog.Handler interface.
+var _ slog.Handler = (*SlogHandler)(nil)
+
+// SlogErrorKey is the attribute key of the error logged by a SlogHandler for
+// records at slog.LevelError and above, in any attribute group.
+const SlogErrorKey = "error"
+
+// SlogMessageKey is the log field name of the record message of the errors
+// logged by a SlogHandler, whose "message" log field is the error message, as
+// for LogError.
+const SlogMessageKey = "slog.message"
+
+// SlogHandlerOptions are the options for a SlogHandler.
+type SlogHandlerOptions struct {
+	// Level is the minimum record level that is logged. If nil, records at
+	// slog.LevelInfo and above are logged.
+	Level slog.Leveler
+}
+
+// SlogHandler is a slog.Handler that writes log records as log events on the
+// opentracing span found in the record's context. Records logged without a
+// span in the context are dropped.
+//
+// The "event" log field is set to the record level, the "level" log field to
+// the corresponding Level, and the "message" log field to the record message.
+// Records at slog.LevelError and above with an error attribute keyed by
+// SlogErrorKey, in any attribute group, are logged with the same tags and log
+// fields as LogErrorWithFields, with the record message in the SlogMessageKey
+// log field.
+type SlogHandler struct {
+	level  slog.Leveler
+	prefix string      // The key prefix of the open attribute groups.
+	fields []log.Field // The log fields of the preformatted attributes.
+	err    error       // The error of the preformatted attributes.
+}
+
+// NewSlogHandler returns a new SlogHandler with the specified options. If opts
+// is nil, the default options are used.
+func NewSlogHandler(opts *SlogHandlerOptions) *SlogHandler {
+	h := &SlogHandler{level: slog.LevelInfo}
+	if opts != nil && opts.Level != nil {
+		h.level = opts.Level
+	}
+	return h
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && opentracing.SpanFromContext(ctx) != nil
+}
+
+// Handle implements the slog.Handler interface.
+func (h *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+	span := opentracing.SpanFromContext(ctx)
+	if span == nil {
+		return nil
+	}
+	fields := make([]log.Field, 0, 4+len(h.fields)+r.NumAttrs())
+	fields = append(fields, log.String(LogFieldEvent, strings.ToLower(r.Level.String())))
+	fields = append(fields, log.String(LogFieldLevel, slogLevel(r.Level).String()))
+	fields = append(fields, log.String(LogFieldMessage, r.Message))
+	fields = append(fields, h.fields...)
+	err := h.err
+	r.Attrs(func(a slog.Attr) bool {
+		fields = appendSlogAttr(fields, &err, h.prefix, a)
+		return true
+	})
+	if err != nil && r.Level >= slog.LevelError {
+		fields[0] = log.String(LogFieldEvent, LogEventError)
+		fields[2] = log.String(LogFieldMessage, err.Error())
+		fields = append(fields, log.String(LogFieldErrorKind, fmt.Sprintf("%T", errors.Cause(err))))
+		if r.Message != "" {
+			fields = append(fields, log.String(SlogMessageKey, r.Message))
+		}
+		ext.Error.Set(span, true)
+	} else if err != nil {
+		fields = append(fields, log.String(SlogErrorKey, err.Error()))
+	}
+	span.LogFields(fields...)
+	return nil
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.fields = make([]log.Field, len(h.fields), len(h.fields)+len(attrs))
+	copy(h2.fields, h.fields)
+	for _, a := range attrs {
+		h2.fields = appendSlogAttr(h2.fields, &h2.err, h.prefix, a)
+	}
+	return &h2
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "."
+	return &h2
+}
+
+// slogLevel returns the Level corresponding to the specified slog level.
+func slogLevel(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return LevelDebug
+	case l < slog.LevelWarn:
+		return LevelInfo
+	case l < slog.LevelError:
+		return LevelWarn
+	}
+	return LevelError
+}
+
+// appendSlogAttr appends the typed log fields for the specified attribute,
+// flattening groups into dot separated keys. An error attribute keyed by
+// SlogErrorKey, in any group, is stored in err instead. Top level attributes
+// with the reserved log field names "event", "level", "error.kind", and
+// "message" are ignored.
+func appendSlogAttr(fields []log.Field, err *error, prefix string, a slog.Attr) []log.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return fields
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			fields = appendSlogAttr(fields, err, prefix, ga)
+		}
+		return fields
+	}
+	if e, ok := a.Value.Any().(error); ok && e != nil && a.Key == SlogErrorKey {
+		*err = e
+		return fields
+	}
+	if prefix == "" && (a.Key == LogFieldEvent || a.Key == LogFieldLevel || a.Key == LogFieldErrorKind || a.Key == LogFieldMessage) {
+		return fields
+	}
+	key := prefix + a.Key
+	v := a.Value
+	switch v.Kind() {
+	case slog.KindString:
+		return append(fields, log.String(key, v.String()))
+	case slog.KindInt64:
+		return append(fields, log.Int64(key, v.Int64()))
+	case slog.KindUint64:
+		return append(fields, log.Uint64(key, v.Uint64()))
+	case slog.KindFloat64:
+		return append(fields, log.Float64(key, v.Float64()))
+	case slog.KindBool:
+		return append(fields, log.Bool(key, v.Bool()))
+	case slog.KindDuration:
+		return append(fields, log.String(key, v.Duration().String()))
+	case slog.KindTime:
+		return append(fields, log.String(key, v.Time().Format(time.RFC3339Nano)))
+	}
+	if err, ok := v.Any().(error); ok {
+		return append(fields, log.String(key, err.Error()))
+	}
+	return append(fields, log.Object(key, v.Any()))
+}
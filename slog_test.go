@@ -0,0 +1,211 @@
+package trace_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestSlogHandler(t *testing.T) {
+	tt := []struct {
+		name   string
+		opts   *otexts.SlogHandlerOptions
+		log    func(ctx context.Context, l *slog.Logger)
+		logs   int
+		err    bool
+		fields map[string]interface{}
+	}{
+		{
+			name: "info",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.InfoContext(ctx, "hello", "count", 3, "ok", true, "took", time.Second)
+			},
+			logs: 1,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:   "info",
+				otexts.LogFieldLevel:   "info",
+				otexts.LogFieldMessage: "hello",
+				"count":                int64(3),
+				"ok":                   true,
+				"took":                 "1s",
+			},
+		},
+		{
+			name: "filtered level",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.DebugContext(ctx, "hello")
+			},
+			logs: 0,
+		},
+		{
+			name: "debug level",
+			opts: &otexts.SlogHandlerOptions{Level: slog.LevelDebug},
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.DebugContext(ctx, "hello")
+			},
+			logs: 1,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:   "debug",
+				otexts.LogFieldLevel:   "debug",
+				otexts.LogFieldMessage: "hello",
+			},
+		},
+		{
+			name: "groups",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.With("a", "1").WithGroup("req").With("id", "2").InfoContext(ctx, "hello", slog.Group("user", "name", "foo"))
+			},
+			logs: 1,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:   "info",
+				otexts.LogFieldLevel:   "info",
+				otexts.LogFieldMessage: "hello",
+				"a":                    "1",
+				"req.id":               "2",
+				"req.user.name":        "foo",
+			},
+		},
+		{
+			name: "reserved keys",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.InfoContext(ctx, "hello", otexts.LogFieldEvent, "foo", otexts.LogFieldMessage, "bar")
+			},
+			logs: 1,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:   "info",
+				otexts.LogFieldLevel:   "info",
+				otexts.LogFieldMessage: "hello",
+			},
+		},
+		{
+			name: "error",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.ErrorContext(ctx, "failed", otexts.SlogErrorKey, errors.New("error"), "foo", "bar")
+			},
+			logs: 1,
+			err:  true,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:     otexts.LogEventError,
+				otexts.LogFieldLevel:     "error",
+				otexts.LogFieldMessage:   "error",
+				otexts.LogFieldErrorKind: fmt.Sprintf("%T", errors.New("error")),
+				otexts.SlogMessageKey:    "failed",
+				"foo":                    "bar",
+			},
+		},
+		{
+			name: "error in group",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.WithGroup("req").ErrorContext(ctx, "failed", otexts.SlogErrorKey, errors.New("error"), "id", "1")
+			},
+			logs: 1,
+			err:  true,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:     otexts.LogEventError,
+				otexts.LogFieldLevel:     "error",
+				otexts.LogFieldMessage:   "error",
+				otexts.LogFieldErrorKind: fmt.Sprintf("%T", errors.New("error")),
+				otexts.SlogMessageKey:    "failed",
+				"req.id":                 "1",
+			},
+		},
+		{
+			name: "error in group attr",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.ErrorContext(ctx, "failed", slog.Group("db", otexts.SlogErrorKey, errors.New("error")))
+			},
+			logs: 1,
+			err:  true,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:     otexts.LogEventError,
+				otexts.LogFieldLevel:     "error",
+				otexts.LogFieldMessage:   "error",
+				otexts.LogFieldErrorKind: fmt.Sprintf("%T", errors.New("error")),
+				otexts.SlogMessageKey:    "failed",
+			},
+		},
+		{
+			name: "error from attrs",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.With(otexts.SlogErrorKey, errors.New("error")).ErrorContext(ctx, "")
+			},
+			logs: 1,
+			err:  true,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:     otexts.LogEventError,
+				otexts.LogFieldLevel:     "error",
+				otexts.LogFieldMessage:   "error",
+				otexts.LogFieldErrorKind: fmt.Sprintf("%T", errors.New("error")),
+			},
+		},
+		{
+			name: "error below error level",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.WarnContext(ctx, "failed", otexts.SlogErrorKey, errors.New("error"))
+			},
+			logs: 1,
+			fields: map[string]interface{}{
+				otexts.LogFieldEvent:   "warn",
+				otexts.LogFieldLevel:   "warn",
+				otexts.LogFieldMessage: "failed",
+				otexts.SlogErrorKey:    "error",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tracer := mocktracer.New()
+			span := tracer.StartSpan("test").(*mocktracer.MockSpan)
+			ctx := opentracing.ContextWithSpan(context.Background(), span)
+			tc.log(ctx, slog.New(otexts.NewSlogHandler(tc.opts)))
+			span.Finish()
+
+			if got, want := span.Tag(string(ext.Error)) != nil, tc.err; got != want {
+				t.Errorf("error tag: got %t, want %t", got, want)
+			}
+			logs := span.Logs()
+			if got, want := len(logs), tc.logs; got != want {
+				t.Fatalf("logs: got %d, want %d", got, want)
+			}
+			if tc.logs == 0 {
+				return
+			}
+			logFields := logs[0].Fields
+			if got, want := len(logFields), len(tc.fields); got != want {
+				t.Fatalf("log fields: got %d, want %d: %v", got, want, logFields)
+			}
+			for _, field := range logFields {
+				want, ok := tc.fields[field.Key]
+				if !ok {
+					t.Errorf("log field: %s: unexpected field", field.Key)
+					continue
+				}
+				if got := field.ValueString; got != fmt.Sprint(want) {
+					t.Errorf("log field: %s: got %q, want %q", field.Key, got, fmt.Sprint(want))
+				}
+				if got, want := field.ValueKind, fmt.Sprintf("%T", want); got.String() != want {
+					t.Errorf("log field: %s: kind: got %s, want %s", field.Key, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSlogHandler_noSpan(t *testing.T) {
+	h := otexts.NewSlogHandler(nil)
+	if h.Enabled(context.Background(), slog.LevelError) {
+		t.Error("expected handler to be disabled without a span")
+	}
+	if err := h.Handle(context.Background(), slog.Record{}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,303 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// StructTagKey is the struct field tag key read by TagsFromStruct and
+// SetTagsFromStruct.
+const StructTagKey = "trace"
+
+// RedactedValue is the tag value set for struct fields with the "redact"
+// option.
+const RedactedValue = "[REDACTED]"
+
+// maxStructDepth is the maximum depth of nested structs that tags are read
+// from, guarding against cyclic values.
+const maxStructDepth = 16
+
+// Ensure structTags implements the opentracing.StartSpanOption interface.
+var _ opentracing.StartSpanOption = structTags{}
+
+// structTags is an opentracing.StartSpanOption that sets the tags read from
+// the fields of a struct.
+type structTags struct {
+	v interface{}
+}
+
+// TagsFromStruct returns an opentracing.StartSpanOption that sets the tags
+// read from the fields of the specified struct, or pointer to a struct, with
+// a "trace" struct field tag. The tag value is the span tag name, optionally
+// followed by comma separated options:
+//
+//	type Request struct {
+//		ID       string        `trace:"request.id"`
+//		Token    string        `trace:"request.token,redact"`
+//		Retries  int           `trace:"request.retries,omitempty"`
+//		Timeout  time.Duration `trace:"request.timeout,omitempty"`
+//		Peer     Peer          `trace:"peer"`
+//		Internal string        `trace:"-"`
+//	}
+//
+// If the tag name is empty, the field name is used. The "omitempty" option
+// skips empty values, following the same semantics as RPCTags, DBTags, and
+// HTTPTags: empty strings, nil or empty IPs, and zero numbers, durations, and
+// times are not set. The "redact" option sets the tag to RedactedValue
+// instead of the field value. Nil pointer and interface fields, e.g. a nil
+// error, and nil or empty IPs are always skipped, as by RPCTags.
+//
+// Nested struct fields with a "trace" struct field tag set their tags with
+// the tag name and a dot as the prefix, e.g. "peer.service". Embedded structs
+// without a "trace" struct field tag set their tags without a prefix.
+//
+// Values of type net.IP, time.Duration, time.Time, fmt.Stringer, and error
+// are set as strings. Values of other non-basic types are formatted with the
+// fmt package. The fields promoted from unexported embedded structs are read
+// by kind: their IPs and durations are set as strings, their named basic
+// types as the underlying basic type, and their other values are formatted
+// with the fmt package.
+//
+// The fields read from each struct type are computed once and cached.
+func TagsFromStruct(v interface{}) opentracing.StartSpanOption {
+	return structTags{v: v}
+}
+
+// Apply implements the opentracing.StartSpanOption interface.
+func (t structTags) Apply(opts *opentracing.StartSpanOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.Tags == nil {
+		opts.Tags = make(map[string]interface{})
+	}
+	readStructTags(t.v, func(k string, v interface{}) {
+		opts.Tags[k] = v
+	})
+}
+
+// SetTagsFromStruct sets the tags read from the fields of the specified
+// struct on the specified span. See TagsFromStruct.
+func SetTagsFromStruct(span opentracing.Span, v interface{}) {
+	if span == nil {
+		return
+	}
+	readStructTags(v, func(k string, v interface{}) {
+		span.SetTag(k, v)
+	})
+}
+
+// SetTagsFromStructCtx calls SetTagsFromStruct with the span from the
+// specified context, if any.
+func SetTagsFromStructCtx(ctx context.Context, v interface{}) {
+	SetTagsFromStruct(opentracing.SpanFromContext(ctx), v)
+}
+
+// structField is a struct field that tags are read from.
+type structField struct {
+	index     int    // The field index.
+	key       string // The tag name, or prefix of nested struct tag names.
+	omitEmpty bool   // Whether to skip empty values.
+	redact    bool   // Whether to redact the value.
+	nested    bool   // Whether the field is a nested struct.
+}
+
+// structPlans is the cache of the fields read from each struct type, a map of
+// reflect.Type to []structField.
+var structPlans sync.Map
+
+// Types with special formatting.
+var (
+	ipType         = reflect.TypeOf(net.IP{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	timeType       = reflect.TypeOf(time.Time{})
+	stringerType   = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	errorIfaceType = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// structPlan returns the fields that tags are read from for the specified
+// struct type.
+func structPlan(t reflect.Type) []structField {
+	if plan, ok := structPlans.Load(t); ok {
+		return plan.([]structField)
+	}
+	var plan []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, tagged := f.Tag.Lookup(StructTagKey)
+		if tag == "-" || (f.PkgPath != "" && !f.Anonymous) {
+			continue
+		}
+		opts := strings.Split(tag, ",")
+		sf := structField{index: i, key: opts[0]}
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "omitempty":
+				sf.omitEmpty = true
+			case "redact":
+				sf.redact = true
+			}
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		sf.nested = ft.Kind() == reflect.Struct && ft != timeType && !sf.redact
+		switch {
+		case sf.nested && !tagged && f.Anonymous:
+		case !tagged || f.PkgPath != "":
+			continue
+		case sf.key == "":
+			sf.key = f.Name
+		}
+		if sf.nested && sf.key != "" {
+			sf.key += "."
+		}
+		plan = append(plan, sf)
+	}
+	actual, _ := structPlans.LoadOrStore(t, plan)
+	return actual.([]structField)
+}
+
+// readStructTags calls the specified function with each tag read from the
+// specified struct.
+func readStructTags(v interface{}, set func(k string, v interface{})) {
+	readStructValue(reflect.ValueOf(v), "", 0, set)
+}
+
+func readStructValue(rv reflect.Value, prefix string, depth int, set func(k string, v interface{})) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct || depth > maxStructDepth {
+		return
+	}
+	for _, sf := range structPlan(rv.Type()) {
+		fv := rv.Field(sf.index)
+		if sf.nested {
+			readStructValue(fv, prefix+sf.key, depth+1, set)
+			continue
+		}
+		switch fv.Kind() {
+		case reflect.Ptr:
+			if fv.IsNil() {
+				continue
+			}
+			fv = fv.Elem()
+		case reflect.Interface:
+			if fv.IsNil() {
+				continue
+			}
+		}
+		if fv.Type() == ipType && fv.Len() == 0 {
+			continue
+		}
+		if sf.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		if sf.redact {
+			set(prefix+sf.key, RedactedValue)
+			continue
+		}
+		set(prefix+sf.key, structTagValue(fv))
+	}
+}
+
+// isEmptyValue reports whether the specified value is empty.
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return v.Len() == 0
+	case reflect.Interface:
+		return v.IsNil()
+	}
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).IsZero()
+	}
+	return v.IsZero()
+}
+
+// structTagValue returns the tag value of the specified struct field value.
+// The values that cannot be interfaced, e.g. the fields promoted from
+// unexported embedded structs, are read with the reflect.Value methods of
+// their kind.
+func structTagValue(v reflect.Value) interface{} {
+	switch v.Type() {
+	case ipType:
+		return net.IP(v.Bytes()).String()
+	case durationType:
+		return time.Duration(v.Int()).String()
+	case timeType:
+		if v.CanInterface() {
+			return v.Interface().(time.Time).Format(time.RFC3339Nano)
+		}
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if !v.CanInterface() || v.Type().PkgPath() != "" && !v.Type().Implements(stringerType) {
+			// Convert named basic types to the underlying basic type.
+			return basicValue(v)
+		}
+		if v.Type().PkgPath() == "" {
+			return v.Interface()
+		}
+	}
+	if !v.CanInterface() {
+		return fmt.Sprint(v)
+	}
+	switch {
+	case v.Type().Implements(errorIfaceType):
+		return v.Interface().(error).Error()
+	case v.Type().Implements(stringerType):
+		return v.Interface().(fmt.Stringer).String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// basicValue returns the specified value of a basic kind as its basic type.
+func basicValue(v reflect.Value) interface{} {
+	var basic reflect.Value
+	switch v.Kind() {
+	case reflect.Bool:
+		basic = reflect.ValueOf(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		basic = reflect.ValueOf(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		basic = reflect.ValueOf(v.Uint())
+	default:
+		basic = reflect.ValueOf(v.Float())
+	}
+	return basic.Convert(basicTypes[v.Kind()]).Interface()
+}
+
+// basicTypes are the basic types for each basic kind.
+var basicTypes = map[reflect.Kind]reflect.Type{
+	reflect.Bool:    reflect.TypeOf(false),
+	reflect.Int:     reflect.TypeOf(int(0)),
+	reflect.Int8:    reflect.TypeOf(int8(0)),
+	reflect.Int16:   reflect.TypeOf(int16(0)),
+	reflect.Int32:   reflect.TypeOf(int32(0)),
+	reflect.Int64:   reflect.TypeOf(int64(0)),
+	reflect.Uint:    reflect.TypeOf(uint(0)),
+	reflect.Uint8:   reflect.TypeOf(uint8(0)),
+	reflect.Uint16:  reflect.TypeOf(uint16(0)),
+	reflect.Uint32:  reflect.TypeOf(uint32(0)),
+	reflect.Uint64:  reflect.TypeOf(uint64(0)),
+	reflect.Float32: reflect.TypeOf(float32(0)),
+	reflect.Float64: reflect.TypeOf(float64(0)),
+}
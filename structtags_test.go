@@ -0,0 +1,198 @@
+package trace_test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+type testStructPeer struct {
+	Service string `trace:"service"`
+	IPv4    net.IP `trace:"ipv4,omitempty"`
+	Port    uint16 `trace:"port,omitempty"`
+}
+
+type testStructEmbedded struct {
+	Tenant string `trace:"tenant"`
+}
+
+type testStructRequest struct {
+	testStructEmbedded
+
+	ID       string           `trace:"request.id"`
+	Token    string           `trace:"request.token,redact"`
+	Retries  int              `trace:"request.retries,omitempty"`
+	Timeout  time.Duration    `trace:"request.timeout"`
+	Kind     ext.SpanKindEnum `trace:"span.kind,omitempty"`
+	Count    *int             `trace:"request.count"`
+	Default  bool             `trace:",omitempty"`
+	Peer     testStructPeer   `trace:"peer"`
+	Upstream *testStructPeer  `trace:"upstream"`
+	Internal string           `trace:"-"`
+	Untagged string
+}
+
+type testStructConn struct {
+	Addr   net.IP           `trace:"conn.addr"`
+	Local  net.IP           `trace:"conn.local"`
+	Idle   time.Duration    `trace:"conn.idle"`
+	Port   uint16           `trace:"conn.port"`
+	Kind   ext.SpanKindEnum `trace:"conn.kind"`
+	Pooled bool             `trace:"conn.pooled"`
+}
+
+type testStructPooled struct {
+	testStructConn
+}
+
+type testStructResult struct {
+	ID     string       `trace:"result.id"`
+	Err    error        `trace:"result.error"`
+	Status fmt.Stringer `trace:"result.status"`
+}
+
+func TestTagsFromStruct(t *testing.T) {
+	count := 3
+	tt := []struct {
+		name string
+		v    interface{}
+		tags map[string]string
+	}{
+		{
+			name: "all fields",
+			v: &testStructRequest{
+				testStructEmbedded: testStructEmbedded{Tenant: "acme"},
+				ID:                 "id",
+				Token:              "secret",
+				Retries:            2,
+				Timeout:            time.Second,
+				Kind:               ext.SpanKindRPCClientEnum,
+				Count:              &count,
+				Default:            true,
+				Peer: testStructPeer{
+					Service: "service",
+					IPv4:    net.IPv4(127, 0, 0, 1),
+					Port:    8080,
+				},
+				Upstream: &testStructPeer{
+					Service: "upstream",
+				},
+				Internal: "internal",
+				Untagged: "untagged",
+			},
+			tags: map[string]string{
+				"tenant":           "acme",
+				"request.id":       "id",
+				"request.token":    otexts.RedactedValue,
+				"request.retries":  "2",
+				"request.timeout":  "1s",
+				"span.kind":        "client",
+				"request.count":    "3",
+				"Default":          "true",
+				"peer.service":     "service",
+				"peer.ipv4":        "127.0.0.1",
+				"peer.port":        "8080",
+				"upstream.service": "upstream",
+			},
+		},
+		{
+			name: "empty fields",
+			v:    testStructRequest{},
+			tags: map[string]string{
+				"tenant":          "",
+				"request.id":      "",
+				"request.token":   otexts.RedactedValue,
+				"request.timeout": "0s",
+				"peer.service":    "",
+			},
+		},
+		{
+			name: "unexported embedded",
+			v: testStructPooled{testStructConn{
+				Addr:   net.IPv4(10, 0, 0, 1),
+				Idle:   time.Minute,
+				Port:   5432,
+				Kind:   ext.SpanKindRPCClientEnum,
+				Pooled: true,
+			}},
+			tags: map[string]string{
+				"conn.addr":   "10.0.0.1",
+				"conn.idle":   "1m0s",
+				"conn.port":   "5432",
+				"conn.kind":   "client",
+				"conn.pooled": "true",
+			},
+		},
+		{
+			name: "nil interfaces",
+			v:    testStructResult{ID: "x"},
+			tags: map[string]string{
+				"result.id": "x",
+			},
+		},
+		{
+			name: "interfaces",
+			v:    testStructResult{ID: "x", Err: errors.New("failed"), Status: time.Second},
+			tags: map[string]string{
+				"result.id":     "x",
+				"result.error":  "failed",
+				"result.status": "1s",
+			},
+		},
+		{
+			name: "nil",
+			v:    (*testStructRequest)(nil),
+			tags: map[string]string{},
+		},
+		{
+			name: "not a struct",
+			v:    "foo",
+			tags: map[string]string{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := opentracing.StartSpan("test", otexts.TagsFromStruct(tc.v)).(*mocktracer.MockSpan)
+			span.Finish()
+			ensureTagsSet(t, tc.tags, span.Tags())
+
+			span = opentracing.StartSpan("test").(*mocktracer.MockSpan)
+			otexts.SetTagsFromStruct(span, tc.v)
+			span.Finish()
+			ensureTagsSet(t, tc.tags, span.Tags())
+		})
+	}
+}
+
+func TestTagsFromStruct_types(t *testing.T) {
+	span := opentracing.StartSpan("test", otexts.TagsFromStruct(testStructRequest{
+		Retries: 2,
+		Kind:    ext.SpanKindRPCServerEnum,
+		Peer:    testStructPeer{Port: 8080},
+	})).(*mocktracer.MockSpan)
+	span.Finish()
+
+	if got, ok := span.Tag("request.retries").(int); !ok || got != 2 {
+		t.Errorf("tag %q: got %#v, want int 2", "request.retries", span.Tag("request.retries"))
+	}
+	if got, ok := span.Tag("span.kind").(string); !ok || got != "server" {
+		t.Errorf("tag %q: got %#v, want string %q", "span.kind", span.Tag("span.kind"), "server")
+	}
+	if got, ok := span.Tag("peer.port").(uint16); !ok || got != 8080 {
+		t.Errorf("tag %q: got %#v, want uint16 8080", "peer.port", span.Tag("peer.port"))
+	}
+
+	span = opentracing.StartSpan("test", otexts.TagsFromStruct(testStructPooled{testStructConn{Port: 5432}})).(*mocktracer.MockSpan)
+	span.Finish()
+	if got, ok := span.Tag("conn.port").(uint16); !ok || got != 5432 {
+		t.Errorf("tag %q: got %#v, want uint16 5432", "conn.port", span.Tag("conn.port"))
+	}
+}
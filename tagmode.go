@@ -0,0 +1,142 @@
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"sync/atomic"
+)
+
+// TagMode selects the tag names set by the tag option types, e.g. RPCTags,
+// DBTags, and HTTPTags: the opentracing tag names, the OpenTelemetry
+// attribute names, or both, e.g. while dashboards are migrated to
+// OpenTelemetry. The tags without an OpenTelemetry attribute name, e.g.
+// "span.kind", are set with their opentracing tag name in every mode.
+type TagMode int32
+
+// Tag modes.
+const (
+	TagModeDefault     TagMode = iota // The global tag mode, see SetTagMode.
+	TagModeOpenTracing                // The opentracing tag names.
+	TagModeOTel                       // The OpenTelemetry attribute names.
+	TagModeBoth                       // Both the opentracing and OpenTelemetry names.
+)
+
+// String returns the tag mode name.
+func (m TagMode) String() string {
+	switch m {
+	case TagModeDefault:
+		return "default"
+	case TagModeOpenTracing:
+		return "opentracing"
+	case TagModeOTel:
+		return "otel"
+	case TagModeBoth:
+		return "both"
+	}
+	return fmt.Sprintf("TagMode(%d)", int32(m))
+}
+
+// keys returns the names of the specified opentracing tag selected by the tag
+// mode, resolving TagModeDefault to the global tag mode.
+func (m TagMode) keys(key string) []string {
+	if m == TagModeDefault {
+		m = GetTagMode()
+	}
+	otel, ok := otelTagKeys[key]
+	if !ok {
+		return []string{key}
+	}
+	switch m {
+	case TagModeOTel:
+		return []string{otel}
+	case TagModeBoth:
+		return []string{key, otel}
+	}
+	return []string{key}
+}
+
+// keysWithoutOTel returns the names of the specified opentracing tag selected
+// by the tag mode, like keys, without its OpenTelemetry attribute name if
+// without is true, e.g. if the attribute is set by a tag with precedence.
+func (m TagMode) keysWithoutOTel(key string, without bool) []string {
+	keys := m.keys(key)
+	if !without {
+		return keys
+	}
+	filtered := keys[:0:0]
+	for _, k := range keys {
+		if k != otelTagKeys[key] {
+			filtered = append(filtered, k)
+		}
+	}
+	return filtered
+}
+
+// openTracingTagKeys returns the sorted opentracing tag names whose
+// OpenTelemetry attribute name is the specified name, if any.
+func openTracingTagKeys(otel string) []string {
+	var keys []string
+	for k, v := range otelTagKeys {
+		if v == otel {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookupTag returns the value of the specified tag, matching the tags set in
+// every tag mode: if the tag is not set, the value of its OpenTelemetry
+// attribute name, or of the opentracing tag names of an OpenTelemetry
+// attribute name, is returned.
+func lookupTag(tags map[string]interface{}, key string) (interface{}, bool) {
+	if v, ok := tags[key]; ok {
+		return v, true
+	}
+	if otel, ok := otelTagKeys[key]; ok {
+		v, ok := tags[otel]
+		return v, ok
+	}
+	for _, k := range openTracingTagKeys(key) {
+		if v, ok := tags[k]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// tagMode is the global tag mode.
+var tagMode atomic.Int32
+
+// GetTagMode returns the global tag mode, used by the tag option types whose
+// Mode is TagModeDefault.
+func GetTagMode() TagMode {
+	if m := TagMode(tagMode.Load()); m != TagModeDefault {
+		return m
+	}
+	return TagModeOpenTracing
+}
+
+// SetTagMode sets the global tag mode. The default is TagModeOpenTracing.
+func SetTagMode(m TagMode) {
+	tagMode.Store(int32(m))
+}
+
+// OTelTagKey returns the OpenTelemetry attribute name of the specified
+// standard opentracing tag name set by the tag option types, and whether it
+// has one.
+func OTelTagKey(key string) (string, bool) {
+	otel, ok := otelTagKeys[key]
+	return otel, ok
+}
+
+// OTelTagKeys returns a new map of the OpenTelemetry attribute names of the
+// standard opentracing tag names, e.g. set by the tag option types, generated
+// from the tags.json spec.
+func OTelTagKeys() map[string]string {
+	keys := make(map[string]string, len(otelTagKeys))
+	for k, v := range otelTagKeys {
+		keys[k] = v
+	}
+	return keys
+}
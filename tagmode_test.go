@@ -0,0 +1,83 @@
+package trace_test
+
+import (
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestSetTagMode(t *testing.T) {
+	defer otexts.SetTagMode(otexts.TagModeDefault)
+
+	tt := []struct {
+		name   string
+		global otexts.TagMode
+		mode   otexts.TagMode
+		want   map[string]string
+	}{
+		{
+			name:   "default",
+			global: otexts.TagModeDefault,
+			want:   map[string]string{"http.url": "https://example.com"},
+		},
+		{
+			name:   "global OpenTelemetry",
+			global: otexts.TagModeOTel,
+			want:   map[string]string{"url.full": "https://example.com"},
+		},
+		{
+			name:   "global both",
+			global: otexts.TagModeBoth,
+			want:   map[string]string{"http.url": "https://example.com", "url.full": "https://example.com"},
+		},
+		{
+			name:   "option overrides global",
+			global: otexts.TagModeBoth,
+			mode:   otexts.TagModeOpenTracing,
+			want:   map[string]string{"http.url": "https://example.com"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			otexts.SetTagMode(tc.global)
+			tags := otexts.HTTPTags{URL: "https://example.com", Mode: tc.mode}
+
+			span := mocktracer.New().StartSpan("test", tags).(*mocktracer.MockSpan)
+			if got, want := len(span.Tags()), len(tc.want); got != want {
+				t.Errorf("tags: got %d, want %d: %v", got, want, span.Tags())
+			}
+			ensureTagsSet(t, tc.want, span.Tags())
+
+			span = mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+			otexts.SetHTTPTags(span, tags)
+			ensureTagsSet(t, tc.want, span.Tags())
+		})
+	}
+
+	otexts.SetTagMode(otexts.TagModeDefault)
+	if got, want := otexts.GetTagMode(), otexts.TagModeOpenTracing; got != want {
+		t.Errorf("default tag mode: got %v, want %v", got, want)
+	}
+}
+
+func TestOTelTagKey(t *testing.T) {
+	if got, ok := otexts.OTelTagKey("db.statement"); !ok || got != "db.query.text" {
+		t.Errorf("db.statement: got %q, %v, want %q", got, ok, "db.query.text")
+	}
+	if _, ok := otexts.OTelTagKey("span.kind"); ok {
+		t.Errorf("span.kind has an OpenTelemetry attribute name")
+	}
+	keys := otexts.OTelTagKeys()
+	keys["db.statement"] = "modified"
+	if got, _ := otexts.OTelTagKey("db.statement"); got != "db.query.text" {
+		t.Errorf("OTelTagKeys returned the package map")
+	}
+
+	violations := otexts.Validator{Strict: true}.Validate(map[string]interface{}{"url.full": "https://example.com"}, nil)
+	if got, want := len(violations), 0; got != want {
+		t.Errorf("strict validation violations: got %d, want %d: %v", got, want, violations)
+	}
+}
@@ -0,0 +1,348 @@
+// Code generated by tagsgen from tags.json. DO NOT EDIT.
+
+package trace
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// peerTags are the standard peer tags.
+type peerTags struct {
+	Addr     string // The remote address.
+	Hostname string // The remote hostname.
+	IPv4     net.IP // The remote IPv4 address.
+	IPv6     net.IP // The remote IPv6 address.
+	Port     uint16 // The remote port.
+	Service  string // The remote service name.
+}
+
+// apply sets the standard peer tags in the specified span options, with the
+// specified tag mode.
+func (t peerTags) apply(opts *opentracing.StartSpanOptions, mode TagMode) {
+	if opts == nil {
+		return
+	}
+	if opts.Tags == nil {
+		opts.Tags = make(map[string]interface{})
+	}
+	if t.Addr != "" {
+		opts.Tags[string(ext.PeerAddress)] = t.Addr
+	}
+	if t.Hostname != "" {
+		for _, k := range mode.keys(string(ext.PeerHostname)) {
+			opts.Tags[k] = t.Hostname
+		}
+	}
+	if t.IPv4 != nil {
+		for _, k := range mode.keys(string(ext.PeerHostIPv4)) {
+			opts.Tags[k] = t.IPv4.String()
+		}
+	}
+	if t.IPv6 != nil {
+		for _, k := range mode.keysWithoutOTel(string(ext.PeerHostIPv6), t.IPv4 != nil) {
+			opts.Tags[k] = t.IPv6.String()
+		}
+	}
+	if t.Port > 0 {
+		for _, k := range mode.keys(string(ext.PeerPort)) {
+			opts.Tags[k] = t.Port
+		}
+	}
+	if t.Service != "" {
+		opts.Tags[string(ext.PeerService)] = t.Service
+	}
+}
+
+// set sets the standard peer tags on the specified span, with the specified tag
+// mode.
+func (t peerTags) set(span opentracing.Span, mode TagMode) {
+	if span == nil {
+		return
+	}
+	if t.Addr != "" {
+		span.SetTag(string(ext.PeerAddress), t.Addr)
+	}
+	if t.Hostname != "" {
+		for _, k := range mode.keys(string(ext.PeerHostname)) {
+			span.SetTag(k, t.Hostname)
+		}
+	}
+	if t.IPv4 != nil {
+		for _, k := range mode.keys(string(ext.PeerHostIPv4)) {
+			span.SetTag(k, t.IPv4.String())
+		}
+	}
+	if t.IPv6 != nil {
+		for _, k := range mode.keysWithoutOTel(string(ext.PeerHostIPv6), t.IPv4 != nil) {
+			span.SetTag(k, t.IPv6.String())
+		}
+	}
+	if t.Port > 0 {
+		for _, k := range mode.keys(string(ext.PeerPort)) {
+			span.SetTag(k, t.Port)
+		}
+	}
+	if t.Service != "" {
+		span.SetTag(string(ext.PeerService), t.Service)
+	}
+}
+
+// Ensure RPCTags implements the opentracing.StartSpanOption interface.
+var _ opentracing.StartSpanOption = (*RPCTags)(nil)
+
+// RPCTags is an opentracing.StartSpanOption that sets the standard RPC tags.
+//
+// See https://github.com/opentracing/specification/blob/master/semantic_conventions.md#rpcs.
+type RPCTags struct {
+	Kind ext.SpanKindEnum // The span kind, "client" or "server".
+
+	// Optional tags that describe the RPC peer.
+	PeerAddr     string // The remote address.
+	PeerHostname string // The remote hostname.
+	PeerIPv4     net.IP // The remote IPv4 address.
+	PeerIPv6     net.IP // The remote IPv6 address.
+	PeerPort     uint16 // The remote port.
+	PeerService  string // The remote service name.
+
+	// Mode selects the opentracing tag names, the OpenTelemetry attribute names,
+	// or both. The zero value uses the global tag mode, see SetTagMode.
+	Mode TagMode
+}
+
+// Apply implements the opentracing.StartSpanOption interface.
+func (t RPCTags) Apply(opts *opentracing.StartSpanOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.Tags == nil {
+		opts.Tags = make(map[string]interface{})
+	}
+	if t.Kind == "client" || t.Kind == "server" {
+		opts.Tags[string(ext.SpanKind)] = string(t.Kind)
+	}
+	peerTags{
+		Addr:     t.PeerAddr,
+		Hostname: t.PeerHostname,
+		IPv4:     t.PeerIPv4,
+		IPv6:     t.PeerIPv6,
+		Port:     t.PeerPort,
+		Service:  t.PeerService,
+	}.apply(opts, t.Mode)
+}
+
+// SetRPCTags sets the standard RPC tags on the specified span.
+func SetRPCTags(span opentracing.Span, t RPCTags) {
+	if span == nil {
+		return
+	}
+	if t.Kind == "client" || t.Kind == "server" {
+		span.SetTag(string(ext.SpanKind), t.Kind)
+	}
+	peerTags{
+		Addr:     t.PeerAddr,
+		Hostname: t.PeerHostname,
+		IPv4:     t.PeerIPv4,
+		IPv6:     t.PeerIPv6,
+		Port:     t.PeerPort,
+		Service:  t.PeerService,
+	}.set(span, t.Mode)
+}
+
+// SetRPCTagsCtx calls SetRPCTags with the span from the specified context, if
+// any.
+func SetRPCTagsCtx(ctx context.Context, t RPCTags) {
+	SetRPCTags(opentracing.SpanFromContext(ctx), t)
+}
+
+// Ensure DBTags implements the opentracing.StartSpanOption interface.
+var _ opentracing.StartSpanOption = (*DBTags)(nil)
+
+// DBTags is an opentracing.StartSpanOption that sets the standard database tags
+// for a database client call.
+//
+// See https://github.com/opentracing/specification/blob/master/semantic_conventions.md#database-client-calls.
+type DBTags struct {
+	Type      string // The database type.
+	Instance  string // The database instance name.
+	User      string // The username of the database accessor.
+	Statement string // The database statement used.
+
+	// Optional tags that describe the database peer.
+	PeerAddr     string // The remote address.
+	PeerHostname string // The remote hostname.
+	PeerIPv4     net.IP // The remote IPv4 address.
+	PeerIPv6     net.IP // The remote IPv6 address.
+	PeerPort     uint16 // The remote port.
+	PeerService  string // The remote service name.
+
+	// Mode selects the opentracing tag names, the OpenTelemetry attribute names,
+	// or both. The zero value uses the global tag mode, see SetTagMode.
+	Mode TagMode
+}
+
+// Apply implements the opentracing.StartSpanOption interface.
+func (t DBTags) Apply(opts *opentracing.StartSpanOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.Tags == nil {
+		opts.Tags = make(map[string]interface{})
+	}
+	opts.Tags[string(ext.SpanKind)] = ext.SpanKindEnum("client")
+	if t.Type != "" {
+		for _, k := range t.Mode.keys(string(ext.DBType)) {
+			opts.Tags[k] = strings.ToLower(t.Type)
+		}
+	}
+	if t.Instance != "" {
+		for _, k := range t.Mode.keys(string(ext.DBInstance)) {
+			opts.Tags[k] = t.Instance
+		}
+	}
+	if t.User != "" {
+		opts.Tags[string(ext.DBUser)] = t.User
+	}
+	if t.Statement != "" {
+		for _, k := range t.Mode.keys(string(ext.DBStatement)) {
+			opts.Tags[k] = t.Statement
+		}
+	}
+	peerTags{
+		Addr:     t.PeerAddr,
+		Hostname: t.PeerHostname,
+		IPv4:     t.PeerIPv4,
+		IPv6:     t.PeerIPv6,
+		Port:     t.PeerPort,
+		Service:  t.PeerService,
+	}.apply(opts, t.Mode)
+}
+
+// SetDBTags sets the standard database tags on the specified span.
+func SetDBTags(span opentracing.Span, t DBTags) {
+	if span == nil {
+		return
+	}
+	span.SetTag(string(ext.SpanKind), ext.SpanKindEnum("client"))
+	if t.Type != "" {
+		for _, k := range t.Mode.keys(string(ext.DBType)) {
+			span.SetTag(k, strings.ToLower(t.Type))
+		}
+	}
+	if t.Instance != "" {
+		for _, k := range t.Mode.keys(string(ext.DBInstance)) {
+			span.SetTag(k, t.Instance)
+		}
+	}
+	if t.User != "" {
+		span.SetTag(string(ext.DBUser), t.User)
+	}
+	if t.Statement != "" {
+		for _, k := range t.Mode.keys(string(ext.DBStatement)) {
+			span.SetTag(k, t.Statement)
+		}
+	}
+	peerTags{
+		Addr:     t.PeerAddr,
+		Hostname: t.PeerHostname,
+		IPv4:     t.PeerIPv4,
+		IPv6:     t.PeerIPv6,
+		Port:     t.PeerPort,
+		Service:  t.PeerService,
+	}.set(span, t.Mode)
+}
+
+// SetDBTagsCtx calls SetDBTags with the span from the specified context, if
+// any.
+func SetDBTagsCtx(ctx context.Context, t DBTags) {
+	SetDBTags(opentracing.SpanFromContext(ctx), t)
+}
+
+// Ensure HTTPTags implements the opentracing.StartSpanOption interface.
+var _ opentracing.StartSpanOption = (*HTTPTags)(nil)
+
+// HTTPTags is an opentracing.StartSpanOption that sets the standard HTTP tags.
+//
+// See https://github.com/opentracing/specification/blob/master/semantic_conventions.md#span-tags-table.
+type HTTPTags struct {
+	Method     string // The HTTP request method.
+	URL        string // The HTTP request URL.
+	StatusCode int    // The HTTP response status code.
+
+	// Mode selects the opentracing tag names, the OpenTelemetry attribute names,
+	// or both. The zero value uses the global tag mode, see SetTagMode.
+	Mode TagMode
+}
+
+// Apply implements the opentracing.StartSpanOption interface.
+func (t HTTPTags) Apply(opts *opentracing.StartSpanOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.Tags == nil {
+		opts.Tags = make(map[string]interface{})
+	}
+	if t.Method != "" {
+		for _, k := range t.Mode.keys(string(ext.HTTPMethod)) {
+			opts.Tags[k] = t.Method
+		}
+	}
+	if t.URL != "" {
+		for _, k := range t.Mode.keys(string(ext.HTTPUrl)) {
+			opts.Tags[k] = t.URL
+		}
+	}
+	if t.StatusCode > 0 {
+		for _, k := range t.Mode.keys(string(ext.HTTPStatusCode)) {
+			opts.Tags[k] = t.StatusCode
+		}
+	}
+}
+
+// SetHTTPTags sets the standard HTTP tags on the specified span.
+func SetHTTPTags(span opentracing.Span, t HTTPTags) {
+	if span == nil {
+		return
+	}
+	if t.Method != "" {
+		for _, k := range t.Mode.keys(string(ext.HTTPMethod)) {
+			span.SetTag(k, t.Method)
+		}
+	}
+	if t.URL != "" {
+		for _, k := range t.Mode.keys(string(ext.HTTPUrl)) {
+			span.SetTag(k, t.URL)
+		}
+	}
+	if t.StatusCode > 0 {
+		for _, k := range t.Mode.keys(string(ext.HTTPStatusCode)) {
+			span.SetTag(k, uint16(t.StatusCode))
+		}
+	}
+}
+
+// SetHTTPTagsCtx calls SetHTTPTags with the span from the specified context, if
+// any.
+func SetHTTPTagsCtx(ctx context.Context, t HTTPTags) {
+	SetHTTPTags(opentracing.SpanFromContext(ctx), t)
+}
+
+// otelTagKeys are the OpenTelemetry attribute names of the standard tags, by
+// tag name.
+var otelTagKeys = map[string]string{
+	string(ext.PeerHostname):          "server.address",
+	string(ext.PeerHostIPv4):          "network.peer.address",
+	string(ext.PeerHostIPv6):          "network.peer.address",
+	string(ext.PeerPort):              "server.port",
+	string(ext.DBType):                "db.system",
+	string(ext.DBInstance):            "db.namespace",
+	string(ext.DBStatement):           "db.query.text",
+	string(ext.HTTPMethod):            "http.request.method",
+	string(ext.HTTPUrl):               "url.full",
+	string(ext.HTTPStatusCode):        "http.response.status_code",
+	string(ext.MessageBusDestination): "messaging.destination.name",
+}
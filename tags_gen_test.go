@@ -0,0 +1,311 @@
+// Code generated by tagsgen from tags.json. DO NOT EDIT.
+
+package trace_test
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestRPCTags_spec(t *testing.T) {
+	tt := []struct {
+		name string
+		tags otexts.RPCTags
+		want map[string]string
+	}{
+		{
+			name: "no tags",
+			want: map[string]string{},
+		},
+		{
+			name: "all tags",
+			tags: otexts.RPCTags{
+				Kind:         "client",
+				PeerAddr:     "test",
+				PeerHostname: "test",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerIPv6:     net.IPv6loopback,
+				PeerPort:     8080,
+				PeerService:  "test",
+			},
+			want: map[string]string{
+				"peer.address":  "test",
+				"peer.hostname": "test",
+				"peer.ipv4":     "127.0.0.1",
+				"peer.ipv6":     "::1",
+				"peer.port":     "8080",
+				"peer.service":  "test",
+				"span.kind":     "client",
+			},
+		},
+		{
+			name: "all tags OpenTelemetry",
+			tags: otexts.RPCTags{
+				Kind:         "client",
+				PeerAddr:     "test",
+				PeerHostname: "test",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerIPv6:     net.IPv6loopback,
+				PeerPort:     8080,
+				PeerService:  "test",
+				Mode:         otexts.TagModeOTel,
+			},
+			want: map[string]string{
+				"network.peer.address": "127.0.0.1",
+				"peer.address":         "test",
+				"peer.service":         "test",
+				"server.address":       "test",
+				"server.port":          "8080",
+				"span.kind":            "client",
+			},
+		},
+		{
+			name: "all tags both",
+			tags: otexts.RPCTags{
+				Kind:         "client",
+				PeerAddr:     "test",
+				PeerHostname: "test",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerIPv6:     net.IPv6loopback,
+				PeerPort:     8080,
+				PeerService:  "test",
+				Mode:         otexts.TagModeBoth,
+			},
+			want: map[string]string{
+				"network.peer.address": "127.0.0.1",
+				"peer.address":         "test",
+				"peer.hostname":        "test",
+				"peer.ipv4":            "127.0.0.1",
+				"peer.ipv6":            "::1",
+				"peer.port":            "8080",
+				"peer.service":         "test",
+				"server.address":       "test",
+				"server.port":          "8080",
+				"span.kind":            "client",
+			},
+		},
+		{
+			name: "invalid Kind",
+			tags: otexts.RPCTags{
+				Kind: "invalid",
+			},
+			want: map[string]string{},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("test", tc.tags).(*mocktracer.MockSpan)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+
+			span = mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+			otexts.SetRPCTags(span, tc.tags)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+		})
+	}
+}
+
+func TestDBTags_spec(t *testing.T) {
+	tt := []struct {
+		name string
+		tags otexts.DBTags
+		want map[string]string
+	}{
+		{
+			name: "no tags",
+			want: map[string]string{
+				"span.kind": "client",
+			},
+		},
+		{
+			name: "all tags",
+			tags: otexts.DBTags{
+				Type:         "TEST",
+				Instance:     "test",
+				User:         "test",
+				Statement:    "test",
+				PeerAddr:     "test",
+				PeerHostname: "test",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerIPv6:     net.IPv6loopback,
+				PeerPort:     8080,
+				PeerService:  "test",
+			},
+			want: map[string]string{
+				"db.instance":   "test",
+				"db.statement":  "test",
+				"db.type":       "test",
+				"db.user":       "test",
+				"peer.address":  "test",
+				"peer.hostname": "test",
+				"peer.ipv4":     "127.0.0.1",
+				"peer.ipv6":     "::1",
+				"peer.port":     "8080",
+				"peer.service":  "test",
+				"span.kind":     "client",
+			},
+		},
+		{
+			name: "all tags OpenTelemetry",
+			tags: otexts.DBTags{
+				Type:         "TEST",
+				Instance:     "test",
+				User:         "test",
+				Statement:    "test",
+				PeerAddr:     "test",
+				PeerHostname: "test",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerIPv6:     net.IPv6loopback,
+				PeerPort:     8080,
+				PeerService:  "test",
+				Mode:         otexts.TagModeOTel,
+			},
+			want: map[string]string{
+				"db.namespace":         "test",
+				"db.query.text":        "test",
+				"db.system":            "test",
+				"db.user":              "test",
+				"network.peer.address": "127.0.0.1",
+				"peer.address":         "test",
+				"peer.service":         "test",
+				"server.address":       "test",
+				"server.port":          "8080",
+				"span.kind":            "client",
+			},
+		},
+		{
+			name: "all tags both",
+			tags: otexts.DBTags{
+				Type:         "TEST",
+				Instance:     "test",
+				User:         "test",
+				Statement:    "test",
+				PeerAddr:     "test",
+				PeerHostname: "test",
+				PeerIPv4:     net.IPv4(127, 0, 0, 1),
+				PeerIPv6:     net.IPv6loopback,
+				PeerPort:     8080,
+				PeerService:  "test",
+				Mode:         otexts.TagModeBoth,
+			},
+			want: map[string]string{
+				"db.instance":          "test",
+				"db.namespace":         "test",
+				"db.query.text":        "test",
+				"db.statement":         "test",
+				"db.system":            "test",
+				"db.type":              "test",
+				"db.user":              "test",
+				"network.peer.address": "127.0.0.1",
+				"peer.address":         "test",
+				"peer.hostname":        "test",
+				"peer.ipv4":            "127.0.0.1",
+				"peer.ipv6":            "::1",
+				"peer.port":            "8080",
+				"peer.service":         "test",
+				"server.address":       "test",
+				"server.port":          "8080",
+				"span.kind":            "client",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("test", tc.tags).(*mocktracer.MockSpan)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+
+			span = mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+			otexts.SetDBTags(span, tc.tags)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+		})
+	}
+}
+
+func TestHTTPTags_spec(t *testing.T) {
+	tt := []struct {
+		name string
+		tags otexts.HTTPTags
+		want map[string]string
+	}{
+		{
+			name: "no tags",
+			want: map[string]string{},
+		},
+		{
+			name: "all tags",
+			tags: otexts.HTTPTags{
+				Method:     "test",
+				URL:        "test",
+				StatusCode: 200,
+			},
+			want: map[string]string{
+				"http.method":      "test",
+				"http.status_code": "200",
+				"http.url":         "test",
+			},
+		},
+		{
+			name: "all tags OpenTelemetry",
+			tags: otexts.HTTPTags{
+				Method:     "test",
+				URL:        "test",
+				StatusCode: 200,
+				Mode:       otexts.TagModeOTel,
+			},
+			want: map[string]string{
+				"http.request.method":       "test",
+				"http.response.status_code": "200",
+				"url.full":                  "test",
+			},
+		},
+		{
+			name: "all tags both",
+			tags: otexts.HTTPTags{
+				Method:     "test",
+				URL:        "test",
+				StatusCode: 200,
+				Mode:       otexts.TagModeBoth,
+			},
+			want: map[string]string{
+				"http.method":               "test",
+				"http.request.method":       "test",
+				"http.response.status_code": "200",
+				"http.status_code":          "200",
+				"http.url":                  "test",
+				"url.full":                  "test",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			span := mocktracer.New().StartSpan("test", tc.tags).(*mocktracer.MockSpan)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+
+			span = mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+			otexts.SetHTTPTags(span, tc.tags)
+			span.Finish()
+			checkSpecTags(t, tc.want, span.Tags())
+		})
+	}
+}
+
+func checkSpecTags(t *testing.T, want map[string]string, tags map[string]interface{}) {
+	t.Helper()
+	if got, want := len(tags), len(want); got != want {
+		t.Errorf("tags: got %d, want %d: %v", got, want, tags)
+	}
+	for k, v := range want {
+		if got := fmt.Sprint(tags[k]); got != v {
+			t.Errorf("tag %q: got %q, want %q", k, got, v)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package trace_test
 
 import (
+	"context"
 	"net"
 	"net/http"
 	"testing"
@@ -403,3 +404,41 @@ func ensurePeerTagsSet(t *testing.T, tags map[string]interface{}, addr, hostname
 		}
 	}
 }
+
+func TestSetTagsCtx(t *testing.T) {
+	rpcTags := otexts.RPCTags{
+		Kind:        ext.SpanKindRPCServerEnum,
+		PeerIPv4:    net.IPv4(127, 0, 0, 1),
+		PeerIPv6:    net.IPv6loopback,
+		PeerService: "service",
+	}
+	dbTags := otexts.DBTags{
+		Type:     "sql",
+		Instance: "test",
+		PeerIPv4: net.IPv4(127, 0, 0, 1),
+		PeerIPv6: net.IPv6loopback,
+	}
+	httpTags := otexts.HTTPTags{
+		Method:     http.MethodGet,
+		URL:        "http://example.com/",
+		StatusCode: http.StatusOK,
+	}
+
+	// Must not panic without a span in the context.
+	otexts.SetRPCTagsCtx(context.Background(), rpcTags)
+	otexts.SetDBTagsCtx(context.Background(), dbTags)
+	otexts.SetHTTPTagsCtx(context.Background(), httpTags)
+
+	span := opentracing.StartSpan("test").(*mocktracer.MockSpan)
+	ctx := opentracing.ContextWithSpan(context.Background(), span)
+	otexts.SetRPCTagsCtx(ctx, rpcTags)
+	otexts.SetHTTPTagsCtx(ctx, httpTags)
+	span.Finish()
+	ensureRPCTagsSet(t, rpcTags, span.Tags())
+	ensureHTTPTagsSet(t, httpTags, span.Tags())
+
+	span = opentracing.StartSpan("test").(*mocktracer.MockSpan)
+	otexts.SetDBTagsCtx(opentracing.ContextWithSpan(context.Background(), span), dbTags)
+	span.Finish()
+	ensureDBTagsSet(t, dbTags, span.Tags())
+}
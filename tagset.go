@@ -0,0 +1,205 @@
+package trace
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+// TagConflictPolicy determines the value of a tag that is set by more than one
+// of the tags in a TagSet.
+type TagConflictPolicy int
+
+// Tag conflict policies.
+const (
+	// TagConflictLast uses the value of the last tags that set the tag, the
+	// same as passing the tags as separate span options.
+	TagConflictLast TagConflictPolicy = iota
+
+	// TagConflictFirst uses the value of the first tags that set the tag.
+	TagConflictFirst
+
+	// TagConflictExplicit uses the value of the last opentracing.Tags or
+	// opentracing.Tag that set the tag, if any, otherwise the value of the
+	// last tags that set the tag. This allows plain tags to override the tags
+	// implied by a tag struct, e.g. the "span.kind" tag set by DBTags.
+	TagConflictExplicit
+)
+
+// Ensure TagSet implements the opentracing.StartSpanOption interface.
+var _ opentracing.StartSpanOption = TagSet{}
+
+// TagSet is an opentracing.StartSpanOption that sets the merged tags of
+// multiple tag structs, e.g. RPCTags, DBTags, and HTTPTags, and plain
+// opentracing.Tags and opentracing.Tag, resolving tags set by more than one of
+// them with the conflict policy.
+//
+// Only the tags set by each option are used, any other span options they set
+// are ignored. Nil options and nil pointers, e.g. a nil *HTTPTags, set no
+// tags.
+type TagSet struct {
+	Tags   []opentracing.StartSpanOption // The tags to merge, in order.
+	Policy TagConflictPolicy             // The tag conflict policy.
+}
+
+// NewTagSet returns a new TagSet of the specified tags using the
+// TagConflictLast policy.
+func NewTagSet(tags ...opentracing.StartSpanOption) TagSet {
+	return TagSet{Tags: tags}
+}
+
+// Merge returns the merged tags of the tag set.
+func (s TagSet) Merge() opentracing.Tags {
+	return s.merge(tagsOf)
+}
+
+// merge returns the merged tags of the tag set, using the specified function
+// to get the tags set by each option.
+func (s TagSet) merge(tagsOf func(opentracing.StartSpanOption) map[string]interface{}) opentracing.Tags {
+	merged := make(opentracing.Tags)
+	explicit := make(map[string]bool)
+	for _, t := range s.Tags {
+		isExplicit := isExplicitTags(t)
+		for k, v := range tagsOf(t) {
+			if _, ok := merged[k]; ok {
+				switch s.Policy {
+				case TagConflictFirst:
+					continue
+				case TagConflictExplicit:
+					if explicit[k] && !isExplicit {
+						continue
+					}
+				}
+			}
+			merged[k] = v
+			explicit[k] = isExplicit
+		}
+	}
+	return merged
+}
+
+// Conflicts returns the sorted keys of the tags that are set to different
+// values by more than one of the tags in the tag set.
+func (s TagSet) Conflicts() []string {
+	seen := make(map[string]string)
+	conflicts := make(map[string]bool)
+	for _, t := range s.Tags {
+		for k, v := range tagsOf(t) {
+			str := fmt.Sprint(v)
+			if prev, ok := seen[k]; ok && prev != str {
+				conflicts[k] = true
+			}
+			seen[k] = str
+		}
+	}
+	keys := make([]string, 0, len(conflicts))
+	for k := range conflicts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Apply implements the opentracing.StartSpanOption interface.
+func (s TagSet) Apply(opts *opentracing.StartSpanOptions) {
+	if opts == nil {
+		return
+	}
+	if opts.Tags == nil {
+		opts.Tags = make(map[string]interface{})
+	}
+	for k, v := range s.Merge() {
+		opts.Tags[k] = v
+	}
+}
+
+// SetTagSet sets the merged tags of the specified tag set on the specified
+// span. The tags of RPCTags, DBTags, and HTTPTags are those set by SetRPCTags,
+// SetDBTags, and SetHTTPTags, e.g. the "http.status_code" tag is a uint16.
+func SetTagSet(span opentracing.Span, s TagSet) {
+	if span == nil {
+		return
+	}
+	for k, v := range s.merge(setTagsOf) {
+		span.SetTag(k, v)
+	}
+}
+
+// SetTagSetCtx calls SetTagSet with the span from the specified context, if
+// any.
+func SetTagSetCtx(ctx context.Context, s TagSet) {
+	SetTagSet(opentracing.SpanFromContext(ctx), s)
+}
+
+// isNilOption reports whether the specified span option is nil or a nil
+// pointer, e.g. a nil *HTTPTags, which sets no tags.
+func isNilOption(opt opentracing.StartSpanOption) bool {
+	if opt == nil {
+		return true
+	}
+	v := reflect.ValueOf(opt)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// tagsOf returns the tags set by the specified span option.
+func tagsOf(opt opentracing.StartSpanOption) map[string]interface{} {
+	if isNilOption(opt) {
+		return nil
+	}
+	var opts opentracing.StartSpanOptions
+	opt.Apply(&opts)
+	return opts.Tags
+}
+
+// setTagsOf returns the tags set on a span by the setter of the specified
+// span option, e.g. SetHTTPTags for HTTPTags or SetTagSet for a nested
+// TagSet, or else the tags it sets as a span option.
+func setTagsOf(opt opentracing.StartSpanOption) map[string]interface{} {
+	if isNilOption(opt) {
+		return nil
+	}
+	r := &tagRecorder{Span: opentracing.NoopTracer{}.StartSpan(""), tags: make(map[string]interface{})}
+	switch t := opt.(type) {
+	case RPCTags:
+		SetRPCTags(r, t)
+	case *RPCTags:
+		SetRPCTags(r, *t)
+	case DBTags:
+		SetDBTags(r, t)
+	case *DBTags:
+		SetDBTags(r, *t)
+	case HTTPTags:
+		SetHTTPTags(r, t)
+	case *HTTPTags:
+		SetHTTPTags(r, *t)
+	case TagSet:
+		return t.merge(setTagsOf)
+	default:
+		return tagsOf(opt)
+	}
+	return r.tags
+}
+
+// tagRecorder is a no-op span that records the tags set on it.
+type tagRecorder struct {
+	opentracing.Span
+	tags map[string]interface{}
+}
+
+// SetTag implements the opentracing.Span interface.
+func (r *tagRecorder) SetTag(key string, value interface{}) opentracing.Span {
+	r.tags[key] = value
+	return r
+}
+
+// isExplicitTags reports whether the specified span option sets plain tags.
+func isExplicitTags(opt opentracing.StartSpanOption) bool {
+	switch opt.(type) {
+	case opentracing.Tags, opentracing.Tag:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package trace_test
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestTagSet(t *testing.T) {
+	dbTags := otexts.DBTags{
+		Type:        "redis",
+		PeerService: "cache",
+	}
+	rpcTags := otexts.RPCTags{
+		Kind:        ext.SpanKindRPCServerEnum,
+		PeerService: "service",
+	}
+	httpTags := otexts.HTTPTags{
+		Method: http.MethodGet,
+	}
+	tt := []struct {
+		name      string
+		set       otexts.TagSet
+		tags      map[string]string
+		conflicts []string
+	}{
+		{
+			name: "nil pointers",
+			set:  otexts.NewTagSet((*otexts.RPCTags)(nil), (*otexts.DBTags)(nil), (*otexts.HTTPTags)(nil), httpTags),
+			tags: map[string]string{
+				string(ext.HTTPMethod): http.MethodGet,
+			},
+		},
+		{
+			name: "no conflicts",
+			set:  otexts.NewTagSet(rpcTags, httpTags, opentracing.Tags{"foo": "bar"}),
+			tags: map[string]string{
+				string(ext.SpanKind):    "server",
+				string(ext.PeerService): "service",
+				string(ext.HTTPMethod):  http.MethodGet,
+				"foo":                   "bar",
+			},
+		},
+		{
+			name: "last",
+			set: otexts.NewTagSet(
+				opentracing.Tag{Key: string(ext.SpanKind), Value: "producer"},
+				dbTags,
+				rpcTags,
+			),
+			tags: map[string]string{
+				string(ext.SpanKind):    "server",
+				string(ext.DBType):      "redis",
+				string(ext.PeerService): "service",
+			},
+			conflicts: []string{string(ext.PeerService), string(ext.SpanKind)},
+		},
+		{
+			name: "first",
+			set: otexts.TagSet{
+				Tags:   []opentracing.StartSpanOption{dbTags, rpcTags},
+				Policy: otexts.TagConflictFirst,
+			},
+			tags: map[string]string{
+				string(ext.SpanKind):    "client",
+				string(ext.DBType):      "redis",
+				string(ext.PeerService): "cache",
+			},
+			conflicts: []string{string(ext.PeerService), string(ext.SpanKind)},
+		},
+		{
+			name: "explicit",
+			set: otexts.TagSet{
+				Tags: []opentracing.StartSpanOption{
+					opentracing.Tag{Key: string(ext.SpanKind), Value: "producer"},
+					dbTags,
+					rpcTags,
+				},
+				Policy: otexts.TagConflictExplicit,
+			},
+			tags: map[string]string{
+				string(ext.SpanKind):    "producer",
+				string(ext.DBType):      "redis",
+				string(ext.PeerService): "service",
+			},
+			conflicts: []string{string(ext.PeerService), string(ext.SpanKind)},
+		},
+		{
+			name: "nested",
+			set:  otexts.NewTagSet(otexts.NewTagSet(httpTags), opentracing.Tags{"foo": "bar"}),
+			tags: map[string]string{
+				string(ext.HTTPMethod): http.MethodGet,
+				"foo":                  "bar",
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got, want := tc.set.Conflicts(), tc.conflicts; len(got) != len(want) || (len(want) > 0 && !reflect.DeepEqual(got, want)) {
+				t.Errorf("conflicts: got %v, want %v", got, want)
+			}
+
+			span := opentracing.StartSpan("test", tc.set).(*mocktracer.MockSpan)
+			span.Finish()
+			ensureTagsSet(t, tc.tags, span.Tags())
+
+			span = opentracing.StartSpan("test").(*mocktracer.MockSpan)
+			otexts.SetTagSet(span, tc.set)
+			span.Finish()
+			ensureTagsSet(t, tc.tags, span.Tags())
+		})
+	}
+}
+
+func TestSetTagSetTypes(t *testing.T) {
+	set := otexts.NewTagSet(
+		otexts.NewTagSet(otexts.HTTPTags{StatusCode: http.StatusOK}),
+		otexts.DBTags{Type: "redis"},
+	)
+	span := opentracing.StartSpan("test").(*mocktracer.MockSpan)
+	otexts.SetTagSet(span, set)
+	span.Finish()
+
+	if got, want := span.Tag(string(ext.HTTPStatusCode)), uint16(http.StatusOK); got != want {
+		t.Errorf("tag %q: got %#v, want %#v", ext.HTTPStatusCode, got, want)
+	}
+	if got, want := span.Tag(string(ext.SpanKind)), ext.SpanKindRPCClientEnum; got != want {
+		t.Errorf("tag %q: got %#v, want %#v", ext.SpanKind, got, want)
+	}
+}
+
+func ensureTagsSet(t *testing.T, want map[string]string, tags map[string]interface{}) {
+	if got, want := len(tags), len(want); got != want {
+		t.Errorf("tags: got %d, want %d: %v", got, want, tags)
+	}
+	for k, v := range want {
+		if got := fmt.Sprint(tags[k]); got != v {
+			t.Errorf("tag %q: got %q, want %q", k, got, v)
+		}
+	}
+}
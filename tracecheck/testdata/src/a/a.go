@@ -0,0 +1,109 @@
+package a
+
+import (
+	"context"
+	"errors"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func finished() {
+	span := opentracing.StartSpan("op")
+	defer span.Finish()
+}
+
+func finishedFromContext(ctx context.Context) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "op")
+	defer span.Finish()
+	_ = ctx
+}
+
+func returned() opentracing.Span {
+	span := opentracing.StartSpan("op")
+	span.SetTag("custom", 1)
+	return span
+}
+
+func leaked(fail bool) error {
+	span := opentracing.StartSpan("op") // want "span is not finished on all paths"
+	span.SetTag("custom", 1)
+	otexts.LogError(span, errors.New("error"))
+	if fail {
+		return errors.New("error") // want "this return statement may be reached without finishing span, started on line 32"
+	}
+	span.Finish()
+	return nil
+}
+
+func leakedFromTracer(tracer opentracing.Tracer) {
+	var span = tracer.StartSpan("op") // want "span is not finished on all paths"
+	ext.Component.Set(span, "a")
+} // want "this return statement may be reached without finishing span, started on line 43"
+
+func discarded() {
+	_ = opentracing.StartSpan("op") // want "the span started by StartSpan is discarded and never finished"
+}
+
+func finishedInClosure() {
+	span := opentracing.StartSpan("op")
+	go func() {
+		defer span.Finish()
+	}()
+}
+
+type mockSpan struct {
+	opentracing.Span
+}
+
+func nilSpan(v interface{}) {
+	span, _ := v.(*mockSpan)
+	otexts.LogError(span, errors.New("error")) // want `span may be a nil \*mockSpan from the type assertion on line 63, which LogError does not treat as a nil span`
+
+	iface, _ := v.(opentracing.Span)
+	otexts.LogError(iface, errors.New("error"))
+
+	if checked, ok := v.(*mockSpan); ok {
+		otexts.LogError(checked, errors.New("error"))
+	}
+}
+
+func tagKeys(span opentracing.Span) {
+	span.SetTag("component", "a") // want `use ext.Component instead of the tag name string literal "component"`
+	span.SetTag(string(ext.Component), "a")
+	span.SetTag("custom", "a")
+	opentracing.StartSpan("op",
+		opentracing.Tag{Key: "error", Value: true}, // want `use ext.Error instead of the tag name string literal "error"`
+		opentracing.Tag{"span.kind", "client"},     // want `use ext.SpanKind instead of the tag name string literal "span.kind"`
+		opentracing.Tags{
+			"http.url": "/", // want `use ext.HTTPUrl instead of the tag name string literal "http.url"`
+			"custom":   "a",
+		},
+	).Finish()
+}
+
+func logFieldKeys(span opentracing.Span) {
+	span.LogKV("event", "a", "message", "b") // want `use otexts.LogFieldEvent instead of the log field name string literal "event"` `use otexts.LogFieldMessage instead of the log field name string literal "message"`
+	span.LogKV(otexts.LogFieldEvent, "a", "custom", "event")
+	span.LogFields(
+		log.String("error.kind", "a"), // want `use otexts.LogFieldErrorKind instead of the log field name string literal "error.kind"`
+		log.String("custom", "event"),
+		log.Object(otexts.LogFieldMessage, "b"),
+	)
+}
+
+func reservedFields(ctx context.Context, span opentracing.Span, err error) {
+	otexts.LogErrorWithFields(span, err, map[string]interface{}{
+		"event":  "a", // want `log field "event" is reserved and ignored by LogErrorWithFields`
+		"custom": "b",
+	})
+	otexts.LogErrorWithFieldsCtx(ctx, err, otexts.LogFields{
+		otexts.LogFieldMessage: "a", // want `log field "message" is reserved and ignored by LogErrorWithFieldsCtx`
+	}.Encode())
+	otexts.LogEvent(span, "a", "b", map[string]interface{}{
+		"error.kind": "c",
+	})
+}
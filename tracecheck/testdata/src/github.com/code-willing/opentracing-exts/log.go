@@ -0,0 +1,26 @@
+// Package trace is a stub of the opentracing-exts package used by the
+// tracecheck tests.
+package trace
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go"
+)
+
+const (
+	LogFieldEvent   = "event"
+	LogFieldMessage = "message"
+)
+
+type LogFields map[string]interface{}
+
+func (f LogFields) Encode() map[string]interface{} { return f }
+
+func LogError(span opentracing.Span, err error) {}
+
+func LogErrorWithFields(span opentracing.Span, err error, fields map[string]interface{}) {}
+
+func LogErrorWithFieldsCtx(ctx context.Context, err error, fields map[string]interface{}) {}
+
+func LogEvent(span opentracing.Span, event, message string, fields map[string]interface{}) {}
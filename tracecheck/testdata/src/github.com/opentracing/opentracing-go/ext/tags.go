@@ -0,0 +1,18 @@
+// Package ext is a stub of the opentracing ext package used by the
+// tracecheck tests.
+package ext
+
+import "github.com/opentracing/opentracing-go"
+
+type StringTagName string
+
+func (tag StringTagName) Set(span opentracing.Span, value string) {}
+
+type BoolTagName string
+
+func (tag BoolTagName) Set(span opentracing.Span, value bool) {}
+
+const (
+	Component = StringTagName("component")
+	Error     = BoolTagName("error")
+)
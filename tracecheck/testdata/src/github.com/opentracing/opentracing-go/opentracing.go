@@ -0,0 +1,49 @@
+// Package opentracing is a stub of the opentracing API used by the
+// tracecheck tests.
+package opentracing
+
+import (
+	"context"
+
+	"github.com/opentracing/opentracing-go/log"
+)
+
+type Span interface {
+	Finish()
+	FinishWithOptions(opts FinishOptions)
+	Context() SpanContext
+	SetOperationName(operationName string) Span
+	SetTag(key string, value interface{}) Span
+	LogFields(fields ...log.Field)
+	LogKV(alternatingKeyValues ...interface{})
+	SetBaggageItem(restrictedKey, value string) Span
+	BaggageItem(restrictedKey string) string
+	Tracer() Tracer
+}
+
+type SpanContext interface{}
+
+type FinishOptions struct{}
+
+type StartSpanOption interface{}
+
+type Tracer interface {
+	StartSpan(operationName string, opts ...StartSpanOption) Span
+}
+
+type Tag struct {
+	Key   string
+	Value interface{}
+}
+
+type Tags map[string]interface{}
+
+func StartSpan(operationName string, opts ...StartSpanOption) Span { return nil }
+
+func StartSpanFromContext(ctx context.Context, operationName string, opts ...StartSpanOption) (Span, context.Context) {
+	return nil, ctx
+}
+
+func ContextWithSpan(ctx context.Context, span Span) context.Context { return ctx }
+
+func SpanFromContext(ctx context.Context) Span { return nil }
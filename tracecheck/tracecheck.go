@@ -0,0 +1,643 @@
+// Package tracecheck defines an Analyzer that reports misuse of opentracing
+// spans and of the span logging and tag utilities of the opentracing-exts
+// package.
+package tracecheck
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/ctrlflow"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/cfg"
+)
+
+const doc = `check for misuse of opentracing spans and the opentracing-exts utilities
+
+The tracecheck analyzer reports:
+
+  - spans started with a StartSpan function or method that are not finished
+    on all paths of the function that started them;
+  - spans of a concrete type from a comma-ok type assertion that discards the
+    ok value, passed to an opentracing-exts function, which does not treat a
+    nil pointer as a nil span;
+  - string literals used as tag names or log field names where an ext tag
+    constant or an opentracing-exts log field constant exists;
+  - reserved log field names set in the fields passed to LogErrorWithFields
+    and the event logging functions, which are silently ignored.`
+
+// Analyzer reports misuse of opentracing spans and the opentracing-exts
+// utilities.
+var Analyzer = &analysis.Analyzer{
+	Name:     "tracecheck",
+	Doc:      doc,
+	Requires: []*analysis.Analyzer{inspect.Analyzer, ctrlflow.Analyzer},
+	Run:      run,
+}
+
+// Package paths.
+const (
+	opentracingPath = "github.com/opentracing/opentracing-go"
+	extPath         = opentracingPath + "/ext"
+	logPath         = opentracingPath + "/log"
+	otextsPath      = "github.com/code-willing/opentracing-exts"
+)
+
+// extTags are the ext tag constant names for each tag name.
+var extTags = map[string]string{
+	"component":               "Component",
+	"db.instance":             "DBInstance",
+	"db.statement":            "DBStatement",
+	"db.type":                 "DBType",
+	"db.user":                 "DBUser",
+	"error":                   "Error",
+	"http.method":             "HTTPMethod",
+	"http.status_code":        "HTTPStatusCode",
+	"http.url":                "HTTPUrl",
+	"message_bus.destination": "MessageBusDestination",
+	"peer.address":            "PeerAddress",
+	"peer.hostname":           "PeerHostname",
+	"peer.ipv4":               "PeerHostIPv4",
+	"peer.ipv6":               "PeerHostIPv6",
+	"peer.port":               "PeerPort",
+	"peer.service":            "PeerService",
+	"sampling.priority":       "SamplingPriority",
+	"span.kind":               "SpanKind",
+}
+
+// logFields are the opentracing-exts log field constant names for each log
+// field name.
+var logFields = map[string]string{
+	"error.kind":   "LogFieldErrorKind",
+	"error.object": "LogFieldErrorObject",
+	"event":        "LogFieldEvent",
+	"level":        "LogFieldLevel",
+	"message":      "LogFieldMessage",
+	"stack":        "LogFieldStack",
+}
+
+// reservedFields are the reserved log field names of the opentracing-exts
+// functions with a fields parameter.
+var reservedFields = map[string][]string{
+	"LogErrorWithFields":    {"event", "level", "error.kind", "message"},
+	"LogErrorWithFieldsCtx": {"event", "level", "error.kind", "message"},
+	"LogEvent":              {"event", "level", "message"},
+	"LogEventCtx":           {"event", "level", "message"},
+	"LogWarning":            {"event", "level", "message"},
+	"LogDebug":              {"event", "level", "message"},
+	"LogDebugCtx":           {"event", "level", "message"},
+	"LogInfo":               {"event", "level", "message"},
+	"LogInfoCtx":            {"event", "level", "message"},
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	span := lookupSpanType(pass.Pkg)
+	if span == nil {
+		return nil, nil // the package does not depend on opentracing
+	}
+	c := &checker{
+		pass:     pass,
+		span:     span,
+		asserted: make(map[*types.Var]*ast.TypeAssertExpr),
+	}
+
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	nodeFilter := []ast.Node{
+		(*ast.File)(nil),
+		(*ast.FuncDecl)(nil),
+		(*ast.FuncLit)(nil),
+		(*ast.AssignStmt)(nil),
+		(*ast.CallExpr)(nil),
+		(*ast.CompositeLit)(nil),
+	}
+	ins.Preorder(nodeFilter, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.File:
+			c.file = n
+		case *ast.FuncDecl, *ast.FuncLit:
+			c.checkUnfinishedSpans(n)
+		case *ast.AssignStmt:
+			c.recordTypeAssertion(n)
+		case *ast.CallExpr:
+			c.checkCall(n)
+		case *ast.CompositeLit:
+			c.checkTagsLiteral(n)
+		}
+	})
+	return nil, nil
+}
+
+// checker checks the files of a package.
+type checker struct {
+	pass *analysis.Pass
+	span *types.Interface // The opentracing.Span interface.
+	file *ast.File        // The file being checked.
+
+	// asserted are the variables of a concrete span type assigned by a
+	// comma-ok type assertion that discards the ok value.
+	asserted map[*types.Var]*ast.TypeAssertExpr
+}
+
+// lookupSpanType returns the opentracing.Span interface if the specified
+// package depends on opentracing, or nil otherwise.
+func lookupSpanType(pkg *types.Package) *types.Interface {
+	seen := make(map[*types.Package]bool)
+	var find func(pkg *types.Package) *types.Interface
+	find = func(pkg *types.Package) *types.Interface {
+		if seen[pkg] {
+			return nil
+		}
+		seen[pkg] = true
+		if pkg.Path() == opentracingPath {
+			if obj, ok := pkg.Scope().Lookup("Span").(*types.TypeName); ok {
+				iface, _ := obj.Type().Underlying().(*types.Interface)
+				return iface
+			}
+			return nil
+		}
+		for _, imp := range pkg.Imports() {
+			if iface := find(imp); iface != nil {
+				return iface
+			}
+		}
+		return nil
+	}
+	return find(pkg)
+}
+
+// isSpan reports whether the specified type implements opentracing.Span.
+func (c *checker) isSpan(t types.Type) bool {
+	return t != nil && types.Implements(t, c.span)
+}
+
+// calledFunc returns the function or method called by the specified call, or
+// nil if it is not a static call.
+func (c *checker) calledFunc(call *ast.CallExpr) *types.Func {
+	var id *ast.Ident
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		id = fun
+	case *ast.SelectorExpr:
+		id = fun.Sel
+	default:
+		return nil
+	}
+	fn, _ := c.pass.TypesInfo.Uses[id].(*types.Func)
+	return fn
+}
+
+// isPkgFunc reports whether the specified function is a package level
+// function of the package with the specified path.
+func isPkgFunc(fn *types.Func, path string) bool {
+	if fn == nil || fn.Pkg() == nil || fn.Pkg().Path() != path {
+		return false
+	}
+	sig, ok := fn.Type().(*types.Signature)
+	return ok && sig.Recv() == nil
+}
+
+// stringConst returns the value of the specified constant string expression.
+func (c *checker) stringConst(e ast.Expr) (string, bool) {
+	tv, ok := c.pass.TypesInfo.Types[e]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return "", false
+	}
+	return constant.StringVal(tv.Value), true
+}
+
+// isStringLit reports whether the specified expression is a string literal.
+func isStringLit(e ast.Expr) bool {
+	lit, ok := ast.Unparen(e).(*ast.BasicLit)
+	return ok && lit.Kind == token.STRING
+}
+
+// otextsName returns the name of the opentracing-exts package in the file
+// being checked.
+func (c *checker) otextsName() string {
+	if c.pass.Pkg.Path() == otextsPath {
+		return ""
+	}
+	for _, imp := range c.file.Imports {
+		if imp.Path.Value != `"`+otextsPath+`"` {
+			continue
+		}
+		if imp.Name != nil {
+			return imp.Name.Name + "."
+		}
+		break
+	}
+	return "trace."
+}
+
+// checkTagKey reports a string literal tag name with an ext tag constant.
+func (c *checker) checkTagKey(e ast.Expr) {
+	if !isStringLit(e) {
+		return
+	}
+	key, _ := c.stringConst(e)
+	if name, ok := extTags[key]; ok {
+		c.pass.ReportRangef(e, "use ext.%s instead of the tag name string literal %q", name, key)
+	}
+}
+
+// checkLogFieldKey reports a string literal log field name with an
+// opentracing-exts log field constant.
+func (c *checker) checkLogFieldKey(e ast.Expr) {
+	if !isStringLit(e) {
+		return
+	}
+	key, _ := c.stringConst(e)
+	if name, ok := logFields[key]; ok {
+		c.pass.ReportRangef(e, "use %s%s instead of the log field name string literal %q", c.otextsName(), name, key)
+	}
+}
+
+// checkCall checks calls that set tags and log fields.
+func (c *checker) checkCall(call *ast.CallExpr) {
+	fn := c.calledFunc(call)
+	if fn == nil {
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+	switch {
+	case c.isSpanMethodCall(call):
+		switch fn.Name() {
+		case "SetTag":
+			if len(call.Args) > 0 {
+				c.checkTagKey(call.Args[0])
+			}
+		case "LogKV":
+			for i := 0; i < len(call.Args); i += 2 {
+				c.checkLogFieldKey(call.Args[i])
+			}
+		}
+	case isPkgFunc(fn, logPath):
+		if sig.Params().Len() > 0 && sig.Params().At(0).Name() == "key" && len(call.Args) > 0 {
+			c.checkLogFieldKey(call.Args[0])
+		}
+	case isPkgFunc(fn, otextsPath):
+		c.checkSpanArg(fn, call)
+		c.checkReservedFields(fn, call)
+	}
+}
+
+// isSpanMethodCall reports whether the specified call is a method call on a
+// value that implements opentracing.Span.
+func (c *checker) isSpanMethodCall(call *ast.CallExpr) bool {
+	sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	selection, ok := c.pass.TypesInfo.Selections[sel]
+	return ok && selection.Kind() == types.MethodVal && c.isSpan(selection.Recv())
+}
+
+// checkTagsLiteral checks opentracing.Tag and opentracing.Tags composite
+// literals.
+func (c *checker) checkTagsLiteral(lit *ast.CompositeLit) {
+	named, ok := types.Unalias(c.pass.TypesInfo.TypeOf(lit)).(*types.Named)
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Pkg().Path() != opentracingPath {
+		return
+	}
+	switch named.Obj().Name() {
+	case "Tag":
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				if id, ok := kv.Key.(*ast.Ident); ok && id.Name == "Key" {
+					c.checkTagKey(kv.Value)
+				}
+			}
+		}
+		if len(lit.Elts) == 2 {
+			if _, ok := lit.Elts[0].(*ast.KeyValueExpr); !ok {
+				c.checkTagKey(lit.Elts[0])
+			}
+		}
+	case "Tags":
+		for _, elt := range lit.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				c.checkTagKey(kv.Key)
+			}
+		}
+	}
+}
+
+// checkReservedFields reports reserved log field names in the fields passed
+// to the opentracing-exts functions with a fields parameter.
+func (c *checker) checkReservedFields(fn *types.Func, call *ast.CallExpr) {
+	reserved, ok := reservedFields[fn.Name()]
+	if !ok || len(call.Args) == 0 {
+		return
+	}
+	fields := ast.Unparen(call.Args[len(call.Args)-1])
+	// Check the receiver of LogFields{...}.Encode() calls.
+	if encode, ok := fields.(*ast.CallExpr); ok {
+		if sel, ok := ast.Unparen(encode.Fun).(*ast.SelectorExpr); ok && sel.Sel.Name == "Encode" {
+			fields = ast.Unparen(sel.X)
+		}
+	}
+	lit, ok := fields.(*ast.CompositeLit)
+	if !ok {
+		return
+	}
+	for _, elt := range lit.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			continue
+		}
+		key, ok := c.stringConst(kv.Key)
+		if !ok {
+			continue
+		}
+		for _, r := range reserved {
+			if key == r {
+				c.pass.ReportRangef(kv.Key, "log field %q is reserved and ignored by %s", key, fn.Name())
+				break
+			}
+		}
+	}
+}
+
+// recordTypeAssertion records the variables of a concrete span type assigned
+// by a comma-ok type assertion that discards the ok value, e.g.
+//
+//	span, _ := v.(*jaeger.Span)
+func (c *checker) recordTypeAssertion(stmt *ast.AssignStmt) {
+	if len(stmt.Lhs) != 2 || len(stmt.Rhs) != 1 {
+		return
+	}
+	assert, ok := ast.Unparen(stmt.Rhs[0]).(*ast.TypeAssertExpr)
+	if !ok {
+		return
+	}
+	if ok, _ := stmt.Lhs[1].(*ast.Ident); ok == nil || ok.Name != "_" {
+		return
+	}
+	id, _ := stmt.Lhs[0].(*ast.Ident)
+	if id == nil {
+		return
+	}
+	v := c.identVar(id)
+	if v == nil || types.IsInterface(v.Type()) || !c.isSpan(v.Type()) {
+		return
+	}
+	c.asserted[v] = assert
+}
+
+// identVar returns the variable defined or used by the specified identifier.
+func (c *checker) identVar(id *ast.Ident) *types.Var {
+	if v, ok := c.pass.TypesInfo.Defs[id].(*types.Var); ok {
+		return v
+	}
+	v, _ := c.pass.TypesInfo.Uses[id].(*types.Var)
+	return v
+}
+
+// checkSpanArg reports a possibly nil span of a concrete type from a type
+// assertion passed to an opentracing-exts function.
+func (c *checker) checkSpanArg(fn *types.Func, call *ast.CallExpr) {
+	sig := fn.Type().(*types.Signature)
+	for i := 0; i < sig.Params().Len() && i < len(call.Args); i++ {
+		if !types.Identical(sig.Params().At(i).Type().Underlying(), c.span) {
+			continue
+		}
+		id, ok := ast.Unparen(call.Args[i]).(*ast.Ident)
+		if !ok {
+			continue
+		}
+		v, _ := c.pass.TypesInfo.Uses[id].(*types.Var)
+		assert, ok := c.asserted[v]
+		if !ok {
+			continue
+		}
+		line := c.pass.Fset.Position(assert.Pos()).Line
+		c.pass.ReportRangef(id, "%s may be a nil %s from the type assertion on line %d, which %s does not treat as a nil span",
+			id.Name, types.TypeString(v.Type(), types.RelativeTo(c.pass.Pkg)), line, fn.Name())
+	}
+}
+
+// checkUnfinishedSpans reports spans started in the specified function that
+// are not finished on all paths.
+func (c *checker) checkUnfinishedSpans(node ast.Node) {
+	var body *ast.BlockStmt
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		body = node.Body
+	case *ast.FuncLit:
+		body = node.Body
+	}
+	if body == nil {
+		return
+	}
+
+	// Find the span variables defined by StartSpan calls, e.g.
+	//
+	//	span := opentracing.StartSpan("name")
+	//	span, ctx := opentracing.StartSpanFromContext(ctx, "name")
+	//	var span = tracer.StartSpan("name")
+	spanVars := make(map[*types.Var]ast.Node)
+	var stack []ast.Node
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			stack = stack[:len(stack)-1]
+			return true
+		}
+		if _, ok := n.(*ast.FuncLit); ok {
+			return false // Checked separately.
+		}
+		stack = append(stack, n)
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(stack) < 2 || !c.isStartSpan(call) {
+			return true
+		}
+		var id *ast.Ident
+		switch stmt := stack[len(stack)-2].(type) {
+		case *ast.ValueSpec:
+			if len(stmt.Values) == 1 {
+				id = stmt.Names[0]
+			}
+		case *ast.AssignStmt:
+			if len(stmt.Rhs) == 1 {
+				id, _ = stmt.Lhs[0].(*ast.Ident)
+			}
+		}
+		if id == nil {
+			return true
+		}
+		if id.Name == "_" {
+			c.pass.ReportRangef(id, "the span started by %s is discarded and never finished", calledName(call))
+			return true
+		}
+		if v := c.identVar(id); v != nil && body.Pos() <= v.Pos() && v.Pos() < body.End() {
+			spanVars[v] = stack[len(stack)-2]
+		}
+		return true
+	})
+	if len(spanVars) == 0 {
+		return
+	}
+
+	cfgs := c.pass.ResultOf[ctrlflow.Analyzer].(*ctrlflow.CFGs)
+	var g *cfg.CFG
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		g = cfgs.FuncDecl(node)
+	case *ast.FuncLit:
+		g = cfgs.FuncLit(node)
+	}
+	if g == nil {
+		return
+	}
+	benign := c.benignUses(body)
+	for v, stmt := range spanVars {
+		ret := unfinishedPath(c.pass, g, v, stmt, benign)
+		if ret == nil {
+			continue
+		}
+		line := c.pass.Fset.Position(stmt.Pos()).Line
+		c.pass.ReportRangef(stmt, "%s is not finished on all paths (possible span leak)", v.Name())
+		pos, end := ret.Pos(), ret.End()
+		if c.pass.Fset.File(pos) != c.pass.Fset.File(end) {
+			end = pos // Synthetic return statements may overflow the file.
+		}
+		c.pass.Report(analysis.Diagnostic{
+			Pos:     pos,
+			End:     end,
+			Message: fmt.Sprintf("this return statement may be reached without finishing %s, started on line %d", v.Name(), line),
+		})
+	}
+}
+
+// isStartSpan reports whether the specified call starts a span, that is a
+// call of a function or method with a name starting with "StartSpan" and an
+// opentracing.Span as its first result.
+func (c *checker) isStartSpan(call *ast.CallExpr) bool {
+	fn := c.calledFunc(call)
+	if fn == nil || !strings.HasPrefix(fn.Name(), "StartSpan") {
+		return false
+	}
+	results := fn.Type().(*types.Signature).Results()
+	return results.Len() > 0 && c.isSpan(results.At(0).Type())
+}
+
+// calledName returns the name of the function called by the specified call.
+func calledName(call *ast.CallExpr) string {
+	switch fun := ast.Unparen(call.Fun).(type) {
+	case *ast.Ident:
+		return fun.Name
+	case *ast.SelectorExpr:
+		return fun.Sel.Name
+	}
+	return "the call"
+}
+
+// benignUses returns the identifiers in the specified function body that
+// reference a span without finishing it or passing its ownership elsewhere:
+// method calls other than Finish and FinishWithOptions, and arguments of the
+// opentracing-exts, ext, and opentracing.ContextWithSpan functions.
+func (c *checker) benignUses(body *ast.BlockStmt) map[*ast.Ident]bool {
+	benign := make(map[*ast.Ident]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if sel, ok := ast.Unparen(call.Fun).(*ast.SelectorExpr); ok {
+			if id, ok := ast.Unparen(sel.X).(*ast.Ident); ok && c.isSpanMethodCall(call) {
+				switch sel.Sel.Name {
+				case "Finish", "FinishWithOptions":
+				default:
+					benign[id] = true
+				}
+			}
+		}
+		fn := c.calledFunc(call)
+		if fn == nil || fn.Pkg() == nil {
+			return true
+		}
+		switch path := fn.Pkg().Path(); {
+		case path == otextsPath, path == extPath,
+			path == opentracingPath && fn.Name() == "ContextWithSpan":
+			for _, arg := range call.Args {
+				if id, ok := ast.Unparen(arg).(*ast.Ident); ok {
+					benign[id] = true
+				}
+			}
+		}
+		return true
+	})
+	return benign
+}
+
+// unfinishedPath finds a path through the CFG, from the statement that
+// defines the span variable to a return statement, that does not finish or
+// otherwise use the span. If it finds one, it returns the return statement,
+// which may be synthetic.
+func unfinishedPath(pass *analysis.Pass, g *cfg.CFG, v *types.Var, stmt ast.Node, benign map[*ast.Ident]bool) *ast.ReturnStmt {
+	// uses reports whether the specified nodes finish or use the span.
+	uses := func(nodes []ast.Node) bool {
+		found := false
+		for _, n := range nodes {
+			ast.Inspect(n, func(n ast.Node) bool {
+				if id, ok := n.(*ast.Ident); ok && pass.TypesInfo.Uses[id] == v && !benign[id] {
+					found = true
+				}
+				return !found
+			})
+		}
+		return found
+	}
+
+	var defblock *cfg.Block
+	var rest []ast.Node
+outer:
+	for _, b := range g.Blocks {
+		for i, n := range b.Nodes {
+			if n == stmt {
+				defblock = b
+				rest = b.Nodes[i+1:]
+				break outer
+			}
+		}
+	}
+	if defblock == nil || uses(rest) {
+		return nil
+	}
+	if ret := defblock.Return(); ret != nil {
+		return ret
+	}
+
+	memo := make(map[*cfg.Block]bool)
+	seen := make(map[*cfg.Block]bool)
+	var search func(blocks []*cfg.Block) *ast.ReturnStmt
+	search = func(blocks []*cfg.Block) *ast.ReturnStmt {
+		for _, b := range blocks {
+			if seen[b] {
+				continue
+			}
+			seen[b] = true
+			used, ok := memo[b]
+			if !ok {
+				used = uses(b.Nodes)
+				memo[b] = used
+			}
+			if used {
+				continue
+			}
+			if ret := b.Return(); ret != nil {
+				return ret
+			}
+			if ret := search(b.Succs); ret != nil {
+				return ret
+			}
+		}
+		return nil
+	}
+	return search(defblock.Succs)
+}
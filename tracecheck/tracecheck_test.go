@@ -0,0 +1,13 @@
+package tracecheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/code-willing/opentracing-exts/tracecheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), tracecheck.Analyzer, "a")
+}
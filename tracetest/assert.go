@@ -0,0 +1,133 @@
+package tracetest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// AssertTags reports an error if the specified span does not have the
+// specified tag values. The values are compared formatted with fmt.Sprint.
+// It returns whether the assertion succeeded.
+func AssertTags(t testing.TB, span *mocktracer.MockSpan, tags map[string]interface{}) bool {
+	t.Helper()
+	if span == nil {
+		t.Errorf("assert tags: nil span")
+		return false
+	}
+	ok := true
+	for _, k := range sortedKeys(tags) {
+		got, set := span.Tags()[k]
+		switch {
+		case !set:
+			t.Errorf("span %q: tag %q not set, want %v", span.OperationName, k, tags[k])
+			ok = false
+		case !tagValueEqual(got, tags[k]):
+			t.Errorf("span %q: tag %q: got %v, want %v", span.OperationName, k, got, tags[k])
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertRPCTags reports an error if the specified span does not have the tags
+// set by the specified RPC tags. It returns whether the assertion succeeded.
+func AssertRPCTags(t testing.TB, span *mocktracer.MockSpan, tags otexts.RPCTags) bool {
+	t.Helper()
+	return AssertTags(t, span, optionTags(tags))
+}
+
+// AssertDBTags reports an error if the specified span does not have the tags
+// set by the specified database tags. It returns whether the assertion
+// succeeded.
+func AssertDBTags(t testing.TB, span *mocktracer.MockSpan, tags otexts.DBTags) bool {
+	t.Helper()
+	return AssertTags(t, span, optionTags(tags))
+}
+
+// AssertHTTPTags reports an error if the specified span does not have the
+// tags set by the specified HTTP tags. It returns whether the assertion
+// succeeded.
+func AssertHTTPTags(t testing.TB, span *mocktracer.MockSpan, tags otexts.HTTPTags) bool {
+	t.Helper()
+	return AssertTags(t, span, optionTags(tags))
+}
+
+// optionTags returns the tags set by the specified span option.
+func optionTags(opt opentracing.StartSpanOption) map[string]interface{} {
+	var sso opentracing.StartSpanOptions
+	opt.Apply(&sso)
+	return sso.Tags
+}
+
+// AssertErrorLogged reports an error if the specified error is not logged
+// for the specified span by LogError, LogErrorf, or LogErrorWithFields: the
+// "error" tag must be set, and an "error" event logged with the error kind
+// and a message ending with the error message. If the error is nil, any
+// logged error satisfies the assertion. It returns whether the assertion
+// succeeded.
+func AssertErrorLogged(t testing.TB, span *mocktracer.MockSpan, err error) bool {
+	t.Helper()
+	if span == nil {
+		t.Errorf("assert error logged: nil span")
+		return false
+	}
+	ok := true
+	if isErr, _ := span.Tag(string(ext.Error)).(bool); !isErr {
+		t.Errorf("span %q: error tag not set", span.OperationName)
+		ok = false
+	}
+	var kind string
+	if err != nil {
+		kind = fmt.Sprintf("%T", errors.Cause(err))
+	}
+	for _, l := range span.Logs() {
+		fields := make(map[string]string, len(l.Fields))
+		for _, f := range l.Fields {
+			fields[f.Key] = f.ValueString
+		}
+		if fields[otexts.LogFieldEvent] != otexts.LogEventError {
+			continue
+		}
+		if err == nil ||
+			fields[otexts.LogFieldErrorKind] == kind &&
+				strings.HasSuffix(fields[otexts.LogFieldMessage], err.Error()) {
+			return ok
+		}
+	}
+	if err == nil {
+		t.Errorf("span %q: no error logged", span.OperationName)
+	} else {
+		t.Errorf("span %q: error %q of kind %s not logged", span.OperationName, err, kind)
+	}
+	return false
+}
+
+// AssertNoUnfinishedSpans reports an error if a span started by the specified
+// recorder is not finished. It returns whether the assertion succeeded.
+func AssertNoUnfinishedSpans(t testing.TB, r *Recorder) bool {
+	t.Helper()
+	unfinished := r.UnfinishedSpans()
+	for _, span := range unfinished {
+		t.Errorf("span %q not finished", span.OperationName)
+	}
+	return len(unfinished) == 0
+}
+
+// sortedKeys returns the sorted keys of the specified tags.
+func sortedKeys(tags map[string]interface{}) []string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,174 @@
+package tracetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// UpdateEnv is the environment variable that sets Update when it is not
+// empty.
+const UpdateEnv = "TRACETEST_UPDATE"
+
+// UpdateFlag is the name of the test binary flag that sets Update.
+const UpdateFlag = "update"
+
+// Update updates the golden files instead of comparing them. It is set by the
+// -update flag of the test binaries importing the package, or when the
+// UpdateEnv environment variable is not empty, and may be set by the tests.
+var Update = os.Getenv(UpdateEnv) != ""
+
+// The -update flag is registered by the test binaries importing the package,
+// unless a package initialized before it already defines an -update flag,
+// which then sets Update too. A test package defining its own -update flag
+// redefines it and panics: use Update instead.
+func init() {
+	if flag.Lookup(UpdateFlag) == nil {
+		flag.BoolVar(&Update, UpdateFlag, Update, "update the tracetest golden files")
+	}
+}
+
+// update reports whether the golden files are updated, by Update or by an
+// -update flag defined by another package.
+func update() bool {
+	if Update {
+		return true
+	}
+	f := flag.Lookup(UpdateFlag)
+	return f != nil && f.Value.String() == "true"
+}
+
+// snapshotSpan is the serialized form of a span in a snapshot.
+type snapshotSpan struct {
+	Operation string                 `json:"operation"`
+	Tags      map[string]interface{} `json:"tags,omitempty"`
+	Baggage   map[string]string      `json:"baggage,omitempty"`
+	Logs      [][]snapshotField      `json:"logs,omitempty"`
+	Children  []*snapshotSpan        `json:"children,omitempty"`
+}
+
+// snapshotField is the serialized form of a log field in a snapshot.
+type snapshotField struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Snapshot returns a JSON serialization of the traces of the specified spans,
+// as a list of root spans with nested children. The span and trace IDs and the
+// timestamps are omitted, and the root spans and the children of each span
+// are sorted by their serialization, so that the snapshot is deterministic.
+func (s Spans) Snapshot() ([]byte, error) {
+	roots := make([]*snapshotSpan, 0)
+	for _, root := range s.Roots() {
+		roots = append(roots, s.snapshot(root))
+	}
+	if err := sortSnapshots(roots); err != nil {
+		return nil, err
+	}
+	b, err := json.MarshalIndent(roots, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// snapshot returns the serialized form of the specified span.
+func (s Spans) snapshot(span *mocktracer.MockSpan) *snapshotSpan {
+	snap := &snapshotSpan{
+		Operation: span.OperationName,
+		Tags:      span.Tags(),
+		Baggage:   span.SpanContext.Baggage,
+	}
+	for _, l := range span.Logs() {
+		fields := make([]snapshotField, len(l.Fields))
+		for i, f := range l.Fields {
+			fields[i] = snapshotField{Key: f.Key, Value: f.ValueString}
+		}
+		snap.Logs = append(snap.Logs, fields)
+	}
+	for _, child := range s.Children(span) {
+		snap.Children = append(snap.Children, s.snapshot(child))
+	}
+	return snap
+}
+
+// sortSnapshots sorts the specified spans, and their children, by their
+// serialization.
+func sortSnapshots(spans []*snapshotSpan) error {
+	keys := make(map[*snapshotSpan]string, len(spans))
+	for _, span := range spans {
+		if err := sortSnapshots(span.Children); err != nil {
+			return err
+		}
+		b, err := json.Marshal(span)
+		if err != nil {
+			return err
+		}
+		keys[span] = string(b)
+	}
+	sort.SliceStable(spans, func(i, j int) bool {
+		return keys[spans[i]] < keys[spans[j]]
+	})
+	return nil
+}
+
+// AssertGolden reports an error if the snapshot of the specified spans does
+// not match the golden file testdata/<name>.golden. If Update is set, e.g. by
+// running the tests with -update or TRACETEST_UPDATE=1, the golden file is
+// written instead. It returns whether the assertion succeeded.
+func AssertGolden(t testing.TB, name string, spans Spans) bool {
+	t.Helper()
+	got, err := spans.Snapshot()
+	if err != nil {
+		t.Errorf("snapshot: %v", err)
+		return false
+	}
+	path := filepath.Join("testdata", name+".golden")
+	if update() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("update golden file: %v", err)
+			return false
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Errorf("update golden file: %v", err)
+			return false
+		}
+		return true
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("read golden file: %v (run the tests with -update to create it)", err)
+		return false
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("snapshot does not match %s (run the tests with -update to update it):\n%s", path, diffLines(string(want), string(got)))
+		return false
+	}
+	return true
+}
+
+// diffLines returns the first differing line of the specified texts.
+func diffLines(want, got string) string {
+	wantLines := bytes.Split([]byte(want), []byte("\n"))
+	gotLines := bytes.Split([]byte(got), []byte("\n"))
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g []byte
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if !bytes.Equal(w, g) {
+			return fmt.Sprintf("line %d:\n-\t%s\n+\t%s", i+1, w, g)
+		}
+	}
+	return ""
+}
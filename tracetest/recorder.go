@@ -0,0 +1,145 @@
+// Package tracetest provides a recording tracer, span queries, and assertions
+// for testing the instrumentation of code using opentracing.
+package tracetest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// Ensure Recorder implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &Recorder{}
+
+// Recorder is an opentracing.Tracer that records the spans it starts, based
+// on the mocktracer package.
+//
+// The spans started by the Tracer of a recorded span are recorded when they
+// finish, but are not reported by UnfinishedSpans.
+type Recorder struct {
+	*mocktracer.MockTracer
+
+	mu      sync.Mutex
+	started []*mocktracer.MockSpan
+}
+
+// NewRecorder returns a new recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{MockTracer: mocktracer.New()}
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (r *Recorder) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	span := r.MockTracer.StartSpan(operationName, opts...)
+	r.mu.Lock()
+	r.started = append(r.started, span.(*mocktracer.MockSpan))
+	r.mu.Unlock()
+	return span
+}
+
+// Spans returns the finished spans, in finish order.
+func (r *Recorder) Spans() Spans {
+	return r.FinishedSpans()
+}
+
+// UnfinishedSpans returns the spans started by the recorder that are not
+// finished, in start order.
+func (r *Recorder) UnfinishedSpans() Spans {
+	finished := make(map[*mocktracer.MockSpan]bool)
+	for _, span := range r.FinishedSpans() {
+		finished[span] = true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unfinished Spans
+	for _, span := range r.started {
+		if !finished[span] {
+			unfinished = append(unfinished, span)
+		}
+	}
+	return unfinished
+}
+
+// Reset clears the recorded spans.
+func (r *Recorder) Reset() {
+	r.MockTracer.Reset()
+	r.mu.Lock()
+	r.started = nil
+	r.mu.Unlock()
+}
+
+// FindSpan returns the first finished span with the specified operation name,
+// or nil if there is none.
+func (r *Recorder) FindSpan(operationName string) *mocktracer.MockSpan {
+	return r.Spans().FindSpan(operationName)
+}
+
+// Children returns the finished children of the specified span.
+func (r *Recorder) Children(parent *mocktracer.MockSpan) Spans {
+	return r.Spans().Children(parent)
+}
+
+// WithTag returns the finished spans with the specified tag value.
+func (r *Recorder) WithTag(key string, value interface{}) Spans {
+	return r.Spans().WithTag(key, value)
+}
+
+// Spans is a list of recorded spans.
+type Spans []*mocktracer.MockSpan
+
+// FindSpan returns the first span with the specified operation name, or nil
+// if there is none.
+func (s Spans) FindSpan(operationName string) *mocktracer.MockSpan {
+	for _, span := range s {
+		if span.OperationName == operationName {
+			return span
+		}
+	}
+	return nil
+}
+
+// WithOperation returns the spans with the specified operation name.
+func (s Spans) WithOperation(operationName string) Spans {
+	var spans Spans
+	for _, span := range s {
+		if span.OperationName == operationName {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Children returns the children of the specified span.
+func (s Spans) Children(parent *mocktracer.MockSpan) Spans {
+	if parent == nil {
+		return nil
+	}
+	var spans Spans
+	for _, span := range s {
+		if span.ParentID == parent.SpanContext.SpanID && span.SpanContext.TraceID == parent.SpanContext.TraceID {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// WithTag returns the spans with the specified tag value. The values are
+// compared formatted with fmt.Sprint, so that e.g. the uint16 value of the
+// "http.status_code" tag matches an int.
+func (s Spans) WithTag(key string, value interface{}) Spans {
+	var spans Spans
+	for _, span := range s {
+		if v, ok := span.Tags()[key]; ok && tagValueEqual(v, value) {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// tagValueEqual reports whether the specified tag values are equal formatted
+// with fmt.Sprint.
+func tagValueEqual(got, want interface{}) bool {
+	return fmt.Sprint(got) == fmt.Sprint(want)
+}
@@ -0,0 +1,222 @@
+package tracetest_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+	"github.com/code-willing/opentracing-exts/tracetest"
+)
+
+// recordingT is a testing.TB that records the reported errors.
+type recordingT struct {
+	testing.TB
+	errors []string
+}
+
+func (t *recordingT) Helper() {}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestRecorder(t *testing.T) {
+	r := tracetest.NewRecorder()
+	root := r.StartSpan("root", otexts.HTTPTags{Method: http.MethodGet, StatusCode: http.StatusOK})
+	first := r.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: "index", Value: 1})
+	second := r.StartSpan("child", opentracing.ChildOf(root.Context()), opentracing.Tag{Key: "index", Value: 2})
+	first.Finish()
+
+	if got, want := len(r.UnfinishedSpans()), 2; got != want {
+		t.Errorf("unfinished spans: got %d, want %d", got, want)
+	}
+	second.Finish()
+	root.Finish()
+
+	rootSpan := r.FindSpan("root")
+	if rootSpan == nil {
+		t.Fatalf("root span not found")
+	}
+	if got, want := len(r.Children(rootSpan)), 2; got != want {
+		t.Errorf("children: got %d, want %d", got, want)
+	}
+	if got, want := r.Children(rootSpan).WithTag("index", 2), second.(*mocktracer.MockSpan); len(got) != 1 || got[0] != want {
+		t.Errorf("children with tag: got %v, want %v", got, want)
+	}
+	if got, want := len(r.WithTag(string(ext.HTTPStatusCode), 200)), 1; got != want {
+		t.Errorf("spans with status code: got %d, want %d", got, want)
+	}
+	if got := r.FindSpan("missing"); got != nil {
+		t.Errorf("missing span: got %v, want nil", got)
+	}
+	tracetest.AssertNoUnfinishedSpans(t, r)
+
+	r.Reset()
+	if got, want := len(r.Spans()), 0; got != want {
+		t.Errorf("spans after reset: got %d, want %d", got, want)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	err := errors.Wrap(errors.New("test"), "wrapped")
+	tt := []struct {
+		name   string
+		span   func(tracer opentracing.Tracer) opentracing.Span
+		assert func(t testing.TB, r *tracetest.Recorder) bool
+		errors int
+	}{
+		{
+			name: "tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", opentracing.Tags{"a": 1, "b": "b"})
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertTags(t, r.FindSpan("test"), map[string]interface{}{"a": 1, "b": "b"})
+			},
+		},
+		{
+			name: "missing tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", opentracing.Tags{"a": 1})
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertTags(t, r.FindSpan("test"), map[string]interface{}{"a": 2, "b": "b"})
+			},
+			errors: 2,
+		},
+		{
+			name: "http tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", otexts.HTTPTags{Method: http.MethodGet, StatusCode: http.StatusOK})
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertHTTPTags(t, r.FindSpan("test"), otexts.HTTPTags{Method: http.MethodGet, StatusCode: http.StatusOK})
+			},
+		},
+		{
+			name: "wrong http tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", otexts.HTTPTags{Method: http.MethodGet})
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertHTTPTags(t, r.FindSpan("test"), otexts.HTTPTags{Method: http.MethodPost})
+			},
+			errors: 1,
+		},
+		{
+			name: "rpc and db tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", otexts.RPCTags{Kind: ext.SpanKindRPCClientEnum}, otexts.DBTags{Type: "sql"})
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertRPCTags(t, r.FindSpan("test"), otexts.RPCTags{Kind: ext.SpanKindRPCClientEnum}) &&
+					tracetest.AssertDBTags(t, r.FindSpan("test"), otexts.DBTags{Type: "sql"})
+			},
+		},
+		{
+			name: "error logged",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("test")
+				otexts.LogErrorf(span, err, "failed")
+				return span
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertErrorLogged(t, r.FindSpan("test"), err)
+			},
+		},
+		{
+			name: "error not logged",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("test")
+				otexts.LogError(span, errors.New("other"))
+				return span
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertErrorLogged(t, r.FindSpan("test"), err)
+			},
+			errors: 1,
+		},
+		{
+			name: "any error logged",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("test")
+				otexts.LogError(span, errors.New("other"))
+				return span
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertErrorLogged(t, r.FindSpan("test"), nil)
+			},
+		},
+		{
+			name: "no error logged",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test")
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertErrorLogged(t, r.FindSpan("test"), nil)
+			},
+			errors: 2,
+		},
+		{
+			name: "nil span",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("other")
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertErrorLogged(t, r.FindSpan("test"), err)
+			},
+			errors: 1,
+		},
+		{
+			name: "unfinished spans",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				tracer.StartSpan("unfinished")
+				return tracer.StartSpan("test")
+			},
+			assert: func(t testing.TB, r *tracetest.Recorder) bool {
+				return tracetest.AssertNoUnfinishedSpans(t, r)
+			},
+			errors: 1,
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := tracetest.NewRecorder()
+			tc.span(r).Finish()
+
+			rt := &recordingT{TB: t}
+			ok := tc.assert(rt, r)
+			if got, want := len(rt.errors), tc.errors; got != want {
+				t.Errorf("errors: got %d %v, want %d", got, rt.errors, want)
+			}
+			if got, want := ok, tc.errors == 0; got != want {
+				t.Errorf("ok: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidateMockSpan(t *testing.T) {
+	span := mocktracer.New().StartSpan("test").(*mocktracer.MockSpan)
+	otexts.LogError(span, errors.New("error"))
+	ext.HTTPStatusCode.Set(span, 200)
+	span.SetTag("foo", "bar")
+	span.Finish()
+
+	violations := tracetest.ValidateMockSpan(otexts.Validator{Strict: true}, span)
+	if got, want := len(violations), 1; got != want {
+		t.Fatalf("violations: got %v, want %d", violations, want)
+	}
+	if got, want := violations[0].Key, "foo"; got != want {
+		t.Errorf("violation key: got %q, want %q", got, want)
+	}
+	if violations := tracetest.ValidateMockSpan(otexts.Validator{}, nil); violations != nil {
+		t.Errorf("nil span violations: got %v, want nil", violations)
+	}
+}
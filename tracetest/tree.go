@@ -0,0 +1,175 @@
+package tracetest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+)
+
+// ErrorState is the expected error state of a span.
+type ErrorState int
+
+// Span error states.
+const (
+	// ErrorAny matches spans with or without the "error" tag set.
+	ErrorAny ErrorState = iota
+
+	// ErrorSet matches spans with the "error" tag set to true.
+	ErrorSet
+
+	// ErrorUnset matches spans without the "error" tag set to true.
+	ErrorUnset
+)
+
+// SpanTree is the expected shape of a span and its descendants.
+type SpanTree struct {
+	// Operation is the operation name of the span.
+	Operation string
+
+	// Tags are the tag values required on the span, compared formatted with
+	// fmt.Sprint.
+	Tags map[string]interface{}
+
+	// Options are span options whose tags are required on the span, e.g.
+	// RPCTags, DBTags, and HTTPTags.
+	Options []opentracing.StartSpanOption
+
+	// Error is the error state of the span.
+	Error ErrorState
+
+	// Children are the children of the span, in any order.
+	Children []SpanTree
+
+	// Partial allows the span to have children other than Children.
+	Partial bool
+}
+
+// Roots returns the spans without a parent, or whose parent is not in the
+// spans, e.g. extracted from a request or not recorded.
+func (s Spans) Roots() Spans {
+	type spanKey struct{ traceID, spanID int }
+	recorded := make(map[spanKey]bool, len(s))
+	for _, span := range s {
+		recorded[spanKey{span.SpanContext.TraceID, span.SpanContext.SpanID}] = true
+	}
+	var spans Spans
+	for _, span := range s {
+		if span.ParentID == 0 || !recorded[spanKey{span.SpanContext.TraceID, span.ParentID}] {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// MatchTree returns an error describing the mismatch if none of the root spans
+// matches the specified span tree.
+func (s Spans) MatchTree(want SpanTree) error {
+	var err error
+	for _, root := range s.Roots() {
+		if root.OperationName != want.Operation {
+			continue
+		}
+		if err = s.match(root, want); err == nil {
+			return nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("no root span %q", want.Operation)
+	}
+	return err
+}
+
+// match returns an error describing the mismatch if the specified span does
+// not match the specified span tree.
+func (s Spans) match(span *mocktracer.MockSpan, want SpanTree) error {
+	if span.OperationName != want.Operation {
+		return fmt.Errorf("span %q: operation name %q", want.Operation, span.OperationName)
+	}
+	tags := make(map[string]interface{}, len(want.Tags))
+	for _, opt := range want.Options {
+		for k, v := range optionTags(opt) {
+			tags[k] = v
+		}
+	}
+	for k, v := range want.Tags {
+		tags[k] = v
+	}
+	for _, k := range sortedKeys(tags) {
+		got, ok := span.Tags()[k]
+		if !ok {
+			return fmt.Errorf("span %q: tag %q not set, want %v", want.Operation, k, tags[k])
+		}
+		if !tagValueEqual(got, tags[k]) {
+			return fmt.Errorf("span %q: tag %q: got %v, want %v", want.Operation, k, got, tags[k])
+		}
+	}
+	isErr, _ := span.Tag(string(ext.Error)).(bool)
+	switch {
+	case want.Error == ErrorSet && !isErr:
+		return fmt.Errorf("span %q: error tag not set", want.Operation)
+	case want.Error == ErrorUnset && isErr:
+		return fmt.Errorf("span %q: error tag set", want.Operation)
+	}
+
+	children := s.Children(span)
+	if !want.Partial && len(children) != len(want.Children) {
+		return fmt.Errorf("span %q: got %d children %v, want %d", want.Operation, len(children), operationNames(children), len(want.Children))
+	}
+	used := make([]bool, len(children))
+	var childErr error
+	var assign func(i int) bool
+	assign = func(i int) bool {
+		if i == len(want.Children) {
+			return true
+		}
+		for j, child := range children {
+			if used[j] {
+				continue
+			}
+			if err := s.match(child, want.Children[i]); err != nil {
+				if child.OperationName == want.Children[i].Operation || childErr == nil {
+					childErr = err
+				}
+				continue
+			}
+			used[j] = true
+			if assign(i + 1) {
+				return true
+			}
+			used[j] = false
+		}
+		return false
+	}
+	if !assign(0) {
+		if childErr == nil {
+			childErr = fmt.Errorf("span %q: got children %v", want.Operation, operationNames(children))
+		}
+		return childErr
+	}
+	return nil
+}
+
+// operationNames returns the operation names of the specified spans.
+func operationNames(spans Spans) string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.OperationName
+	}
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+// AssertTree reports an error if none of the root spans of the specified
+// spans matches the specified span tree. It returns whether the assertion
+// succeeded.
+func AssertTree(t testing.TB, spans Spans, want SpanTree) bool {
+	t.Helper()
+	if err := spans.MatchTree(want); err != nil {
+		t.Errorf("trace mismatch: %v", err)
+		return false
+	}
+	return true
+}
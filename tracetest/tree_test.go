@@ -0,0 +1,203 @@
+package tracetest_test
+
+import (
+	"flag"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+	"github.com/code-willing/opentracing-exts/tracetest"
+)
+
+// recordTrace records a trace of an HTTP request querying a database twice.
+func recordTrace() *tracetest.Recorder {
+	r := tracetest.NewRecorder()
+	root := r.StartSpan("GET /users", otexts.HTTPTags{Method: http.MethodGet, URL: "/users", StatusCode: http.StatusOK})
+	root.SetBaggageItem("user", "test")
+	for _, statement := range []string{"SELECT 2", "SELECT 1"} {
+		span := r.StartSpan("query", opentracing.ChildOf(root.Context()), otexts.DBTags{Type: "sql", Statement: statement})
+		if statement == "SELECT 2" {
+			otexts.LogError(span, errors.New("timeout"))
+		}
+		span.Finish()
+	}
+	r.StartSpan("render", opentracing.ChildOf(root.Context())).Finish()
+	root.Finish()
+	r.StartSpan("background").Finish()
+	return r
+}
+
+func TestMatchTree(t *testing.T) {
+	tt := []struct {
+		name string
+		tree tracetest.SpanTree
+		err  string
+	}{
+		{
+			name: "match",
+			tree: tracetest.SpanTree{
+				Operation: "GET /users",
+				Options:   []opentracing.StartSpanOption{otexts.HTTPTags{Method: http.MethodGet, StatusCode: http.StatusOK}},
+				Error:     tracetest.ErrorUnset,
+				Children: []tracetest.SpanTree{
+					{Operation: "render"},
+					{Operation: "query", Tags: map[string]interface{}{string(ext.DBStatement): "SELECT 1"}, Error: tracetest.ErrorUnset},
+					{Operation: "query", Options: []opentracing.StartSpanOption{otexts.DBTags{Type: "sql"}}, Error: tracetest.ErrorSet},
+				},
+			},
+		},
+		{
+			name: "partial",
+			tree: tracetest.SpanTree{
+				Operation: "GET /users",
+				Children:  []tracetest.SpanTree{{Operation: "query", Error: tracetest.ErrorSet}},
+				Partial:   true,
+			},
+		},
+		{
+			name: "missing root",
+			tree: tracetest.SpanTree{Operation: "POST /users"},
+			err:  `no root span "POST /users"`,
+		},
+		{
+			name: "tag mismatch",
+			tree: tracetest.SpanTree{
+				Operation: "GET /users",
+				Options:   []opentracing.StartSpanOption{otexts.HTTPTags{Method: http.MethodPost}},
+			},
+			err: `span "GET /users": tag "http.method": got GET, want POST`,
+		},
+		{
+			name: "children count",
+			tree: tracetest.SpanTree{
+				Operation: "GET /users",
+				Children:  []tracetest.SpanTree{{Operation: "render"}},
+			},
+			err: `span "GET /users": got 3 children [query, query, render], want 1`,
+		},
+		{
+			name: "child mismatch",
+			tree: tracetest.SpanTree{
+				Operation: "GET /users",
+				Children: []tracetest.SpanTree{
+					{Operation: "render"},
+					{Operation: "query", Error: tracetest.ErrorUnset},
+					{Operation: "query", Error: tracetest.ErrorUnset},
+				},
+			},
+			err: `span "query": error tag set`,
+		},
+	}
+	spans := recordTrace().Spans()
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := spans.MatchTree(tc.tree)
+			switch {
+			case tc.err == "" && err != nil:
+				t.Errorf("match tree: %v", err)
+			case tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)):
+				t.Errorf("match tree: got error %v, want %q", err, tc.err)
+			}
+		})
+	}
+}
+
+func TestMatchTree_unrecordedParent(t *testing.T) {
+	// The parent span is started by the client, e.g. extracted from a request.
+	client := mocktracer.New().StartSpan("client")
+	r := tracetest.NewRecorder()
+	server := r.StartSpan("server", ext.RPCServerOption(client.Context()))
+	r.StartSpan("query", opentracing.ChildOf(server.Context())).Finish()
+	server.Finish()
+
+	spans := r.Spans()
+	if got, want := len(spans.Roots()), 1; got != want {
+		t.Fatalf("roots: got %d, want %d", got, want)
+	}
+	if err := spans.MatchTree(tracetest.SpanTree{
+		Operation: "server",
+		Children:  []tracetest.SpanTree{{Operation: "query"}},
+	}); err != nil {
+		t.Errorf("match tree: %v", err)
+	}
+	snapshot, err := spans.Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if !strings.Contains(string(snapshot), `"operation": "server"`) {
+		t.Errorf("snapshot: got %s, want the server span", snapshot)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	tracetest.AssertGolden(t, "trace", recordTrace().Spans())
+}
+
+func TestAssertGolden_update(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer os.Chdir(wd)
+	defer func(update bool) { tracetest.Update = update }(tracetest.Update)
+
+	tracetest.Update = true
+	if !tracetest.AssertGolden(t, "trace", recordTrace().Spans()) {
+		t.Fatalf("update golden file failed")
+	}
+	tracetest.Update = false
+	tracetest.AssertGolden(t, "trace", recordTrace().Spans())
+
+	// The -update flag sets Update.
+	if err := flag.Set(tracetest.UpdateFlag, "true"); err != nil {
+		t.Fatalf("set -update flag: %v", err)
+	}
+	if !tracetest.Update {
+		t.Errorf("-update flag: Update not set")
+	}
+	if !tracetest.AssertGolden(t, "users", recordTrace().Spans()) {
+		t.Fatalf("update golden file failed")
+	}
+	if _, err := os.Stat("testdata/users.golden"); err != nil {
+		t.Errorf("-update flag: %v", err)
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	// The same trace with the children started in a different order.
+	r := tracetest.NewRecorder()
+	r.StartSpan("background").Finish()
+	root := r.StartSpan("GET /users", otexts.HTTPTags{Method: http.MethodGet, URL: "/users", StatusCode: http.StatusOK})
+	root.SetBaggageItem("user", "test")
+	r.StartSpan("render", opentracing.ChildOf(root.Context())).Finish()
+	for _, statement := range []string{"SELECT 1", "SELECT 2"} {
+		span := r.StartSpan("query", opentracing.ChildOf(root.Context()), otexts.DBTags{Type: "sql", Statement: statement})
+		if statement == "SELECT 2" {
+			otexts.LogError(span, errors.New("timeout"))
+		}
+		span.Finish()
+	}
+	root.Finish()
+
+	got, err := r.Spans().Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	want, err := recordTrace().Spans().Snapshot()
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("snapshot: got %s, want %s", got, want)
+	}
+}
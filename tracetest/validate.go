@@ -0,0 +1,27 @@
+package tracetest
+
+import (
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+// ValidateMockSpan returns the semantic conventions violations of the tags
+// and logs of the specified mocktracer span found by the specified validator.
+// See otexts.Validator.
+func ValidateMockSpan(v otexts.Validator, span *mocktracer.MockSpan) []otexts.Violation {
+	if span == nil {
+		return nil
+	}
+	mockLogs := span.Logs()
+	logs := make([]opentracing.LogRecord, len(mockLogs))
+	for i, l := range mockLogs {
+		logs[i].Timestamp = l.Timestamp
+		for _, f := range l.Fields {
+			logs[i].Fields = append(logs[i].Fields, log.String(f.Key, f.ValueString))
+		}
+	}
+	return v.Validate(span.Tags(), logs)
+}
@@ -0,0 +1,187 @@
+package trace
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+)
+
+// Violation is a semantic conventions violation of a span's tags or logs.
+type Violation struct {
+	Key     string // The tag or log field name.
+	Message string // The violation description.
+}
+
+// Error implements the error interface.
+func (v Violation) Error() string {
+	return v.Key + ": " + v.Message
+}
+
+// Validator validates span tags and logs against the opentracing semantic
+// conventions. The OpenTelemetry attribute names set by the tag option types
+// are validated as their opentracing tag names, in every tag mode.
+//
+// See https://github.com/opentracing/specification/blob/master/semantic_conventions.md.
+type Validator struct {
+	// Strict reports tags that are not in the semantic conventions or in
+	// KnownTags.
+	Strict bool
+
+	// KnownTags are the tag names known in addition to the semantic
+	// conventions tags, used in strict mode. The OpenTelemetry attribute
+	// names of the semantic conventions tags are known.
+	KnownTags []string
+}
+
+// conventionTags are the tag names in the semantic conventions.
+var conventionTags = map[string]bool{
+	string(ext.Component):             true,
+	string(ext.DBInstance):            true,
+	string(ext.DBStatement):           true,
+	string(ext.DBType):                true,
+	string(ext.DBUser):                true,
+	string(ext.Error):                 true,
+	string(ext.HTTPMethod):            true,
+	string(ext.HTTPStatusCode):        true,
+	string(ext.HTTPUrl):               true,
+	string(ext.MessageBusDestination): true,
+	string(ext.PeerAddress):           true,
+	string(ext.PeerHostname):          true,
+	string(ext.PeerHostIPv4):          true,
+	string(ext.PeerHostIPv6):          true,
+	string(ext.PeerPort):              true,
+	string(ext.PeerService):           true,
+	string(ext.SamplingPriority):      true,
+	string(ext.SpanKind):              true,
+}
+
+// Validate returns the semantic conventions violations of the specified span
+// tags and logs, sorted by key.
+func (v Validator) Validate(tags map[string]interface{}, logs []opentracing.LogRecord) []Violation {
+	var violations []Violation
+	report := func(key, format string, args ...interface{}) {
+		violations = append(violations, Violation{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+
+	var known map[string]bool
+	if v.Strict {
+		known = make(map[string]bool, len(v.KnownTags))
+		for _, k := range v.KnownTags {
+			known[k] = true
+		}
+	}
+	for k, val := range tags {
+		// The OpenTelemetry attribute names are validated as their
+		// opentracing tag names, valid if valid for any of them.
+		keys := openTracingTagKeys(k)
+		if len(keys) == 0 {
+			keys = []string{k}
+		}
+		var msg string
+		for i, key := range keys {
+			m := v.validateTag(key, val, known)
+			if m == "" {
+				msg = ""
+				break
+			}
+			if i == 0 {
+				msg = m
+			}
+		}
+		if msg != "" {
+			report(k, "%s", msg)
+		}
+	}
+
+	if isErr, _ := tags[string(ext.Error)].(bool); isErr && !hasErrorLog(logs) {
+		report(string(ext.Error), "error tag set without an %q log event", LogEventError)
+	}
+
+	sort.SliceStable(violations, func(i, j int) bool {
+		return violations[i].Key < violations[j].Key
+	})
+	return violations
+}
+
+// validateTag returns the violation message of the specified tag, or an
+// empty string if the tag is valid.
+func (v Validator) validateTag(k string, val interface{}, known map[string]bool) string {
+	switch k {
+	case string(ext.SpanKind):
+		switch kind := fmt.Sprint(val); kind {
+		case string(ext.SpanKindRPCClientEnum), string(ext.SpanKindRPCServerEnum),
+			string(ext.SpanKindProducerEnum), string(ext.SpanKindConsumerEnum):
+		default:
+			return fmt.Sprintf("invalid span kind %q", kind)
+		}
+	case string(ext.Error):
+		if _, ok := val.(bool); !ok {
+			return fmt.Sprintf("invalid type %T, want bool", val)
+		}
+	case string(ext.HTTPStatusCode), string(ext.PeerPort), string(ext.SamplingPriority):
+		if !isInteger(val) {
+			return fmt.Sprintf("invalid type %T, want integer", val)
+		}
+	case string(ext.PeerHostIPv4):
+		if !isIPv4(val) {
+			return fmt.Sprintf("invalid IPv4 address %v", val)
+		}
+	case string(ext.PeerHostIPv6):
+		if !isIPv6(val) {
+			return fmt.Sprintf("invalid IPv6 address %v", val)
+		}
+	case string(ext.DBType):
+		if s := fmt.Sprint(val); s != strings.ToLower(s) {
+			return fmt.Sprintf("database type %q is not lowercase", s)
+		}
+	default:
+		if v.Strict && !conventionTags[k] && !known[k] {
+			return "unknown tag"
+		}
+	}
+	return ""
+}
+
+// hasErrorLog reports whether the specified logs have an error event.
+func hasErrorLog(logs []opentracing.LogRecord) bool {
+	for _, l := range logs {
+		for _, f := range l.Fields {
+			if f.Key() == LogFieldEvent && fmt.Sprint(f.Value()) == LogEventError {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isInteger reports whether the specified value is an integer.
+func isInteger(v interface{}) bool {
+	switch v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	}
+	return false
+}
+
+// isIPv4 reports whether the specified value is an IPv4 address, either a
+// dotted decimal string or the uint32 set by ext.PeerHostIPv4.
+func isIPv4(v interface{}) bool {
+	switch ip := v.(type) {
+	case uint32:
+		return true
+	case string:
+		parsed := net.ParseIP(ip)
+		return parsed != nil && parsed.To4() != nil && !strings.Contains(ip, ":")
+	}
+	return false
+}
+
+// isIPv6 reports whether the specified value is an IPv6 address string.
+func isIPv6(v interface{}) bool {
+	ip, ok := v.(string)
+	return ok && net.ParseIP(ip) != nil && strings.Contains(ip, ":")
+}
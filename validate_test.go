@@ -0,0 +1,196 @@
+package trace_test
+
+import (
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"github.com/opentracing/opentracing-go/log"
+	"github.com/opentracing/opentracing-go/mocktracer"
+	"github.com/pkg/errors"
+
+	otexts "github.com/code-willing/opentracing-exts"
+)
+
+func TestValidator(t *testing.T) {
+	tt := []struct {
+		name       string
+		validator  otexts.Validator
+		span       func(tracer opentracing.Tracer) opentracing.Span
+		violations []string
+	}{
+		{
+			name: "valid tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("test", otexts.DBTags{
+					Type:     "SQL",
+					PeerIPv4: net.IPv4(127, 0, 0, 1),
+					PeerIPv6: net.IPv6loopback,
+					PeerPort: 5432,
+				}, otexts.HTTPTags{
+					Method:     http.MethodGet,
+					StatusCode: http.StatusOK,
+				})
+				otexts.SetRPCTags(span, otexts.RPCTags{Kind: ext.SpanKindRPCServerEnum})
+				ext.PeerHostIPv4.Set(span, 2130706433)
+				return span
+			},
+		},
+		{
+			name: "valid OpenTelemetry tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", otexts.DBTags{
+					Type:     "sql",
+					PeerIPv6: net.IPv6loopback,
+					PeerPort: 5432,
+					Mode:     otexts.TagModeOTel,
+				}, otexts.HTTPTags{
+					Method:     http.MethodGet,
+					StatusCode: http.StatusOK,
+					Mode:       otexts.TagModeOTel,
+				})
+			},
+		},
+		{
+			name: "valid error",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("test")
+				otexts.LogError(span, errors.New("error"))
+				return span
+			},
+		},
+		{
+			name: "invalid tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", opentracing.Tags{
+					string(ext.SpanKind):         "internal",
+					string(ext.HTTPStatusCode):   "200",
+					string(ext.PeerHostIPv4):     "::1",
+					string(ext.PeerHostIPv6):     "127.0.0.1",
+					string(ext.DBType):           "SQL",
+					string(ext.SamplingPriority): 1.5,
+					"foo":                        "bar",
+				})
+			},
+			violations: []string{
+				string(ext.DBType),
+				string(ext.HTTPStatusCode),
+				string(ext.PeerHostIPv4),
+				string(ext.PeerHostIPv6),
+				string(ext.SamplingPriority),
+				string(ext.SpanKind),
+			},
+		},
+		{
+			name: "invalid OpenTelemetry tags",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", opentracing.Tags{
+					"http.response.status_code": "200",
+					"network.peer.address":      "localhost",
+				})
+			},
+			violations: []string{
+				"http.response.status_code",
+				"network.peer.address",
+			},
+		},
+		{
+			name: "error tag without log",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				span := tracer.StartSpan("test")
+				ext.Error.Set(span, true)
+				return span
+			},
+			violations: []string{string(ext.Error)},
+		},
+		{
+			name: "invalid error tag",
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", opentracing.Tag{Key: string(ext.Error), Value: "true"})
+			},
+			violations: []string{string(ext.Error)},
+		},
+		{
+			name:      "strict",
+			validator: otexts.Validator{Strict: true, KnownTags: []string{"known"}},
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", opentracing.Tags{
+					string(ext.Component): "test",
+					"known":               "bar",
+					"unknown":             "bar",
+				})
+			},
+			violations: []string{"unknown"},
+		},
+		{
+			name:      "strict OpenTelemetry",
+			validator: otexts.Validator{Strict: true, KnownTags: []string{"known"}},
+			span: func(tracer opentracing.Tracer) opentracing.Span {
+				return tracer.StartSpan("test", otexts.HTTPTags{
+					Method: http.MethodGet,
+					Mode:   otexts.TagModeOTel,
+				}, opentracing.Tags{
+					string(ext.PeerHostIPv4): "127.0.0.1",
+					"known":                  "bar",
+				})
+			},
+		},
+	}
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var keys []string
+			tracer := otexts.NewValidatingTracer(mocktracer.New(), tc.validator, func(operationName string, violations []otexts.Violation) {
+				if operationName != "test" {
+					t.Errorf("operation name: got %q, want %q", operationName, "test")
+				}
+				for _, v := range violations {
+					keys = append(keys, v.Key)
+				}
+			})
+			tc.span(tracer).Finish()
+
+			if got, want := keys, tc.violations; !reflect.DeepEqual(got, want) {
+				t.Errorf("violations: got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestValidatingTracer(t *testing.T) {
+	mock := mocktracer.New()
+	var violations []otexts.Violation
+	tracer := otexts.NewValidatingTracer(mock, otexts.Validator{}, func(operationName string, v []otexts.Violation) {
+		violations = append(violations, v...)
+	})
+
+	parent := tracer.StartSpan("parent")
+	span := tracer.StartSpan("child", opentracing.ChildOf(parent.Context()))
+	span.SetOperationName("test")
+	span.LogKV(otexts.LogFieldEvent, otexts.LogEventError)
+	span.LogEvent("deprecated")
+	span.SetTag(string(ext.Error), true)
+	span.FinishWithOptions(opentracing.FinishOptions{LogRecords: []opentracing.LogRecord{{
+		Fields: []log.Field{log.String(string(ext.SpanKind), "internal")},
+	}}})
+	parent.Finish()
+
+	if len(violations) != 0 {
+		t.Errorf("violations: got %v, want none", violations)
+	}
+	finished := mock.FinishedSpans()
+	if got, want := len(finished), 2; got != want {
+		t.Fatalf("finished spans: got %d, want %d", got, want)
+	}
+	if got, want := finished[0].ParentID, finished[1].SpanContext.SpanID; got != want {
+		t.Errorf("parent ID: got %d, want %d", got, want)
+	}
+	if got, want := len(finished[0].Logs()), 3; got != want {
+		t.Errorf("logs: got %d, want %d", got, want)
+	}
+	if _, ok := span.Context().(mocktracer.MockSpanContext); !ok {
+		t.Errorf("span context: got %T, want mocktracer.MockSpanContext", span.Context())
+	}
+}
@@ -0,0 +1,192 @@
+package trace
+
+import (
+	stdlog "log"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/log"
+)
+
+// Ensure ValidatingTracer implements the opentracing.Tracer interface.
+var _ opentracing.Tracer = &ValidatingTracer{}
+
+// ValidatingTracer is an opentracing.Tracer that wraps another tracer,
+// validating the tags and logs of every span it starts with a Validator when
+// the span finishes. It is meant to be set as the global tracer in debug
+// mode, to catch the misuse of the tag options in development:
+//
+//	opentracing.SetGlobalTracer(trace.NewValidatingTracer(tracer, trace.Validator{}, nil))
+//
+// The spans of a ValidatingTracer wrap the spans of the wrapped tracer, their
+// span contexts are the span contexts of the wrapped tracer.
+type ValidatingTracer struct {
+	tracer    opentracing.Tracer
+	validator Validator
+	report    func(operationName string, violations []Violation)
+}
+
+// NewValidatingTracer returns a new tracer that wraps the specified tracer,
+// validating every span with the specified validator when it finishes. The
+// violations of each span are passed to the specified report function, or
+// logged with the standard library log package if it is nil.
+func NewValidatingTracer(tracer opentracing.Tracer, validator Validator, report func(operationName string, violations []Violation)) *ValidatingTracer {
+	if report == nil {
+		report = logViolations
+	}
+	return &ValidatingTracer{tracer: tracer, validator: validator, report: report}
+}
+
+// logViolations logs the specified violations with the standard library log
+// package.
+func logViolations(operationName string, violations []Violation) {
+	for _, v := range violations {
+		stdlog.Printf("trace: span %q: %v", operationName, v)
+	}
+}
+
+// StartSpan implements the opentracing.Tracer interface.
+func (t *ValidatingTracer) StartSpan(operationName string, opts ...opentracing.StartSpanOption) opentracing.Span {
+	var sso opentracing.StartSpanOptions
+	for _, o := range opts {
+		o.Apply(&sso)
+	}
+	tags := make(map[string]interface{}, len(sso.Tags))
+	for k, v := range sso.Tags {
+		tags[k] = v
+	}
+	return &validatingSpan{
+		span:          t.tracer.StartSpan(operationName, opts...),
+		tracer:        t,
+		operationName: operationName,
+		tags:          tags,
+	}
+}
+
+// Inject implements the opentracing.Tracer interface.
+func (t *ValidatingTracer) Inject(sc opentracing.SpanContext, format interface{}, carrier interface{}) error {
+	return t.tracer.Inject(sc, format, carrier)
+}
+
+// Extract implements the opentracing.Tracer interface.
+func (t *ValidatingTracer) Extract(format interface{}, carrier interface{}) (opentracing.SpanContext, error) {
+	return t.tracer.Extract(format, carrier)
+}
+
+// validatingSpan is a span of a ValidatingTracer, recording the tags and logs
+// of the span to validate them when it finishes.
+type validatingSpan struct {
+	span   opentracing.Span
+	tracer *ValidatingTracer
+
+	mu            sync.Mutex
+	operationName string
+	tags          map[string]interface{}
+	logs          []opentracing.LogRecord
+}
+
+// log records the specified log record.
+func (s *validatingSpan) log(record opentracing.LogRecord) {
+	s.mu.Lock()
+	s.logs = append(s.logs, record)
+	s.mu.Unlock()
+}
+
+// Finish implements the opentracing.Span interface.
+func (s *validatingSpan) Finish() {
+	s.FinishWithOptions(opentracing.FinishOptions{})
+}
+
+// FinishWithOptions implements the opentracing.Span interface.
+func (s *validatingSpan) FinishWithOptions(opts opentracing.FinishOptions) {
+	s.span.FinishWithOptions(opts)
+
+	s.mu.Lock()
+	s.logs = append(s.logs, opts.LogRecords...)
+	for _, ld := range opts.BulkLogData {
+		s.logs = append(s.logs, ld.ToLogRecord())
+	}
+	violations := s.tracer.validator.Validate(s.tags, s.logs)
+	operationName := s.operationName
+	s.mu.Unlock()
+
+	if len(violations) > 0 {
+		s.tracer.report(operationName, violations)
+	}
+}
+
+// Context implements the opentracing.Span interface.
+func (s *validatingSpan) Context() opentracing.SpanContext {
+	return s.span.Context()
+}
+
+// SetOperationName implements the opentracing.Span interface.
+func (s *validatingSpan) SetOperationName(operationName string) opentracing.Span {
+	s.span.SetOperationName(operationName)
+	s.mu.Lock()
+	s.operationName = operationName
+	s.mu.Unlock()
+	return s
+}
+
+// SetTag implements the opentracing.Span interface.
+func (s *validatingSpan) SetTag(key string, value interface{}) opentracing.Span {
+	s.span.SetTag(key, value)
+	s.mu.Lock()
+	s.tags[key] = value
+	s.mu.Unlock()
+	return s
+}
+
+// LogFields implements the opentracing.Span interface.
+func (s *validatingSpan) LogFields(fields ...log.Field) {
+	s.span.LogFields(fields...)
+	s.log(opentracing.LogRecord{Timestamp: time.Now(), Fields: fields})
+}
+
+// LogKV implements the opentracing.Span interface.
+func (s *validatingSpan) LogKV(alternatingKeyValues ...interface{}) {
+	s.span.LogKV(alternatingKeyValues...)
+	fields, err := log.InterleavedKVToFields(alternatingKeyValues...)
+	if err != nil {
+		fields = []log.Field{log.Error(err), log.String("function", "LogKV")}
+	}
+	s.log(opentracing.LogRecord{Timestamp: time.Now(), Fields: fields})
+}
+
+// SetBaggageItem implements the opentracing.Span interface.
+func (s *validatingSpan) SetBaggageItem(restrictedKey, value string) opentracing.Span {
+	s.span.SetBaggageItem(restrictedKey, value)
+	return s
+}
+
+// BaggageItem implements the opentracing.Span interface.
+func (s *validatingSpan) BaggageItem(restrictedKey string) string {
+	return s.span.BaggageItem(restrictedKey)
+}
+
+// Tracer implements the opentracing.Span interface.
+func (s *validatingSpan) Tracer() opentracing.Tracer {
+	return s.tracer
+}
+
+// LogEvent implements the deprecated opentracing.Span method.
+func (s *validatingSpan) LogEvent(event string) {
+	s.span.LogEvent(event)
+	data := opentracing.LogData{Event: event}
+	s.log(data.ToLogRecord())
+}
+
+// LogEventWithPayload implements the deprecated opentracing.Span method.
+func (s *validatingSpan) LogEventWithPayload(event string, payload interface{}) {
+	s.span.LogEventWithPayload(event, payload)
+	data := opentracing.LogData{Event: event, Payload: payload}
+	s.log(data.ToLogRecord())
+}
+
+// Log implements the deprecated opentracing.Span method.
+func (s *validatingSpan) Log(data opentracing.LogData) {
+	s.span.Log(data)
+	s.log(data.ToLogRecord())
+}